@@ -0,0 +1,183 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 16:20:45
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 16:20:45
+ * @FilePath: \common\cmd\migrate\main.go
+ * @Description: 数据库迁移命令行工具
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/config_source/local"
+	"github.com/hongliu9527/common/infra/orm"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+	"github.com/hongliu9527/common/infra/orm/migrate"
+	redis_infra "github.com/hongliu9527/common/infra/redis"
+	redisConfig "github.com/hongliu9527/common/infra/redis/config"
+)
+
+// 命令相关定义
+const (
+	commandUp     = "up"     // 执行未执行的迁移
+	commandDown   = "down"   // 回滚已经执行的迁移
+	commandStatus = "status" // 查询迁移状态
+	commandRedo   = "redo"   // 回滚并重新执行最后一个迁移
+)
+
+// 命令行参数
+var (
+	configPath   = flag.String("config", "./config/", "配置文件目录，读取其中的"+ormConfig.OrmInfraConfigFileName)
+	migrationDir = flag.String("dir", "./migrations/", "迁移文件根目录，每个数据库的迁移文件放在以配置名称命名的子目录下")
+	databaseName = flag.String("db", "", "只处理指定配置名称的数据库，为空时处理所有存在迁移目录的数据库")
+	steps        = flag.Int("steps", 0, "up时最多执行的版本数量(0表示全部)，down时回滚的版本数量(0表示1个)")
+	external     = flag.Bool("external", false, "使用外网地址访问数据库")
+	useRedis     = flag.Bool("redis", false, "使用"+redisConfig.RedisInfraConfigFileName+"中的Redis分布式锁作为迁移锁(ClickHouse没有可用的数据库锁，必须开启)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] up|down|status|redo\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "执行迁移失败(%s)\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run 对配置中的数据库依次执行迁移命令
+func run(ctx context.Context, command string) error {
+	switch command {
+	case commandUp, commandDown, commandStatus, commandRedo:
+	default:
+		return fmt.Errorf("命令(%s)不支持", command)
+	}
+
+	basePath := *configPath
+	if !strings.HasSuffix(basePath, "/") && !strings.HasSuffix(basePath, string(os.PathSeparator)) {
+		basePath += string(os.PathSeparator)
+	}
+
+	var infraConfig ormConfig.OrmInfraConfig
+	source := local.New("migrate", basePath)
+	if err := source.Read(ormConfig.OrmInfraConfigFileName, &infraConfig, 20*time.Second); err != nil {
+		return err
+	}
+
+	// 开启时所有数据库都使用Redis分布式锁作为迁移锁
+	var redis common.Redis
+	if *useRedis {
+		config, err := redisConfig.New(source, *external)
+		if err != nil {
+			return err
+		}
+		redisInfra := redis_infra.New(config)
+		if err := redisInfra.Start(ctx); err != nil {
+			return fmt.Errorf("启动Redis基础设施失败(%s)", err.Error())
+		}
+		defer redisInfra.Stop()
+		redis = redisInfra
+	}
+
+	found := false
+	for _, dbConfig := range infraConfig.Configs {
+		if *databaseName != "" && dbConfig.Name != *databaseName {
+			continue
+		}
+
+		// 处理所有数据库时，跳过没有迁移目录的数据库
+		if _, err := os.Stat(filepath.Join(*migrationDir, dbConfig.Name)); err != nil && *databaseName == "" {
+			continue
+		}
+		found = true
+
+		if err := runOne(ctx, command, dbConfig, redis); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("没有找到需要迁移的数据库(db=%s)", *databaseName)
+	}
+	return nil
+}
+
+// runOne 对单个数据库执行迁移命令，redis不为空时使用Redis分布式锁作为迁移锁
+func runOne(ctx context.Context, command string, dbConfig ormConfig.DataBaseConfig, redis common.Redis) error {
+	db, err := orm.Open(dbConfig, *external)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, dbConfig, os.DirFS(*migrationDir), migrate.Options{Redis: redis})
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case commandUp:
+		migrations, err := migrator.Up(ctx, *steps)
+		fmt.Printf("数据库(%s)执行了%d个版本\n", dbConfig.Name, len(migrations))
+		return err
+	case commandDown:
+		migrations, err := migrator.Down(ctx, *steps)
+		fmt.Printf("数据库(%s)回滚了%d个版本\n", dbConfig.Name, len(migrations))
+		return err
+	case commandRedo:
+		migration, err := migrator.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("数据库(%s)重新执行了版本(%s)\n", dbConfig.Name, migration)
+		return nil
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatuses(dbConfig.Name, statuses)
+		return nil
+	}
+}
+
+// printStatuses 以表格形式输出迁移状态
+func printStatuses(name string, statuses []migrate.Status) {
+	fmt.Printf("数据库(%s):\n", name)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if status.Missing {
+			state = "missing file"
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	writer.Flush()
+}
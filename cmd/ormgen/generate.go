@@ -0,0 +1,113 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-23 11:02:39
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-23 11:02:39
+ * @FilePath: \common\cmd\ormgen\generate.go
+ * @Description: 实体代码生成
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// entityTemplate 实体代码模板
+var entityTemplate = template.Must(template.New("entity").Parse(`// Code generated by ormgen. DO NOT EDIT.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{end}}
+// {{.StructName}}Table 数据表名称
+const {{.StructName}}Table = "{{.TableName}}"
+
+// {{.StructName}}列名相关定义
+const (
+{{- range .Fields}}
+	{{$.StructName}}Column{{.Name}} = "{{.Column}}"{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+)
+
+// {{.StructName}} 数据表({{.TableName}})实体
+type {{.StructName}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `db:"{{.Column}}"{{if .PrimaryKey}} orm:"pk"{{end}}` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+
+// TableName 数据表名称
+func ({{.StructName}}) TableName() string {
+	return {{.StructName}}Table
+}
+`))
+
+// entity 实体代码模板参数
+type entity struct {
+	Package    string        // 包名
+	Imports    []string      // 导入的包
+	StructName string        // 结构体名称
+	TableName  string        // 数据表名称
+	Fields     []entityField // 字段列表
+}
+
+// entityField 实体字段
+type entityField struct {
+	Name       string // 字段名称
+	Column     string // 列名
+	Type       string // Go类型
+	PrimaryKey bool   // 是否为主键
+	Comment    string // 注释
+}
+
+// generateEntity 根据数据表列信息生成实体代码
+func generateEntity(packageName string, dbType string, tableName string, tablePrefix string, columns []column) ([]byte, error) {
+	data := entity{
+		Package:    packageName,
+		StructName: goName(strings.TrimPrefix(tableName, tablePrefix)),
+		TableName:  tableName,
+		Fields:     make([]entityField, 0, len(columns)),
+	}
+
+	importTime := false
+	for _, column := range columns {
+		typ := goType(dbType, column.Type, column.Nullable)
+		if strings.Contains(typ, "time.Time") {
+			importTime = true
+		}
+
+		data.Fields = append(data.Fields, entityField{
+			Name:       goName(column.Name),
+			Column:     column.Name,
+			Type:       typ,
+			PrimaryKey: column.PrimaryKey,
+			Comment:    strings.Join(strings.Fields(column.Comment), " "),
+		})
+	}
+	if importTime {
+		data.Imports = append(data.Imports, "time")
+	}
+
+	var buffer bytes.Buffer
+	if err := entityTemplate.Execute(&buffer, data); err != nil {
+		return nil, errors.Wrapf(err, "生成数据表(%s)的实体代码失败", tableName)
+	}
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "格式化数据表(%s)的实体代码失败", tableName)
+	}
+	return source, nil
+}
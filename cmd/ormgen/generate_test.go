@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update 使用生成结果更新golden文件：go test ./cmd/ormgen -run TestGenerateEntity -update
+var update = flag.Bool("update", false, "更新golden文件")
+
+func TestGenerateEntity(t *testing.T) {
+	columns := []column{
+		{Name: "id", Type: "bigint(20) unsigned", PrimaryKey: true, Comment: "主键"},
+		{Name: "device_sn", Type: "varchar(64)", Comment: "设备\n序列号"},
+		{Name: "online", Type: "tinyint(1)"},
+		{Name: "firmware", Type: "blob", Nullable: true},
+		{Name: "last_ip", Type: "varchar(15)", Nullable: true, Comment: "最后上线IP"},
+		{Name: "create_time", Type: "datetime"},
+		{Name: "update_time", Type: "datetime", Nullable: true},
+	}
+
+	source, err := generateEntity("entity", "mysql", "t_device_info", "t_", columns)
+	if err != nil {
+		t.Fatalf("生成实体代码失败(%s)", err)
+	}
+
+	golden := filepath.Join("testdata", "device_info.golden")
+	if *update {
+		if err := os.WriteFile(golden, source, 0644); err != nil {
+			t.Fatalf("更新golden文件失败(%s)", err)
+		}
+	}
+
+	expect, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("读取golden文件失败(%s)", err)
+	}
+	if string(source) != string(expect) {
+		t.Fatalf("生成的实体代码与golden文件不一致\n实际:\n%s\n期望:\n%s", source, expect)
+	}
+}
+
+func TestGenerateEntityWithoutTime(t *testing.T) {
+	columns := []column{
+		{Name: "id", Type: "Int64", PrimaryKey: true},
+		{Name: "tags", Type: "Array(LowCardinality(String))"},
+	}
+
+	source, err := generateEntity("entity", "clickhouse", "event", "", columns)
+	if err != nil {
+		t.Fatalf("生成实体代码失败(%s)", err)
+	}
+	if strings.Contains(string(source), `"time"`) {
+		t.Fatalf("没有时间类型的列时不应该导入time包\n%s", source)
+	}
+	if !strings.Contains(string(source), "Tags []string `db:\"tags\"`") {
+		t.Fatalf("数组列类型错误\n%s", source)
+	}
+}
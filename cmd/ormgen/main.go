@@ -0,0 +1,128 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-23 09:48:03
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-23 09:48:03
+ * @FilePath: \common\cmd\ormgen\main.go
+ * @Description: 根据数据库表结构生成实体代码的命令行工具
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hongliu9527/common/infra/config_source/local"
+	"github.com/hongliu9527/common/infra/orm"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+)
+
+// 命令行参数
+var (
+	configPath   = flag.String("config", "./config/", "配置文件目录，读取其中的"+ormConfig.OrmInfraConfigFileName)
+	databaseName = flag.String("db", "", "只处理指定配置名称的数据库，为空时处理所有数据库")
+	tables       = flag.String("tables", "", "只生成指定的表，多个表名使用逗号分隔，为空时生成所有符合表名前缀的表")
+	outputDir    = flag.String("out", "./model/", "生成代码的输出目录")
+	packageName  = flag.String("package", "model", "生成代码的包名")
+	external     = flag.Bool("external", false, "使用外网地址访问数据库")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "生成实体代码失败(%s)\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run 读取配置并为每个数据库生成实体代码
+func run() error {
+	basePath := *configPath
+	if !strings.HasSuffix(basePath, "/") && !strings.HasSuffix(basePath, string(os.PathSeparator)) {
+		basePath += string(os.PathSeparator)
+	}
+
+	var infraConfig ormConfig.OrmInfraConfig
+	source := local.New("ormgen", basePath)
+	if err := source.Read(ormConfig.OrmInfraConfigFileName, &infraConfig, 20*time.Second); err != nil {
+		return err
+	}
+
+	tableFilter := make(map[string]struct{})
+	for _, tableName := range strings.Split(*tables, ",") {
+		if tableName = strings.TrimSpace(tableName); tableName != "" {
+			tableFilter[tableName] = struct{}{}
+		}
+	}
+
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		return fmt.Errorf("创建输出目录(%s)失败(%s)", *outputDir, err.Error())
+	}
+
+	found := false
+	for _, dbConfig := range infraConfig.Configs {
+		if *databaseName != "" && dbConfig.Name != *databaseName {
+			continue
+		}
+		found = true
+
+		if err := generateDatabase(dbConfig, tableFilter); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("没有找到数据库配置(db=%s)", *databaseName)
+	}
+	return nil
+}
+
+// generateDatabase 为数据库中符合表名前缀的表生成实体代码，每张表生成一个文件
+func generateDatabase(dbConfig ormConfig.DataBaseConfig, tableFilter map[string]struct{}) error {
+	db, err := orm.Open(dbConfig, *external)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tableNames, err := orm.TableNames(db, dbConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, tableName := range tableNames {
+		if _, ok := tableFilter[tableName]; len(tableFilter) > 0 && !ok {
+			continue
+		}
+
+		columns, err := queryColumns(db, dbConfig, tableName)
+		if err != nil {
+			return err
+		}
+
+		source, err := generateEntity(*packageName, dbConfig.Type, tableName, dbConfig.TablePrefix, columns)
+		if err != nil {
+			return err
+		}
+
+		fileName := filepath.Join(*outputDir, strings.ToLower(strings.TrimPrefix(tableName, dbConfig.TablePrefix))+".go")
+		if err := os.WriteFile(fileName, source, 0o644); err != nil {
+			return fmt.Errorf("写入文件(%s)失败(%s)", fileName, err.Error())
+		}
+		fmt.Printf("数据表(%s)的实体代码已生成(%s)\n", tableName, fileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-23 10:05:14
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-23 10:05:14
+ * @FilePath: \common\cmd\ormgen\schema.go
+ * @Description: 数据表列信息查询
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// column 数据表列信息
+type column struct {
+	Name       string `db:"name"`        // 列名
+	Type       string `db:"type"`        // 数据库中的列类型
+	Nullable   bool   `db:"nullable"`    // 是否可以为NULL
+	PrimaryKey bool   `db:"primary_key"` // 是否为主键
+	Comment    string `db:"comment"`     // 列注释
+}
+
+// sqliteColumn SQLite的PRAGMA table_info查询结果
+type sqliteColumn struct {
+	Cid          int     `db:"cid"`        // 列序号
+	Name         string  `db:"name"`       // 列名
+	Type         string  `db:"type"`       // 列类型
+	NotNull      bool    `db:"notnull"`    // 是否不能为NULL
+	DefaultValue *string `db:"dflt_value"` // 默认值
+	PrimaryKey   int     `db:"pk"`         // 在主键中的序号，0表示不是主键
+}
+
+// queryColumns 按照列顺序查询数据表的列信息
+func queryColumns(db *sqlx.DB, config ormConfig.DataBaseConfig, tableName string) ([]column, error) {
+	columns := make([]column, 0)
+
+	var err error
+	switch config.Type {
+	case "mysql", "tidb":
+		err = db.Select(&columns, "SELECT column_name AS name, column_type AS type, is_nullable = 'YES' AS nullable, "+
+			"column_key = 'PRI' AS primary_key, column_comment AS comment FROM information_schema.columns "+
+			"WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position", config.DatabaseName, tableName)
+	case "clickhouse":
+		err = db.Select(&columns, "SELECT name, type, startsWith(type, 'Nullable(') AS nullable, "+
+			"is_in_primary_key AS primary_key, comment FROM system.columns "+
+			"WHERE database = ? AND table = ? ORDER BY position", config.DatabaseName, tableName)
+	case "postgres":
+		err = db.Select(&columns, "SELECT c.column_name AS name, c.data_type AS type, c.is_nullable = 'YES' AS nullable, "+
+			"EXISTS (SELECT 1 FROM information_schema.table_constraints t JOIN information_schema.key_column_usage k "+
+			"ON t.constraint_name = k.constraint_name AND t.table_schema = k.table_schema "+
+			"WHERE t.constraint_type = 'PRIMARY KEY' AND t.table_schema = c.table_schema AND t.table_name = c.table_name "+
+			"AND k.column_name = c.column_name) AS primary_key, "+
+			"COALESCE(col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position), '') AS comment "+
+			"FROM information_schema.columns c WHERE c.table_schema = current_schema() AND c.table_name = $1 ORDER BY c.ordinal_position", tableName)
+	case "sqlite":
+		sqliteColumns := make([]sqliteColumn, 0)
+		err = db.Select(&sqliteColumns, fmt.Sprintf("PRAGMA table_info(%q)", tableName))
+		for _, sqliteColumn := range sqliteColumns {
+			columns = append(columns, column{
+				Name:       sqliteColumn.Name,
+				Type:       sqliteColumn.Type,
+				Nullable:   !sqliteColumn.NotNull && sqliteColumn.PrimaryKey == 0,
+				PrimaryKey: sqliteColumn.PrimaryKey > 0,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("数据库类型未知(%s)", config.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "查询数据表(%s)的列信息失败", tableName)
+	}
+
+	return columns, nil
+}
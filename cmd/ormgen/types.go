@@ -0,0 +1,164 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-23 10:31:27
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-23 10:31:27
+ * @FilePath: \common\cmd\ormgen\types.go
+ * @Description: 列类型与Go类型映射、命名转换
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms 转换为Go名称时保持全大写的缩写
+var commonInitialisms = map[string]struct{}{
+	"api": {}, "cpu": {}, "dns": {}, "gps": {}, "html": {}, "http": {}, "https": {}, "id": {}, "imei": {},
+	"ip": {}, "json": {}, "mac": {}, "md5": {}, "sn": {}, "sql": {}, "ssl": {}, "tcp": {}, "ttl": {},
+	"udp": {}, "uid": {}, "ui": {}, "uri": {}, "url": {}, "utc": {}, "uuid": {}, "xml": {},
+}
+
+// goType 将数据库列类型转换为Go类型，可以为NULL的列使用指针类型
+func goType(dbType string, columnType string, nullable bool) string {
+	var typ string
+	if dbType == "clickhouse" {
+		typ, nullable = clickhouseGoType(columnType)
+	} else {
+		typ = sqlGoType(dbType, columnType)
+	}
+
+	if nullable && !strings.HasPrefix(typ, "[]") {
+		return "*" + typ
+	}
+	return typ
+}
+
+// clickhouseGoType 将ClickHouse列类型转换为Go类型，并返回是否为Nullable类型
+func clickhouseGoType(columnType string) (string, bool) {
+	columnType = strings.TrimSpace(columnType)
+	if inner, ok := unwrapType(columnType, "LowCardinality"); ok {
+		return clickhouseGoType(inner)
+	}
+	if inner, ok := unwrapType(columnType, "Nullable"); ok {
+		typ, _ := clickhouseGoType(inner)
+		return typ, true
+	}
+	if inner, ok := unwrapType(columnType, "Array"); ok {
+		typ, _ := clickhouseGoType(inner)
+		return "[]" + typ, false
+	}
+
+	baseType := columnType
+	if index := strings.IndexByte(baseType, '('); index >= 0 {
+		baseType = baseType[:index]
+	}
+
+	switch baseType {
+	case "UInt8", "UInt16", "UInt32", "UInt64", "Int8", "Int16", "Int32", "Int64", "Float32", "Float64":
+		return strings.ToLower(baseType), false
+	case "Bool":
+		return "bool", false
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return "time.Time", false
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128":
+		return "float64", false
+	default:
+		// String、FixedString、UUID、Enum、IPv4、IPv6等类型
+		return "string", false
+	}
+}
+
+// unwrapType 去除类型包装，例如：Nullable(String) -> String
+func unwrapType(columnType string, wrapper string) (string, bool) {
+	if strings.HasPrefix(columnType, wrapper+"(") && strings.HasSuffix(columnType, ")") {
+		return columnType[len(wrapper)+1 : len(columnType)-1], true
+	}
+	return "", false
+}
+
+// sqlGoType 将MySQL/TiDB/PostgreSQL/SQLite列类型转换为Go类型
+func sqlGoType(dbType string, columnType string) string {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+	unsigned := strings.Contains(columnType, "unsigned")
+	baseType := columnType
+	if index := strings.IndexAny(baseType, "( "); index >= 0 && !strings.HasPrefix(baseType, "double precision") &&
+		!strings.HasPrefix(baseType, "character varying") && !strings.HasPrefix(baseType, "timestamp") {
+		baseType = baseType[:index]
+	}
+
+	integer := func(bits string) string {
+		if unsigned {
+			return "uint" + bits
+		}
+		return "int" + bits
+	}
+
+	switch {
+	case strings.HasPrefix(columnType, "tinyint(1)") || baseType == "bool" || baseType == "boolean":
+		return "bool"
+	case baseType == "tinyint":
+		return integer("8")
+	case baseType == "smallint" || baseType == "year":
+		return integer("16")
+	case (baseType == "mediumint" || baseType == "int" || baseType == "integer") && dbType != "sqlite":
+		return integer("32")
+	case baseType == "bigint" || baseType == "int" || baseType == "integer":
+		return integer("64")
+	case baseType == "float" || (baseType == "real" && dbType != "sqlite"):
+		return "float32"
+	case baseType == "double" || baseType == "double precision" || baseType == "real" || baseType == "decimal" || baseType == "numeric":
+		return "float64"
+	case baseType == "date" || baseType == "datetime" || strings.HasPrefix(baseType, "timestamp"):
+		return "time.Time"
+	case strings.Contains(baseType, "blob") || strings.Contains(baseType, "binary") || baseType == "bytea":
+		return "[]byte"
+	case dbType == "sqlite":
+		// 亲和性规则作用于完整的声明类型，例如：UNSIGNED BIG INT
+		return sqliteAffinityType(columnType)
+	default:
+		// char、varchar、text、enum、set、json、uuid、time等类型
+		return "string"
+	}
+}
+
+// sqliteAffinityType 按照SQLite的类型亲和性规则转换未知的列类型
+func sqliteAffinityType(columnType string) string {
+	switch {
+	case strings.Contains(columnType, "int"):
+		return "int64"
+	case strings.Contains(columnType, "real") || strings.Contains(columnType, "floa") || strings.Contains(columnType, "doub"):
+		return "float64"
+	default:
+		return "string"
+	}
+}
+
+// goName 将下划线分隔的名称转换为Go的驼峰名称，例如：device_ip -> DeviceIP
+func goName(name string) string {
+	var builder strings.Builder
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, part := range parts {
+		lower := strings.ToLower(part)
+		if _, ok := commonInitialisms[lower]; ok {
+			builder.WriteString(strings.ToUpper(lower))
+			continue
+		}
+
+		runes := []rune(part)
+		builder.WriteRune(unicode.ToUpper(runes[0]))
+		builder.WriteString(string(runes[1:]))
+	}
+
+	result := builder.String()
+	if result == "" || unicode.IsDigit([]rune(result)[0]) {
+		result = "T" + result
+	}
+	return result
+}
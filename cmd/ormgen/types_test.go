@@ -0,0 +1,121 @@
+package main
+
+import "testing"
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect string
+	}{
+		{"device_name", "DeviceName"},
+		{"device_ip", "DeviceIP"},
+		{"id", "ID"},
+		{"user_uuid", "UserUUID"},
+		{"createTime", "CreateTime"},
+		{"mac-address", "MACAddress"},
+		{"2fa_secret", "T2faSecret"},
+		{"___", "T"},
+	}
+
+	for _, test := range tests {
+		if actual := goName(test.name); actual != test.expect {
+			t.Errorf("名称(%s)转换结果错误(实际: %s, 期望: %s)", test.name, actual, test.expect)
+		}
+	}
+}
+
+func TestSQLGoType(t *testing.T) {
+	tests := []struct {
+		dbType     string
+		columnType string
+		expect     string
+	}{
+		{"mysql", "tinyint(1)", "bool"},
+		{"mysql", "tinyint(4)", "int8"},
+		{"mysql", "tinyint unsigned", "uint8"},
+		{"mysql", "smallint(6)", "int16"},
+		{"mysql", "int(11)", "int32"},
+		{"mysql", "int(10) unsigned", "uint32"},
+		{"tidb", "mediumint", "int32"},
+		{"mysql", "bigint(20) unsigned", "uint64"},
+		{"mysql", "float", "float32"},
+		{"mysql", "double", "float64"},
+		{"mysql", "decimal(10,2)", "float64"},
+		{"mysql", "datetime(3)", "time.Time"},
+		{"mysql", "varchar(64)", "string"},
+		{"mysql", "json", "string"},
+		{"mysql", "longblob", "[]byte"},
+		{"mysql", "varbinary(16)", "[]byte"},
+		{"postgres", "integer", "int32"},
+		{"postgres", "boolean", "bool"},
+		{"postgres", "real", "float32"},
+		{"postgres", "double precision", "float64"},
+		{"postgres", "character varying", "string"},
+		{"postgres", "timestamp with time zone", "time.Time"},
+		{"postgres", "bytea", "[]byte"},
+		{"postgres", "ARRAY", "string"},
+		{"postgres", "USER-DEFINED", "string"},
+		{"sqlite", "INTEGER", "int64"},
+		{"sqlite", "int", "int64"},
+		{"sqlite", "REAL", "float64"},
+		{"sqlite", "BOOLEAN", "bool"},
+		{"sqlite", "UNSIGNED BIG INT", "int64"},
+		{"sqlite", "DOUBLE PRECISION", "float64"},
+		{"sqlite", "NVARCHAR(20)", "string"},
+		{"sqlite", "BLOB", "[]byte"},
+	}
+
+	for _, test := range tests {
+		if actual := sqlGoType(test.dbType, test.columnType); actual != test.expect {
+			t.Errorf("%s列类型(%s)转换结果错误(实际: %s, 期望: %s)", test.dbType, test.columnType, actual, test.expect)
+		}
+	}
+}
+
+func TestClickHouseGoType(t *testing.T) {
+	tests := []struct {
+		columnType string
+		expect     string
+		nullable   bool
+	}{
+		{"UInt8", "uint8", false},
+		{"Int64", "int64", false},
+		{"Float32", "float32", false},
+		{"Bool", "bool", false},
+		{"DateTime64(3, 'Asia/Shanghai')", "time.Time", false},
+		{"Decimal(18, 4)", "float64", false},
+		{"FixedString(16)", "string", false},
+		{"Enum8('a' = 1, 'b' = 2)", "string", false},
+		{"Nullable(Int32)", "int32", true},
+		{"LowCardinality(String)", "string", false},
+		{"LowCardinality(Nullable(String))", "string", true},
+		{"Array(Nullable(UInt16))", "[]uint16", false},
+	}
+
+	for _, test := range tests {
+		actual, nullable := clickhouseGoType(test.columnType)
+		if actual != test.expect || nullable != test.nullable {
+			t.Errorf("ClickHouse列类型(%s)转换结果错误(实际: %s, %v, 期望: %s, %v)", test.columnType, actual, nullable, test.expect, test.nullable)
+		}
+	}
+}
+
+func TestGoType(t *testing.T) {
+	tests := []struct {
+		dbType     string
+		columnType string
+		nullable   bool
+		expect     string
+	}{
+		{"mysql", "int(11)", true, "*int32"},
+		{"mysql", "blob", true, "[]byte"},
+		{"clickhouse", "Nullable(DateTime)", false, "*time.Time"},
+		{"clickhouse", "Array(String)", false, "[]string"},
+	}
+
+	for _, test := range tests {
+		if actual := goType(test.dbType, test.columnType, test.nullable); actual != test.expect {
+			t.Errorf("%s列类型(%s)转换结果错误(实际: %s, 期望: %s)", test.dbType, test.columnType, actual, test.expect)
+		}
+	}
+}
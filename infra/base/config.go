@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/metrics"
 
 	"github.com/hongliu9527/go-tools/logger"
 )
@@ -61,6 +62,7 @@ func (c *BaseConfig) ListenSource(ctx context.Context, source common.ConfigSourc
 				continue
 			}
 			logger.Error("(%s)配置读取数据源(%s)事件失败(%s)", c.Name, c.FileName, err)
+			metrics.ObserveConfigReload(c.Name, err)
 			continue
 		}
 
@@ -68,8 +70,10 @@ func (c *BaseConfig) ListenSource(ctx context.Context, source common.ConfigSourc
 		err = source.Read(c.FileName, configInstance, readSourceTimeout*time.Second)
 		if err != nil {
 			logger.Error("读取(%s)配置信息失败(%s)", c.Name, err.Error())
+			metrics.ObserveConfigReload(c.Name, err)
 			continue
 		}
+		metrics.ObserveConfigReload(c.Name, nil)
 
 		event := common.ConfigEvent{
 			Type: common.ConfigChanged,
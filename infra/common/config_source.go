@@ -218,6 +218,11 @@ func setStructDecodeValue(path string, configMap map[string]interface{}, structP
 		mapValue, ok := configMap[tagName]
 		if !ok {
 			*spareStructTags = append(*spareStructTags, fmt.Sprintf("%s%s", path, tagName))
+
+			// 切片类型没有默认值，未配置时保持为空切片
+			if fieldValue.Kind() == reflect.Slice {
+				continue
+			}
 			setDefaultFieldValue(fieldValue, defaultValue)
 		}
 
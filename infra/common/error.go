@@ -17,4 +17,9 @@ var (
 	ErrReceiveEventTimeout = errors.New("接收事件出现超时")
 	ErrReceiveDataTimeout  = errors.New("接收数据出现超时")
 	ErrAdvanceExit         = errors.New("提前退出")
+	ErrKeyNotFound         = errors.New("键不存在")
+	ErrInfraNotStarted     = errors.New("基础设施未启动")
+	ErrTooManyKeys         = errors.New("键数量超过限制")
+	ErrLockNotAcquired     = errors.New("获取锁失败")
+	ErrLockNotHeld         = errors.New("未持有锁")
 )
@@ -10,7 +10,11 @@
  */
 package common
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"time"
+)
 
 // Infra 基础设施接口定义
 type Infra interface {
@@ -24,6 +28,12 @@ type Infra interface {
 type OrmInfra interface {
 	Orm
 	Infra
+
+	Stats() map[string]sql.DBStats                                    // 获取所有数据库实例(包括只读副本)的连接池统计信息，键为数据库实例名称
+	RegisterShardingRule(logicalTable string, rule ShardingRule)      // 注册逻辑表的分表规则
+	ConnShard(logicalTable string, shardKey interface{}) (Orm, error) // 根据分片键获取物理表的数据库连接
+	ConnClickHouse(tableName string) (ClickHouseOrm, error)           // 获取ClickHouse数据表的专用连接
+	EnableCache(redis Redis, ttl time.Duration, tables ...string)     // 启用基于Redis的查询结果缓存，tables为空时所有数据表都启用
 }
 
 // OssInfra oss基础设施接口定义
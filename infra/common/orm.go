@@ -10,21 +10,112 @@
  */
 package common
 
-import "strings"
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
 
 // Orm Orm基础设施接口定义
+// 不带上下文的方法使用基础设施的上下文对象，需要超时控制或者取消操作时请使用带Context后缀的方法
 type Orm interface {
-	Conn(tableName string) (orm Orm, err error)                        // 获取数据库连接
-	Get(dest interface{}, query string, args ...interface{}) error     // 查询单个数据
-	Select(dest interface{}, query string, args ...interface{}) error  // 查询多个数据
-	Insert(value interface{}) (uint64, error)                          // 插入单个数据
-	BatchInsert(values interface{}) error                              // 批量插入
-	Update(condition string, updateValue map[string]interface{}) error // 更新数据
-	Delete(condition string, args ...interface{}) error                // 删除数据
-	Exec(query string, args ...interface{}) error                      // 执行原生sql
-	Begin() error                                                      // 开启事务
-	Rollback() error                                                   // 回滚事务
-	Commit() error                                                     // 执行事务
+	Conn(tableName string) (orm Orm, err error)                                  // 获取数据库连接
+	Get(dest interface{}, query string, args ...interface{}) error               // 查询单个数据
+	Select(dest interface{}, query string, args ...interface{}) error            // 查询多个数据
+	QueryRows(query string, args ...interface{}) (Rows, error)                   // 查询数据并返回逐行读取的结果集
+	Insert(value interface{}) (Result, error)                                    // 插入单个数据
+	BatchInsert(values interface{}) error                                        // 批量插入
+	Update(condition string, updateValue map[string]interface{}) (Result, error) // 更新数据
+	Delete(condition string, args ...interface{}) (Result, error)                // 删除数据(开启软删除时只写入删除时间)
+	ForceDelete(condition string, args ...interface{}) (Result, error)           // 物理删除数据(忽略软删除配置)
+	UpdateByPK(value interface{}, columns ...string) (Result, error)             // 根据主键更新结构体数据
+	UpdateStruct(origin interface{}, value interface{}) (Result, error)          // 根据主键更新结构体中发生变化的字段
+	Upsert(value interface{}) (Result, error)                                    // 插入数据，主键或唯一键冲突时更新
+	Exec(query string, args ...interface{}) (Result, error)                      // 执行原生sql
+	Begin() error                                                                // 开启事务
+	Rollback() error                                                             // 回滚事务
+	Commit() error                                                               // 执行事务
+
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error               // 查询单个数据(带上下文)
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error            // 查询多个数据(带上下文)
+	QueryRowsContext(ctx context.Context, query string, args ...interface{}) (Rows, error)                   // 查询数据并返回逐行读取的结果集(带上下文)
+	InsertContext(ctx context.Context, value interface{}) (Result, error)                                    // 插入单个数据(带上下文)
+	BatchInsertContext(ctx context.Context, values interface{}) error                                        // 批量插入(带上下文)
+	BatchInsertWithOptions(ctx context.Context, values interface{}, options BatchOptions) error              // 分批插入切片数据(带上下文和批量选项)
+	BatchInsertStream(ctx context.Context, values <-chan interface{}, options BatchOptions) error            // 分批插入通道中的数据，通道关闭后结束
+	UpdateContext(ctx context.Context, condition string, updateValue map[string]interface{}) (Result, error) // 更新数据(带上下文)
+	DeleteContext(ctx context.Context, condition string, args ...interface{}) (Result, error)                // 删除数据(带上下文)
+	ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (Result, error)           // 物理删除数据(带上下文)
+	UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (Result, error)             // 根据主键更新结构体数据(带上下文)
+	UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (Result, error)          // 根据主键更新结构体中发生变化的字段(带上下文)
+	UpsertContext(ctx context.Context, value interface{}) (Result, error)                                    // 插入或更新数据(带上下文)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error)                      // 执行原生sql(带上下文)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) error                                                  // 开启事务(带上下文和事务选项)
+	WithTx(ctx context.Context, fn func(tx Orm) error) error                                                 // 在事务中执行函数，根据返回值自动提交或回滚
+}
+
+// ClickHouseOrm ClickHouse专用的Orm接口，事务以及Update/Delete等行级写操作会直接返回错误，请使用对应的mutation方法
+type ClickHouseOrm interface {
+	Orm
+
+	AsyncInsert(ctx context.Context, values interface{}, wait bool) error                                                           // 异步插入结构体或结构体切片，wait为true时等待数据写入后返回
+	ColumnarInsert(ctx context.Context, columns []string, data ...interface{}) error                                                // 按列批量插入，data[i]为columns[i]列的值切片，所有切片长度必须一致
+	MutateUpdate(ctx context.Context, updateValue map[string]interface{}, condition string, args ...interface{}) error              // 提交ALTER TABLE ... UPDATE异步变更
+	MutateDelete(ctx context.Context, condition string, args ...interface{}) error                                                  // 提交ALTER TABLE ... DELETE异步变更
+	Mutations(ctx context.Context) ([]MutationStatus, error)                                                                        // 查询数据表未完成的变更
+	WaitMutations(ctx context.Context, interval time.Duration) error                                                                // 轮询等待数据表所有变更完成，变更失败时返回错误
+	GetWithSettings(ctx context.Context, dest interface{}, settings ClickHouseSettings, query string, args ...interface{}) error    // 使用查询设置查询单个数据
+	SelectWithSettings(ctx context.Context, dest interface{}, settings ClickHouseSettings, query string, args ...interface{}) error // 使用查询设置查询多个数据
+	From(final bool, sample float64) string                                                                                         // 构建带FINAL/SAMPLE修饰的表名，sample不在(0,1)区间时不采样
+}
+
+// ClickHouseSettings ClickHouse单次查询的设置，例如：{"max_execution_time": 10, "max_threads": 4}
+type ClickHouseSettings map[string]interface{}
+
+// MutationStatus ClickHouse数据变更状态
+type MutationStatus struct {
+	MutationId       string    `db:"mutation_id"`        // 变更编号
+	Command          string    `db:"command"`            // 变更命令
+	CreateTime       time.Time `db:"create_time"`        // 创建时间
+	PartsToDo        int64     `db:"parts_to_do"`        // 剩余需要处理的数据分片数量
+	IsDone           bool      `db:"is_done"`            // 是否已经完成
+	LatestFailReason string    `db:"latest_fail_reason"` // 最近一次失败原因
+}
+
+// Rows 逐行读取的查询结果集，使用完毕后必须关闭
+type Rows interface {
+	Next() bool                        // 移动到下一行，没有更多数据或者出错时返回false
+	Scan(dest ...interface{}) error    // 按列顺序读取当前行
+	StructScan(dest interface{}) error // 按db标签将当前行读取到结构体
+	Err() error                        // 遍历过程中出现的错误
+	Close() error                      // 关闭结果集并释放连接
+}
+
+// ShardingRule 分表规则，根据逻辑表名和分片键计算物理表名
+type ShardingRule interface {
+	PhysicalTable(logicalTable string, shardKey interface{}) (string, error) // 计算物理表名
+}
+
+// Result 写操作执行结果
+type Result struct {
+	LastInsertId int64 // 最后插入数据的自增主键(数据库不支持时为0)
+	RowsAffected int64 // 受影响的数据行数(数据库不支持时为0)
+}
+
+// BatchOptions 批量插入选项
+type BatchOptions struct {
+	ChunkSize     int                          // 每批插入的数据条数，小于等于0时使用数据库配置的批量大小
+	InTransaction bool                         // 是否在同一个事务中插入所有批次(ClickHouse不支持事务，每批独立提交)
+	Progress      func(progress BatchProgress) // 每批数据插入完成(或失败)后的回调
+}
+
+// BatchProgress 批量插入进度
+type BatchProgress struct {
+	Chunk int   // 批次序号，从1开始
+	Rows  int   // 本批次数据条数
+	Total int   // 已成功插入的数据总条数
+	Err   error // 本批次插入错误
 }
 
 // FilterParam 筛选参数
@@ -41,6 +132,8 @@ type SortParm struct {
 }
 
 // ToSql 筛选参数转换为SQL语句
+//
+// Deprecated: 该方法直接拼接列名和参数值，存在SQL注入风险，请使用orm.QueryBuilder构建参数化查询条件
 func (p FilterParam) ToSql(tableName ...string) string {
 	if len(p.Values) == 0 {
 		return " 1 = 1 "
@@ -63,6 +156,8 @@ func (p FilterParam) ToSql(tableName ...string) string {
 }
 
 // ToSql 排序参数转换为SQL语句
+//
+// Deprecated: 该方法直接拼接列名和排序方向，存在SQL注入风险，请使用orm.QueryBuilder构建排序条件
 func (p SortParm) ToSql(tableName ...string) string {
 	var tablePrefix string
 	if len(tableName) > 0 {
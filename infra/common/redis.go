@@ -11,13 +11,129 @@
 
 package common
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// Redis Redis接口定义
+// Redis Redis接口定义，键不存在时读操作返回的错误可以通过errors.Is(err, ErrKeyNotFound)判断
+// 不带上下文的方法使用基础设施的上下文对象，需要超时控制或者取消操作时请使用带Context后缀的方法
+// 基础设施未启动时所有方法都返回ErrInfraNotStarted
 type Redis interface {
 	Set(key string, value interface{}, expiration time.Duration) error           // 设置单个字符串数据
 	Get(key string) (string, error)                                              // 读取单个字符串数据
-	ReadAllKeys(match string) ([]string, error)                                  // 读取所有的key
+	ReadAllKeys(match string) ([]string, error)                                  // 读取所有匹配的key，超过100000个时返回ErrTooManyKeys
 	Delete(key string) error                                                     // 删除
 	SetNX(key string, value interface{}, expiration time.Duration) (bool, error) // 单个键值数据不存在时设置
+
+	MGet(keys ...string) (map[string]string, error)                                          // 批量读取字符串数据，不存在的键不包含在结果中
+	MSet(values map[string]interface{}, expiration time.Duration) error                      // 批量设置字符串数据，expiration为0时不过期
+	Exists(keys ...string) (int64, error)                                                    // 查询存在的键数量
+	Expire(key string, expiration time.Duration) (bool, error)                               // 设置过期时间，键不存在时返回false
+	TTL(key string) (time.Duration, error)                                                   // 查询剩余过期时间，键不存在时返回ErrKeyNotFound，不过期时返回-1
+	Incr(key string, expiration time.Duration) (int64, error)                                // 计数器加1，expiration大于0且键没有过期时间时设置过期时间
+	IncrBy(key string, value int64, expiration time.Duration) (int64, error)                 // 计数器增加指定值，expiration大于0且键没有过期时间时设置过期时间
+	HSet(key string, values map[string]interface{}) error                                    // 设置哈希表的多个字段
+	HGet(key string, field string) (string, error)                                           // 读取哈希表的单个字段
+	HGetAll(key string) (map[string]string, error)                                           // 读取哈希表的所有字段，键不存在时返回空哈希表
+	HDel(key string, fields ...string) (int64, error)                                        // 删除哈希表的字段，返回删除的字段数量
+	HIncrBy(key string, field string, value int64) (int64, error)                            // 哈希表字段增加指定值
+	HLen(key string) (int64, error)                                                          // 查询哈希表的字段数量
+	LPush(key string, values ...interface{}) (int64, error)                                  // 在列表头部插入数据，返回列表长度
+	RPush(key string, values ...interface{}) (int64, error)                                  // 在列表尾部插入数据，返回列表长度
+	LPop(key string) (string, error)                                                         // 弹出列表头部的数据，列表为空时返回ErrKeyNotFound
+	RPop(key string) (string, error)                                                         // 弹出列表尾部的数据，列表为空时返回ErrKeyNotFound
+	LRange(key string, start int64, stop int64) ([]string, error)                            // 读取列表指定范围的数据，stop为-1时读取到末尾
+	LTrim(key string, start int64, stop int64) error                                         // 只保留列表指定范围的数据
+	LLen(key string) (int64, error)                                                          // 查询列表长度
+	SAdd(key string, members ...interface{}) (int64, error)                                  // 向集合添加成员，返回新添加的成员数量
+	SRem(key string, members ...interface{}) (int64, error)                                  // 从集合删除成员，返回删除的成员数量
+	SMembers(key string) ([]string, error)                                                   // 读取集合的所有成员
+	SIsMember(key string, member interface{}) (bool, error)                                  // 判断是否为集合的成员
+	SCard(key string) (int64, error)                                                         // 查询集合的成员数量
+	ZAdd(key string, members ...ZMember) (int64, error)                                      // 向有序集合添加成员(已存在时更新分数)，返回新添加的成员数量
+	ZRem(key string, members ...interface{}) (int64, error)                                  // 从有序集合删除成员，返回删除的成员数量
+	ZIncrBy(key string, increment float64, member string) (float64, error)                   // 有序集合成员的分数增加指定值
+	ZScore(key string, member string) (float64, error)                                       // 查询有序集合成员的分数，成员不存在时返回ErrKeyNotFound
+	ZRange(key string, start int64, stop int64) ([]string, error)                            // 按分数从小到大读取指定排名范围的成员
+	ZRevRange(key string, start int64, stop int64) ([]string, error)                         // 按分数从大到小读取指定排名范围的成员
+	ZRangeWithScores(key string, start int64, stop int64) ([]ZMember, error)                 // 按分数从小到大读取指定排名范围的成员和分数
+	ZRangeByScore(key string, min string, max string, offset, count int64) ([]string, error) // 读取分数在[min, max]范围内的成员，count为0时不限制数量
+	ZCard(key string) (int64, error)                                                         // 查询有序集合的成员数量
+
+	SetContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error                       // 设置单个字符串数据(带上下文)
+	GetContext(ctx context.Context, key string) (string, error)                                                          // 读取单个字符串数据(带上下文)
+	ReadAllKeysContext(ctx context.Context, match string) ([]string, error)                                              // 读取所有匹配的key(带上下文)
+	DeleteContext(ctx context.Context, key string) error                                                                 // 删除(带上下文)
+	SetNXContext(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)             // 单个键值数据不存在时设置(带上下文)
+	MGetContext(ctx context.Context, keys ...string) (map[string]string, error)                                          // 批量读取字符串数据(带上下文)
+	MSetContext(ctx context.Context, values map[string]interface{}, expiration time.Duration) error                      // 批量设置字符串数据(带上下文)
+	ExistsContext(ctx context.Context, keys ...string) (int64, error)                                                    // 查询存在的键数量(带上下文)
+	ExpireContext(ctx context.Context, key string, expiration time.Duration) (bool, error)                               // 设置过期时间(带上下文)
+	TTLContext(ctx context.Context, key string) (time.Duration, error)                                                   // 查询剩余过期时间(带上下文)
+	IncrContext(ctx context.Context, key string, expiration time.Duration) (int64, error)                                // 计数器加1(带上下文)
+	IncrByContext(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error)                 // 计数器增加指定值(带上下文)
+	HSetContext(ctx context.Context, key string, values map[string]interface{}) error                                    // 设置哈希表的多个字段(带上下文)
+	HGetContext(ctx context.Context, key string, field string) (string, error)                                           // 读取哈希表的单个字段(带上下文)
+	HGetAllContext(ctx context.Context, key string) (map[string]string, error)                                           // 读取哈希表的所有字段(带上下文)
+	HDelContext(ctx context.Context, key string, fields ...string) (int64, error)                                        // 删除哈希表的字段(带上下文)
+	HIncrByContext(ctx context.Context, key string, field string, value int64) (int64, error)                            // 哈希表字段增加指定值(带上下文)
+	HLenContext(ctx context.Context, key string) (int64, error)                                                          // 查询哈希表的字段数量(带上下文)
+	LPushContext(ctx context.Context, key string, values ...interface{}) (int64, error)                                  // 在列表头部插入数据(带上下文)
+	RPushContext(ctx context.Context, key string, values ...interface{}) (int64, error)                                  // 在列表尾部插入数据(带上下文)
+	LPopContext(ctx context.Context, key string) (string, error)                                                         // 弹出列表头部的数据(带上下文)
+	RPopContext(ctx context.Context, key string) (string, error)                                                         // 弹出列表尾部的数据(带上下文)
+	LRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error)                            // 读取列表指定范围的数据(带上下文)
+	LTrimContext(ctx context.Context, key string, start int64, stop int64) error                                         // 只保留列表指定范围的数据(带上下文)
+	LLenContext(ctx context.Context, key string) (int64, error)                                                          // 查询列表长度(带上下文)
+	SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error)                                  // 向集合添加成员(带上下文)
+	SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error)                                  // 从集合删除成员(带上下文)
+	SMembersContext(ctx context.Context, key string) ([]string, error)                                                   // 读取集合的所有成员(带上下文)
+	SIsMemberContext(ctx context.Context, key string, member interface{}) (bool, error)                                  // 判断是否为集合的成员(带上下文)
+	SCardContext(ctx context.Context, key string) (int64, error)                                                         // 查询集合的成员数量(带上下文)
+	ZAddContext(ctx context.Context, key string, members ...ZMember) (int64, error)                                      // 向有序集合添加成员(已存在时更新分数)(带上下文)
+	ZRemContext(ctx context.Context, key string, members ...interface{}) (int64, error)                                  // 从有序集合删除成员(带上下文)
+	ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error)                   // 有序集合成员的分数增加指定值(带上下文)
+	ZScoreContext(ctx context.Context, key string, member string) (float64, error)                                       // 查询有序集合成员的分数(带上下文)
+	ZRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error)                            // 按分数从小到大读取指定排名范围的成员(带上下文)
+	ZRevRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error)                         // 按分数从大到小读取指定排名范围的成员(带上下文)
+	ZRangeWithScoresContext(ctx context.Context, key string, start int64, stop int64) ([]ZMember, error)                 // 按分数从小到大读取指定排名范围的成员和分数(带上下文)
+	ZRangeByScoreContext(ctx context.Context, key string, min string, max string, offset, count int64) ([]string, error) // 读取分数在[min, max]范围内的成员(带上下文)
+	ZCardContext(ctx context.Context, key string) (int64, error)                                                         // 查询有序集合的成员数量(带上下文)
+	ScanKeys(ctx context.Context, match string, options ScanOptions, fn func(keys []string) error) error                 // 按游标分批遍历匹配的键，集群模式下遍历所有主节点
+	NewLock(key string, options LockOptions) Lock                                                                        // 创建分布式锁(不会立即加锁)
+}
+
+// LockOptions 分布式锁选项
+type LockOptions struct {
+	TTL           time.Duration // 锁的租期，小于等于0时使用默认值30秒
+	WaitTimeout   time.Duration // Lock阻塞等待的最长时间，0表示只尝试一次，小于0表示一直等待到上下文对象取消
+	RetryInterval time.Duration // 阻塞等待期间的重试间隔，小于等于0时使用默认值100毫秒
+	Watchdog      bool          // 持有锁期间是否自动续期(每隔TTL/3续期一次)，适用于执行时间不确定的长任务
+	Reentrant     bool          // 是否允许同一个锁对象重复加锁，重复加锁后需要相同次数的解锁才会释放
+}
+
+// Lock 分布式锁接口定义，通过随机令牌标识持有者，只有持有者才能续期和释放
+// 获取锁失败时返回的错误可以通过errors.Is(err, ErrLockNotAcquired)判断，锁已过期或者被其他实例持有时返回ErrLockNotHeld
+type Lock interface {
+	Key() string                                                // 锁的键
+	Token() string                                              // 锁对象的持有者令牌
+	Lock(ctx context.Context) error                             // 加锁，按WaitTimeout阻塞等待
+	TryLock(ctx context.Context) (bool, error)                  // 尝试加锁一次，锁被其他实例持有时返回false
+	Unlock(ctx context.Context) error                           // 解锁，使用Lua脚本比较令牌后删除，避免误删其他实例的锁
+	UnlockAfter(ctx context.Context, delay time.Duration) error // 停止续期并在delay后自动过期，delay小于等于0时等同于Unlock
+	Refresh(ctx context.Context, ttl time.Duration) error       // 续期，ttl小于等于0时使用选项中的租期
+	Lost() <-chan struct{}                                      // 看门狗续期失败(锁已丢失)时关闭，未持有锁时返回已关闭的通道
+}
+
+// ScanOptions 键遍历选项
+type ScanOptions struct {
+	Count   int64 // 每次SCAN命令的数量提示，小于等于0时使用默认值1000
+	MaxKeys int   // 最多遍历的键数量(按SCAN返回的数量计数，包含重复的键)，超过时停止遍历并返回ErrTooManyKeys，0表示不限制
+}
+
+// ZMember 有序集合成员
+type ZMember struct {
+	Score  float64     // 分数
+	Member interface{} // 成员
 }
@@ -60,7 +60,7 @@ func (l *LocalConfigSource) Init(ctx context.Context) error {
 func (l *LocalConfigSource) Read(filename string, value interface{}, timeout time.Duration) error {
 	viperInstance := viper.New()
 	configFileName := l.basePath + filename
-	viper.SetConfigFile(configFileName)
+	viperInstance.SetConfigFile(configFileName)
 
 	if err := viperInstance.ReadInConfig(); err != nil {
 		return fmt.Errorf("读取模块(%s)的配置文件(%s)失败(%s)", l.moduleName, configFileName, err.Error())
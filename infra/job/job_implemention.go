@@ -12,11 +12,18 @@
 package job
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"time"
 
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/metrics"
+	"github.com/hongliu9527/common/infra/tracing"
+
 	"github.com/hongliu9527/go-tools/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // taskBarrierTime 同名任务多实例之间的屏障时间(等价于应用实例之间最大时差)
@@ -27,6 +34,22 @@ func encodeName2Key(name string) string {
 	return fmt.Sprintf("%X", (md5.Sum([]byte(name))))
 }
 
+// runJob 执行一次定时任务，并记录执行日志、监控指标和链路
+func runJob(name string, job func() error) {
+	_, span := tracing.Start(context.Background(), "job "+name, trace.SpanKindInternal, attribute.String("job.name", name))
+
+	logger.Info("开始执行定时任务(%s)...", name)
+	begin := time.Now()
+	err := job()
+	metrics.ObserveJobRun(name, time.Since(begin), err)
+	tracing.End(span, err)
+	if err != nil {
+		logger.Error("执行定时任务(%s)失败(%s)", name, err.Error())
+	} else {
+		logger.Info("定时任务(%s)执行完成", name)
+	}
+}
+
 // AddTask 增加分布式定时任务
 func (i *DistributedJobInfra) AddTask(name string, expr string, job func() error) error {
 	i.taskLock.RLock()
@@ -39,19 +62,24 @@ func (i *DistributedJobInfra) AddTask(name string, expr string, job func() error
 	taskKey := encodeName2Key(name)
 	// 定时任务保证多实例执行一次
 	onceJob := func() {
-		ok, err := i.redis.SetNX(taskKey, 1, taskBarrierTime)
+		// 执行期间由看门狗续期，避免长任务执行过程中锁过期导致其他实例重复执行
+		lock := i.redis.NewLock(taskKey, common.LockOptions{TTL: taskBarrierTime, Watchdog: true})
+		ok, err := lock.TryLock(i.ctx)
 		if err != nil {
 			logger.Error("执行定时任务(%s)失败(%s)", name, err.Error())
 			return
 		}
-		if ok {
-			logger.Info("开始执行定时任务(%s)...", name)
-			err := job()
-			if err != nil {
-				logger.Error("执行定时任务(%s)失败(%s)", name, err.Error())
-			} else {
-				logger.Info("定时任务(%s)执行完成", name)
-			}
+		if !ok {
+			metrics.IncJobLockContention(name)
+			return
+		}
+
+		begin := time.Now()
+		runJob(name, job)
+
+		// 锁至少保持屏障时间，防止存在时差的其他实例在任务结束后再次执行
+		if err := lock.UnlockAfter(i.ctx, taskBarrierTime-time.Since(begin)); err != nil {
+			logger.Warning("释放定时任务(%s)的分布式锁失败(%s)", name, err.Error())
 		}
 	}
 
@@ -95,13 +123,7 @@ func (i *StandaloneJobInfra) AddTask(name string, expr string, job func() error)
 	}
 
 	execJob := func() {
-		logger.Info("开始执行定时任务(%s)...", name)
-		err := job()
-		if err != nil {
-			logger.Error("执行定时任务(%s)失败(%s)", name, err.Error())
-		} else {
-			logger.Info("定时任务(%s)执行完成", name)
-		}
+		runJob(name, job)
 	}
 
 	entryID, err := i.cron.AddFunc(expr, execJob)
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRunJobSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"执行成功", nil, codes.Unset},
+		{"执行失败", errors.New("任务失败"), codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exporter.Reset()
+			runJob("清理过期数据", func() error { return test.err })
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("链路数量错误(%d)", len(spans))
+			}
+			span := spans[0]
+			if span.Name != "job 清理过期数据" || span.SpanKind != trace.SpanKindInternal {
+				t.Fatalf("链路名称或类型错误(%s, %s)", span.Name, span.SpanKind)
+			}
+			// 定时任务没有上游调用方，每次执行都是根链路
+			if span.Parent.IsValid() {
+				t.Fatal("定时任务链路应该是根链路")
+			}
+			if len(span.Attributes) != 1 || span.Attributes[0] != attribute.String("job.name", "清理过期数据") {
+				t.Fatalf("链路属性错误(%v)", span.Attributes)
+			}
+			if span.Status.Code != test.code {
+				t.Fatalf("链路状态错误(%v)，期望(%v)", span.Status.Code, test.code)
+			}
+		})
+	}
+}
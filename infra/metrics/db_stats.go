@@ -0,0 +1,104 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 10:40:02
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 10:40:02
+ * @FilePath: \common\infra\metrics\db_stats.go
+ * @Description: 数据库连接池监控指标采集
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbStatsCollector 数据库连接池状态采集器，采集时实时读取sql.DB.Stats()
+type dbStatsCollector struct {
+	lock      sync.RWMutex       // 数据库实例哈希表读写锁
+	databases map[string]*sql.DB // 数据库实例名-数据库实例哈希表
+
+	maxOpen           *prometheus.Desc // 最大连接数
+	open              *prometheus.Desc // 当前连接数
+	inUse             *prometheus.Desc // 使用中的连接数
+	idle              *prometheus.Desc // 空闲连接数
+	waitCount         *prometheus.Desc // 等待连接的总次数
+	waitDuration      *prometheus.Desc // 等待连接的总耗时
+	maxIdleClosed     *prometheus.Desc // 因超过最大空闲数关闭的连接数
+	maxIdleTimeClosed *prometheus.Desc // 因超过最大空闲时间关闭的连接数
+	maxLifetimeClosed *prometheus.Desc // 因超过最大复用时间关闭的连接数
+}
+
+// dbStats 数据库连接池状态采集器单例
+var dbStats = newDBStatsCollector()
+
+// newDBStatsCollector 创建数据库连接池状态采集器
+func newDBStatsCollector() *dbStatsCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "orm", name), help, []string{"database"}, nil)
+	}
+
+	return &dbStatsCollector{
+		databases:         make(map[string]*sql.DB),
+		maxOpen:           desc("pool_max_open_connections", "连接池允许的最大连接数"),
+		open:              desc("pool_open_connections", "连接池当前连接数"),
+		inUse:             desc("pool_in_use_connections", "连接池使用中的连接数"),
+		idle:              desc("pool_idle_connections", "连接池空闲连接数"),
+		waitCount:         desc("pool_wait_count_total", "等待连接的总次数"),
+		waitDuration:      desc("pool_wait_duration_seconds_total", "等待连接的总耗时"),
+		maxIdleClosed:     desc("pool_max_idle_closed_total", "因超过最大空闲数关闭的连接数"),
+		maxIdleTimeClosed: desc("pool_max_idle_time_closed_total", "因超过最大空闲时间关闭的连接数"),
+		maxLifetimeClosed: desc("pool_max_lifetime_closed_total", "因超过最大复用时间关闭的连接数"),
+	}
+}
+
+// AddDBStats 添加需要采集连接池状态的数据库实例
+func AddDBStats(name string, db *sql.DB) {
+	dbStats.lock.Lock()
+	dbStats.databases[name] = db
+	dbStats.lock.Unlock()
+}
+
+// RemoveDBStats 移除需要采集连接池状态的数据库实例
+func RemoveDBStats(name string) {
+	dbStats.lock.Lock()
+	delete(dbStats.databases, name)
+	dbStats.lock.Unlock()
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect 实现prometheus.Collector接口
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for name, db := range c.databases {
+		stats := db.Stats()
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse), name)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle), name)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), name)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), name)
+		ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), name)
+	}
+}
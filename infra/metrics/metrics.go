@@ -0,0 +1,234 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 10:12:31
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 10:12:31
+ * @FilePath: \common\infra\metrics\metrics.go
+ * @Description: 基础设施监控指标定义
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package metrics
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 常量相关定义
+const (
+	namespace = "infra" // 监控指标命名空间
+)
+
+// infraMetrics 基础设施监控指标集合
+type infraMetrics struct {
+	ormQueries        *prometheus.CounterVec   // orm查询次数
+	ormQueryErrors    *prometheus.CounterVec   // orm查询失败次数
+	ormQueryDuration  *prometheus.HistogramVec // orm查询耗时
+	redisCommands     *prometheus.HistogramVec // redis命令耗时
+	redisErrors       *prometheus.CounterVec   // redis命令失败次数
+	ossBytes          *prometheus.CounterVec   // oss传输字节数
+	ossDuration       *prometheus.HistogramVec // oss传输耗时
+	ossRetries        *prometheus.CounterVec   // oss重试次数
+	jobRuns           *prometheus.CounterVec   // 定时任务执行次数
+	jobFailures       *prometheus.CounterVec   // 定时任务失败次数
+	jobDuration       *prometheus.HistogramVec // 定时任务执行耗时
+	jobLockContention *prometheus.CounterVec   // 定时任务抢锁失败次数
+	configReloads     *prometheus.CounterVec   // 配置重新加载次数
+	configFailures    *prometheus.CounterVec   // 配置重新加载失败次数
+}
+
+// singleton 监控指标单例对象，未注册时为空，此时所有观测方法都不做任何操作
+var singleton atomic.Pointer[infraMetrics]
+
+// Register 将所有基础设施监控指标注册到调用方提供的注册器中，未调用该方法时不采集任何指标
+func Register(registerer prometheus.Registerer) error {
+	if registerer == nil {
+		return errors.New("监控指标注册器不能为空")
+	}
+
+	m := &infraMetrics{
+		ormQueries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "orm", Name: "queries_total",
+			Help: "orm执行的数据库操作次数",
+		}, []string{"table", "operation"}),
+		ormQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "orm", Name: "query_errors_total",
+			Help: "orm执行失败的数据库操作次数",
+		}, []string{"table", "operation"}),
+		ormQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "orm", Name: "query_duration_seconds",
+			Help:    "orm执行数据库操作的耗时",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"table", "operation"}),
+		redisCommands: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "redis", Name: "command_duration_seconds",
+			Help:    "redis命令执行耗时",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command"}),
+		redisErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "redis", Name: "command_errors_total",
+			Help: "redis命令执行失败次数",
+		}, []string{"command"}),
+		ossBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "oss", Name: "transfer_bytes_total",
+			Help: "oss上传下载的字节数",
+		}, []string{"vendor", "operation"}),
+		ossDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "oss", Name: "transfer_duration_seconds",
+			Help:    "oss上传下载的耗时",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"vendor", "operation"}),
+		ossRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "oss", Name: "retries_total",
+			Help: "oss上传重试次数",
+		}, []string{"vendor", "operation"}),
+		jobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "job", Name: "runs_total",
+			Help: "定时任务执行次数",
+		}, []string{"job"}),
+		jobFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "job", Name: "failures_total",
+			Help: "定时任务执行失败次数",
+		}, []string{"job"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "job", Name: "duration_seconds",
+			Help:    "定时任务执行耗时",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 600, 1800},
+		}, []string{"job"}),
+		jobLockContention: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "job", Name: "lock_contention_total",
+			Help: "分布式定时任务抢锁失败次数",
+		}, []string{"job"}),
+		configReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "config", Name: "reloads_total",
+			Help: "配置重新加载次数",
+		}, []string{"module"}),
+		configFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "config", Name: "reload_failures_total",
+			Help: "配置重新加载失败次数",
+		}, []string{"module"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.ormQueries, m.ormQueryErrors, m.ormQueryDuration,
+		m.redisCommands, m.redisErrors,
+		m.ossBytes, m.ossDuration, m.ossRetries,
+		m.jobRuns, m.jobFailures, m.jobDuration, m.jobLockContention,
+		m.configReloads, m.configFailures,
+		dbStats,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return errors.Wrap(err, "注册基础设施监控指标失败")
+		}
+	}
+
+	singleton.Store(m)
+	return nil
+}
+
+// ObserveOrmQuery 记录一次orm数据库操作
+func ObserveOrmQuery(table string, operation string, duration time.Duration, err error) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.ormQueries.WithLabelValues(table, operation).Inc()
+	m.ormQueryDuration.WithLabelValues(table, operation).Observe(duration.Seconds())
+	if err != nil {
+		m.ormQueryErrors.WithLabelValues(table, operation).Inc()
+	}
+}
+
+// ObserveRedisCommand 记录一次redis命令执行
+func ObserveRedisCommand(command string, duration time.Duration, err error) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.redisCommands.WithLabelValues(command).Observe(duration.Seconds())
+	if err != nil {
+		m.redisErrors.WithLabelValues(command).Inc()
+	}
+}
+
+// ObserveOssTransfer 记录一次oss上传或下载
+func ObserveOssTransfer(vendor string, operation string, bytes int64, duration time.Duration) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	if bytes > 0 {
+		m.ossBytes.WithLabelValues(vendor, operation).Add(float64(bytes))
+	}
+	m.ossDuration.WithLabelValues(vendor, operation).Observe(duration.Seconds())
+}
+
+// IncOssRetry 记录一次oss重试
+func IncOssRetry(vendor string, operation string) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.ossRetries.WithLabelValues(vendor, operation).Inc()
+}
+
+// ObserveJobRun 记录一次定时任务执行
+func ObserveJobRun(job string, duration time.Duration, err error) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.jobRuns.WithLabelValues(job).Inc()
+	m.jobDuration.WithLabelValues(job).Observe(duration.Seconds())
+	if err != nil {
+		m.jobFailures.WithLabelValues(job).Inc()
+	}
+}
+
+// IncJobLockContention 记录一次分布式定时任务抢锁失败
+func IncJobLockContention(job string) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.jobLockContention.WithLabelValues(job).Inc()
+}
+
+// ObserveConfigReload 记录一次配置重新加载
+func ObserveConfigReload(module string, err error) {
+	m := singleton.Load()
+	if m == nil {
+		return
+	}
+
+	m.configReloads.WithLabelValues(module).Inc()
+	if err != nil {
+		m.configFailures.WithLabelValues(module).Inc()
+	}
+}
+
+// CountingReader 统计读取字节数的io.Reader封装，用于无法预知大小的数据流上传
+type CountingReader struct {
+	Reader io.Reader // 原始数据流
+	Count  int64     // 已读取的字节数
+}
+
+// Read 实现io.Reader接口
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.Count += int64(n)
+	return n, err
+}
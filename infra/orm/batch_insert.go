@@ -0,0 +1,229 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-20 11:08:26
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-20 11:08:26
+ * @FilePath: \common\infra\orm\batch_insert.go
+ * @Description: 分批插入实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// defaultBatchSize 未配置批量大小时每批插入的数据条数
+const defaultBatchSize = 500
+
+// BatchInsertWithOptions 分批插入切片数据(带上下文和批量选项)，某一批插入失败时停止插入并返回错误
+func (c *dbConnection) BatchInsertWithOptions(ctx context.Context, values interface{}, options common.BatchOptions) error {
+	sliceValues := reflect.ValueOf(values)
+	if sliceValues.Kind() != reflect.Slice {
+		return errors.New("批量插入的参数必须为结构体切片")
+	}
+
+	if sliceValues.Len() == 0 {
+		return errors.New("批量插入的参数切片不能为空")
+	}
+
+	index := 0
+	return c.batchInsert(ctx, options, func() (interface{}, bool) {
+		if index >= sliceValues.Len() {
+			return nil, false
+		}
+		index++
+		return sliceValues.Index(index - 1).Interface(), true
+	})
+}
+
+// BatchInsertStream 分批插入通道中的数据，通道关闭后插入剩余数据并返回，适用于无法一次性加载到内存的数据集
+func (c *dbConnection) BatchInsertStream(ctx context.Context, values <-chan interface{}, options common.BatchOptions) error {
+	if values == nil {
+		return errors.New("批量插入的数据通道不能为空")
+	}
+
+	return c.batchInsert(ctx, options, func() (interface{}, bool) {
+		select {
+		case value, ok := <-values:
+			return value, ok
+		case <-ctx.Done():
+			return nil, false
+		}
+	})
+}
+
+// batchInsert 从next中依次读取数据，每凑满一批执行一次插入
+func (c *dbConnection) batchInsert(ctx context.Context, options common.BatchOptions, next func() (interface{}, bool)) (err error) {
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = c.config.BatchSize
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultBatchSize
+	}
+
+	// 需要在同一个事务中插入所有批次，且当前没有事务时，由这里负责开启和结束事务
+	if options.InTransaction && c.tx == nil && c.config.Type != "clickhouse" {
+		if err := c.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				c.Rollback()
+				return
+			}
+			err = c.Commit()
+		}()
+	}
+
+	// ClickHouse驱动在一个事务中只能写入一个数据块，调用者已经开启事务时所有数据作为一批写入
+	singleBlock := c.config.Type == "clickhouse" && c.tx != nil
+
+	progress := common.BatchProgress{}
+	chunk := make([]interface{}, 0, chunkSize)
+	flush := func() error {
+		progress.Chunk++
+		progress.Rows = len(chunk)
+		progress.Err = c.insertChunk(ctx, chunk)
+		if progress.Err == nil {
+			progress.Total += len(chunk)
+		}
+
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+
+		if progress.Err != nil {
+			return errors.WithMessagef(progress.Err, "第%d批数据插入失败(已插入%d条)", progress.Chunk, progress.Total)
+		}
+
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for {
+		value, ok := next()
+		if !ok {
+			break
+		}
+
+		chunk = append(chunk, value)
+		if !singleBlock && len(chunk) >= chunkSize {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 数据流因上下文对象取消而结束时，不再插入剩余数据
+	if err = ctx.Err(); err != nil {
+		return errors.WithMessagef(err, "批量插入被取消(已插入%d条)", progress.Total)
+	}
+
+	if len(chunk) > 0 {
+		err = flush()
+	}
+	return err
+}
+
+// insertChunk 插入一批数据
+func (c *dbConnection) insertChunk(ctx context.Context, chunk []interface{}) error {
+	if c.config.Type == "clickhouse" {
+		return c.insertClickHouseChunk(ctx, chunk)
+	}
+
+	query, err := c.createInsertSql(chunk...)
+	if err != nil {
+		return err
+	}
+
+	return c.execute(ctx, "batch_insert", query, chunk, func(ctx context.Context) (int64, error) {
+		// 如果事务实例存在，则使用事务批量插入
+		if c.tx != nil {
+			result, err := c.tx.NamedExecContext(ctx, query, chunk)
+			return affectedRows(result), err
+		}
+
+		result, err := c.db.NamedExecContext(ctx, query, chunk)
+		return affectedRows(result), err
+	})
+}
+
+// insertClickHouseChunk 使用ClickHouse驱动的批量写入方式插入一批数据：开启批次、逐行写入预编译语句、提交时一次性发送
+func (c *dbConnection) insertClickHouseChunk(ctx context.Context, chunk []interface{}) error {
+	model, _, err := parseModel(chunk[0])
+	if err != nil {
+		return err
+	}
+
+	columns := model.columnNames()
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.tableName, strings.Join(columns, ","), placeholders)
+
+	return c.execute(ctx, "batch_insert", query, chunk, func(ctx context.Context) (int64, error) {
+		// 已经开启事务时数据在事务提交时发送，否则每批数据单独开启一个批次
+		tx := c.tx
+		if tx != nil {
+			if c.txBlock {
+				return 0, errors.Errorf("ClickHouse数据表(%s)在一个事务中只能写入一个数据块，请将数据合并为一次批量插入或者在事务外插入", c.tableName)
+			}
+			c.txBlock = true
+		} else {
+			var err error
+			tx, err = c.db.BeginTxx(ctx, nil)
+			if err != nil {
+				return 0, err
+			}
+			defer tx.Rollback()
+		}
+
+		if err := writeRows(ctx, tx, query, model, chunk); err != nil {
+			return 0, err
+		}
+
+		if c.tx == nil {
+			return int64(len(chunk)), tx.Commit()
+		}
+		return int64(len(chunk)), nil
+	})
+}
+
+// writeRows 逐行写入预编译的插入语句
+func writeRows(ctx context.Context, tx *sqlx.Tx, query string, model *modelInfo, rows []interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(model.fields))
+	for _, row := range rows {
+		rowModel, rowValue, err := parseModel(row)
+		if err != nil {
+			return err
+		}
+		if rowModel != model {
+			return errors.New("批量插入的数据类型必须一致")
+		}
+
+		for i, field := range model.fields {
+			args[i] = field.value(rowValue)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
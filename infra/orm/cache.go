@@ -0,0 +1,308 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-23 15:12:46
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-23 15:12:46
+ * @FilePath: \common\infra\orm\cache.go
+ * @Description: 基于Redis的查询结果缓存
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/hongliu9527/go-tools/logger"
+)
+
+// 查询缓存相关定义
+const (
+	defaultCacheTTL       = time.Minute      // 未指定缓存有效期时的默认值
+	cacheKeyPrefix        = "orm:cache:"     // 查询结果缓存键前缀
+	cacheGenerationPrefix = "orm:cache:gen:" // 数据表缓存版本键前缀
+)
+
+// queryCache 查询结果缓存，缓存键包含数据表的缓存版本，写操作通过更新缓存版本使旧的缓存整体失效(旧数据由有效期自动清理)
+type queryCache struct {
+	redis  common.Redis        // Redis实例
+	ttl    time.Duration       // 缓存有效期
+	tables map[string]struct{} // 启用缓存的数据表，为空时所有数据表都启用缓存
+	flight flightGroup         // 相同查询的并发请求合并
+}
+
+// newQueryCache 创建查询结果缓存
+func newQueryCache(redis common.Redis, ttl time.Duration, tables []string) *queryCache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	cache := &queryCache{
+		redis:  redis,
+		ttl:    ttl,
+		tables: make(map[string]struct{}, len(tables)),
+		flight: flightGroup{calls: make(map[string]*flightCall)},
+	}
+	for _, tableName := range tables {
+		cache.tables[tableName] = struct{}{}
+	}
+	return cache
+}
+
+// enabled 判断数据表是否启用了缓存
+func (q *queryCache) enabled(tableName string) bool {
+	if q == nil {
+		return false
+	}
+	if len(q.tables) == 0 {
+		return true
+	}
+
+	_, ok := q.tables[tableName]
+	return ok
+}
+
+// generation 查询数据表当前的缓存版本，不存在时初始化，scope为数据库配置名称和数据表名称组成的缓存范围
+func (q *queryCache) generation(scope string) (string, error) {
+	key := cacheGenerationPrefix + scope
+	generation, err := q.redis.Get(key)
+	if err == nil {
+		return generation, nil
+	}
+	if !errors.Is(err, common.ErrKeyNotFound) {
+		return "", err
+	}
+
+	// 版本不存在时初始化，并发初始化时以先写入的为准
+	generation = strconv.FormatInt(time.Now().UnixNano(), 10)
+	ok, err := q.redis.SetNX(key, generation, 0)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return generation, nil
+	}
+	return q.redis.Get(key)
+}
+
+// invalidate 更新缓存范围的缓存版本，使该范围所有已缓存的查询结果失效
+func (q *queryCache) invalidate(scope string) error {
+	return q.redis.Set(cacheGenerationPrefix+scope, strconv.FormatInt(time.Now().UnixNano(), 10), 0)
+}
+
+// key 根据缓存范围、缓存版本、操作类型、sql语句和参数生成缓存键
+func (q *queryCache) key(scope string, generation string, operation string, query string, args []interface{}) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(operation))
+	hash.Write([]byte{0})
+	hash.Write([]byte(query))
+	hash.Write([]byte{0})
+	hash.Write(data)
+	return cacheKeyPrefix + scope + ":" + generation + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cacheScope 查询缓存范围，不同数据库中的同名数据表使用不同的缓存
+func (c *dbConnection) cacheScope() string {
+	return c.config.Name + ":" + c.tableName
+}
+
+// flightContext 合并查询使用的上下文，只读取发起请求的上下文中的值(例如链路)，取消和超时跟随连接的上下文
+// 避免发起请求的调用方取消后，所有等待共享结果的请求都失败
+type flightContext struct {
+	context.Context                 // 连接的上下文对象
+	values          context.Context // 发起请求的上下文对象
+}
+
+// Value 读取发起请求的上下文中的值
+func (f flightContext) Value(key interface{}) interface{} {
+	return f.values.Value(key)
+}
+
+// cachedQuery 优先从缓存读取查询结果，未命中时执行load查询数据库并写入缓存
+// 事务中的查询以及未启用缓存的数据表直接查询数据库；缓存读写失败不影响查询，只记录警告日志
+func (c *dbConnection) cachedQuery(ctx context.Context, operation string, dest interface{}, query string, args []interface{}, load func(ctx context.Context, dest interface{}) error) error {
+	destValue := reflect.ValueOf(dest)
+	if c.cache == nil || c.tx != nil || destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return load(ctx, dest)
+	}
+
+	scope := c.cacheScope()
+	generation, err := c.cache.generation(scope)
+	if err != nil {
+		logger.Warning("查询数据表(%s)的缓存版本失败(%s)", c.tableName, err.Error())
+		return load(ctx, dest)
+	}
+
+	key, err := c.cache.key(scope, generation, operation, query, args)
+	if err != nil {
+		return load(ctx, dest)
+	}
+
+	if data, err := c.cache.redis.Get(key); err == nil {
+		if err := decodeCache([]byte(data), dest); err == nil {
+			return nil
+		}
+	}
+
+	// 相同查询的并发请求只查询一次数据库，其余请求共享查询结果
+	// 查询结果读取到新分配的值中，调用方取消后不再等待，也不会在返回后修改调用方的dest
+	parent := c.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	result, err := c.cache.flight.do(ctx, key, func() (flightResult, error) {
+		value := reflect.New(destValue.Type().Elem()).Interface()
+		if err := load(flightContext{Context: parent, values: ctx}, value); err != nil {
+			return flightResult{}, err
+		}
+
+		// 无法编码的查询结果(例如包含interface{}字段或者只有未导出字段的自定义类型)不缓存，等待中的请求各自查询数据库
+		data, err := encodeCache(value)
+		if err != nil {
+			logger.Warning("编码数据表(%s)的查询结果失败，不缓存该查询(%s)", c.tableName, err.Error())
+			return flightResult{value: value}, nil
+		}
+		if err := c.cache.redis.Set(key, data, c.cache.ttl); err != nil {
+			logger.Warning("写入数据表(%s)的查询缓存失败(%s)", c.tableName, err.Error())
+		}
+		return flightResult{data: data, value: value}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 发起查询的请求直接使用查询结果，其余请求解码各自的副本，避免共享切片和指针
+	if !result.shared {
+		destValue.Elem().Set(reflect.ValueOf(result.value).Elem())
+		return nil
+	}
+	if result.data == nil {
+		return load(ctx, dest)
+	}
+	return decodeCache(result.data, dest)
+}
+
+// encodeCache 使用gob编码查询结果，gob按导出字段编码，与json标签无关，保证json:"-"等字段也能缓存
+func encodeCache(dest interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(dest); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeCache 将缓存的查询结果解码到dest，先解码到新分配的零值再整体赋值
+// gob不编码零值字段，直接解码到dest会保留dest中原有的值
+func decodeCache(data []byte, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return errors.New("传入的参数必须是非空指针")
+	}
+
+	fresh := reflect.New(destValue.Type().Elem())
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(fresh.Interface()); err != nil {
+		return err
+	}
+
+	// gob将空切片解码为nil，与直接查询数据库的结果(空切片)保持一致
+	if fresh.Elem().Kind() == reflect.Slice && fresh.Elem().IsNil() {
+		fresh.Elem().Set(reflect.MakeSlice(fresh.Elem().Type(), 0, 0))
+	}
+	destValue.Elem().Set(fresh.Elem())
+	return nil
+}
+
+// invalidateCache 写操作成功后使数据表的查询缓存失效，事务中的写操作在提交事务后失效
+func (c *dbConnection) invalidateCache() {
+	if c.cache == nil {
+		return
+	}
+
+	if c.tx != nil {
+		c.cacheDirty = true
+		return
+	}
+
+	c.cacheDirty = false
+	if err := c.cache.invalidate(c.cacheScope()); err != nil {
+		logger.Warning("清除数据表(%s)的查询缓存失败(%s)", c.tableName, err.Error())
+	}
+}
+
+// readOperations 不修改数据的操作类型，其余操作执行成功后使查询缓存失效
+var readOperations = map[string]struct{}{
+	"get":      {},
+	"select":   {},
+	"query":    {},
+	"begin":    {},
+	"commit":   {},
+	"rollback": {},
+}
+
+// flightResult 合并查询的结果
+type flightResult struct {
+	data   []byte      // 编码后的查询结果，无法编码时为空
+	value  interface{} // 查询结果(指向新分配值的指针)
+	shared bool        // 结果是否来自其他请求发起的查询
+}
+
+// flightCall 正在执行的查询
+type flightCall struct {
+	done   chan struct{} // 查询完成时关闭
+	result flightResult  // 查询结果
+	err    error         // 查询错误
+}
+
+// flightGroup 合并相同键的并发查询
+type flightGroup struct {
+	mutex sync.Mutex             // 查询哈希表互斥锁
+	calls map[string]*flightCall // 键-正在执行的查询哈希表
+}
+
+// do 执行查询，相同键的查询正在执行时等待其完成并共享结果
+// 查询在独立的协程中执行，每个请求只等待到自己的上下文取消为止，不影响其他请求
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (flightResult, error)) (flightResult, error) {
+	g.mutex.Lock()
+	call, shared := g.calls[key]
+	if !shared {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go func() {
+			defer func() {
+				g.mutex.Lock()
+				delete(g.calls, key)
+				g.mutex.Unlock()
+				close(call.done)
+			}()
+			call.result, call.err = fn()
+		}()
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		result := call.result
+		result.shared = shared
+		return result, call.err
+	case <-ctx.Done():
+		return flightResult{}, ctx.Err()
+	}
+}
@@ -0,0 +1,249 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// cachedUser 查询缓存编码测试数据模型
+type cachedUser struct {
+	ID       int64          `db:"id" json:"id"`
+	Password string         `db:"password" json:"-"`
+	Nickname sql.NullString `db:"nickname"`
+	Birthday time.Time      `db:"birthday"`
+	Score    *int           `db:"score"`
+}
+
+func TestCacheCodec(t *testing.T) {
+	score := 90
+	origin := cachedUser{
+		ID:       1,
+		Password: "secret",
+		Nickname: sql.NullString{String: "alice", Valid: true},
+		Birthday: time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC),
+		Score:    &score,
+	}
+
+	data, err := encodeCache(&origin)
+	if err != nil {
+		t.Fatalf("编码查询结果失败(%s)", err)
+	}
+
+	// json:"-"字段和sql.Scanner类型同样需要缓存
+	var user cachedUser
+	if err := decodeCache(data, &user); err != nil {
+		t.Fatalf("解码查询结果失败(%s)", err)
+	}
+	if user.ID != origin.ID || user.Password != origin.Password || user.Nickname != origin.Nickname ||
+		!user.Birthday.Equal(origin.Birthday) || user.Score == nil || *user.Score != score {
+		t.Fatalf("解码结果错误(%+v)", user)
+	}
+
+	// 零值字段不能保留目标中原有的值
+	data, err = encodeCache(&cachedUser{ID: 2})
+	if err != nil {
+		t.Fatalf("编码查询结果失败(%s)", err)
+	}
+	if err := decodeCache(data, &user); err != nil {
+		t.Fatalf("解码查询结果失败(%s)", err)
+	}
+	if user.ID != 2 || user.Password != "" || user.Nickname.Valid || user.Score != nil {
+		t.Fatalf("零值字段保留了原有的值(%+v)", user)
+	}
+
+	// 空切片解码后仍然是空切片
+	data, err = encodeCache(&[]cachedUser{})
+	if err != nil {
+		t.Fatalf("编码空切片失败(%s)", err)
+	}
+	users := []cachedUser{{ID: 3}}
+	if err := decodeCache(data, &users); err != nil {
+		t.Fatalf("解码空切片失败(%s)", err)
+	}
+	if users == nil || len(users) != 0 {
+		t.Fatalf("空切片的解码结果错误(%v)", users)
+	}
+
+	// 无法编码的类型返回错误，由调用方跳过缓存
+	if _, err := encodeCache(&[]interface{}{struct{ value int }{1}}); err == nil {
+		t.Fatal("无法编码的类型应该返回错误")
+	}
+}
+
+// fakeRedis 测试用Redis，只实现查询缓存用到的接口
+type fakeRedis struct {
+	common.Redis
+	mutex  sync.Mutex
+	values map[string]string
+}
+
+// Get 读取键值
+func (r *fakeRedis) Get(key string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return "", common.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// Set 写入键值
+func (r *fakeRedis) Set(key string, value interface{}, expiration time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if data, ok := value.([]byte); ok {
+		value = string(data)
+	}
+	r.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+// SetNX 键不存在时写入键值
+func (r *fakeRedis) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	r.mutex.Lock()
+	_, ok := r.values[key]
+	r.mutex.Unlock()
+	if ok {
+		return false, nil
+	}
+	return true, r.Set(key, value, expiration)
+}
+
+// newCachedConnection 创建启用查询缓存的数据库连接
+func newCachedConnection(cache *queryCache, name string) *dbConnection {
+	return &dbConnection{
+		ctx:       context.Background(),
+		config:    ormConfig.DataBaseConfig{Name: name},
+		tableName: "user",
+		cache:     cache,
+	}
+}
+
+func TestQueryCacheInvalidation(t *testing.T) {
+	cache := newQueryCache(&fakeRedis{values: make(map[string]string)}, time.Minute, nil)
+	connA := newCachedConnection(cache, "a.mysql")
+	connB := newCachedConnection(cache, "b.mysql")
+
+	loads := map[string]int{}
+	query := func(conn *dbConnection) []int64 {
+		var ids []int64
+		err := conn.cachedQuery(context.Background(), "select", &ids, "SELECT id FROM user", nil, func(ctx context.Context, dest interface{}) error {
+			loads[conn.config.Name]++
+			*dest.(*[]int64) = []int64{int64(len(conn.config.Name)), int64(loads[conn.config.Name])}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("查询失败(%s)", err)
+		}
+		return ids
+	}
+
+	first := query(connA)
+	if cached := query(connA); !reflect.DeepEqual(cached, first) || loads["a.mysql"] != 1 {
+		t.Fatalf("第二次查询应该命中缓存(%v, %d)", cached, loads["a.mysql"])
+	}
+
+	// 不同数据库中的同名数据表不能共享缓存
+	query(connB)
+	if loads["b.mysql"] != 1 {
+		t.Fatalf("其他数据库的同名数据表不应该命中缓存(%d)", loads["b.mysql"])
+	}
+
+	// 写操作只使本数据库的数据表缓存失效
+	connA.invalidateCache()
+	if ids := query(connA); loads["a.mysql"] != 2 || ids[1] != 2 {
+		t.Fatalf("缓存失效后应该重新查询数据库(%v, %d)", ids, loads["a.mysql"])
+	}
+	query(connB)
+	if loads["b.mysql"] != 1 {
+		t.Fatalf("其他数据库的缓存不应该失效(%d)", loads["b.mysql"])
+	}
+
+	// 事务中的写操作在提交事务后才使缓存失效
+	connA.tx = &sqlx.Tx{}
+	connA.invalidateCache()
+	connA.tx = nil
+	if query(connA); loads["a.mysql"] != 2 || !connA.cacheDirty {
+		t.Fatalf("事务中的写操作不应该立即使缓存失效(%d)", loads["a.mysql"])
+	}
+}
+
+func TestQueryCacheSingleflight(t *testing.T) {
+	cache := newQueryCache(&fakeRedis{values: make(map[string]string)}, time.Minute, nil)
+	conn := newCachedConnection(cache, "a.mysql")
+
+	var loads int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loadErr := make(chan error, 1)
+	load := func(ctx context.Context, dest interface{}) error {
+		atomic.AddInt32(&loads, 1)
+		close(started)
+		<-release
+		loadErr <- ctx.Err()
+		*dest.(*[]int64) = []int64{1, 2}
+		return nil
+	}
+
+	// 发起查询的请求
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		var ids []int64
+		leaderDone <- conn.cachedQuery(leaderCtx, "select", &ids, "SELECT id FROM user", nil, load)
+	}()
+	<-started
+
+	// 等待共享结果的请求
+	const followers = 5
+	var wg sync.WaitGroup
+	results := make([][]int64, followers)
+	errs := make([]error, followers)
+	for i := 0; i < followers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = conn.cachedQuery(context.Background(), "select", &results[i], "SELECT id FROM user", nil, load)
+		}(i)
+	}
+	// 等待请求进入合并查询，晚到的请求会直接命中缓存，同样不会查询数据库
+	time.Sleep(10 * time.Millisecond)
+
+	// 发起查询的请求取消后立即返回，不影响正在执行的查询和其他请求
+	cancel()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消的请求应该返回上下文错误(%v)", err)
+	}
+	close(release)
+	wg.Wait()
+
+	if err := <-loadErr; err != nil {
+		t.Fatalf("合并查询的上下文不应该随发起请求取消(%s)", err)
+	}
+	if loads := atomic.LoadInt32(&loads); loads != 1 {
+		t.Fatalf("并发的相同查询应该只查询一次数据库(%d)", loads)
+	}
+	for i := 0; i < followers; i++ {
+		if errs[i] != nil || !reflect.DeepEqual(results[i], []int64{1, 2}) {
+			t.Fatalf("等待的请求应该共享查询结果(%v, %v)", results[i], errs[i])
+		}
+	}
+	// 每个请求解码各自的副本
+	results[0][0] = 9
+	if results[1][0] != 1 {
+		t.Fatal("等待的请求不应该共享同一个切片")
+	}
+}
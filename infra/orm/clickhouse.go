@@ -0,0 +1,410 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-22 14:12:50
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-22 14:12:50
+ * @FilePath: \common\infra\orm\clickhouse.go
+ * @Description: ClickHouse专用连接实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/ClickHouse/clickhouse-go"
+	"github.com/pkg/errors"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.ClickHouseOrm = (*clickHouseConnection)(nil)
+
+// defaultMutationPollInterval 未指定轮询间隔时查询变更状态的间隔
+const defaultMutationPollInterval = time.Second
+
+// identifierPattern 列名和查询设置名称的格式，避免拼接到sql语句中时出现注入
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// clickHouseConnection ClickHouse专用连接，事务和行级更新删除操作直接返回ErrUnsupported
+type clickHouseConnection struct {
+	*dbConnection
+}
+
+// unsupported 构建不支持操作的错误
+func (c *clickHouseConnection) unsupported(operation string, suggestion string) error {
+	return errors.WithMessagef(ErrUnsupported, "ClickHouse数据表(%s)不支持%s，%s", c.tableName, operation, suggestion)
+}
+
+// Begin 开启事务(不支持)
+func (c *clickHouseConnection) Begin() error {
+	return c.unsupported("事务", "写入数据请使用BatchInsert或者ColumnarInsert")
+}
+
+// BeginTx 开启事务(不支持)
+func (c *clickHouseConnection) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	return c.Begin()
+}
+
+// Commit 提交事务(不支持)
+func (c *clickHouseConnection) Commit() error {
+	return c.Begin()
+}
+
+// Rollback 回滚事务(不支持)
+func (c *clickHouseConnection) Rollback() error {
+	return c.Begin()
+}
+
+// WithTx 在事务中执行函数(不支持)
+func (c *clickHouseConnection) WithTx(ctx context.Context, fn func(tx common.Orm) error) error {
+	return c.Begin()
+}
+
+// Insert 插入单个数据，ClickHouse驱动只支持批量写入方式，使用单行批次插入
+func (c *clickHouseConnection) Insert(value interface{}) (common.Result, error) {
+	return c.InsertContext(c.ctx, value)
+}
+
+// InsertContext 插入单个数据(带上下文)，频繁的单行插入会在服务端产生大量数据分片，请优先使用BatchInsert或者AsyncInsert
+func (c *clickHouseConnection) InsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	if err := c.insertClickHouseChunk(ctx, []interface{}{value}); err != nil {
+		return common.Result{}, err
+	}
+	return common.Result{RowsAffected: 1}, nil
+}
+
+// Update 更新数据(不支持)
+func (c *clickHouseConnection) Update(condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return c.UpdateContext(c.ctx, condition, updateValue)
+}
+
+// UpdateContext 更新数据(不支持)
+func (c *clickHouseConnection) UpdateContext(ctx context.Context, condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("行级更新", "请使用MutateUpdate提交异步变更")
+}
+
+// UpdateByPK 根据主键更新结构体数据(不支持)
+func (c *clickHouseConnection) UpdateByPK(value interface{}, columns ...string) (common.Result, error) {
+	return c.UpdateContext(c.ctx, "", nil)
+}
+
+// UpdateByPKContext 根据主键更新结构体数据(不支持)
+func (c *clickHouseConnection) UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (common.Result, error) {
+	return c.UpdateContext(ctx, "", nil)
+}
+
+// UpdateStruct 根据主键更新结构体中发生变化的字段(不支持)
+func (c *clickHouseConnection) UpdateStruct(origin interface{}, value interface{}) (common.Result, error) {
+	return c.UpdateContext(c.ctx, "", nil)
+}
+
+// UpdateStructContext 根据主键更新结构体中发生变化的字段(不支持)
+func (c *clickHouseConnection) UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (common.Result, error) {
+	return c.UpdateContext(ctx, "", nil)
+}
+
+// Delete 删除数据(不支持)
+func (c *clickHouseConnection) Delete(condition string, args ...interface{}) (common.Result, error) {
+	return c.DeleteContext(c.ctx, condition, args...)
+}
+
+// DeleteContext 删除数据(不支持)
+func (c *clickHouseConnection) DeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("行级删除", "请使用MutateDelete提交异步变更")
+}
+
+// ForceDelete 物理删除数据(不支持)
+func (c *clickHouseConnection) ForceDelete(condition string, args ...interface{}) (common.Result, error) {
+	return c.DeleteContext(c.ctx, condition, args...)
+}
+
+// ForceDeleteContext 物理删除数据(不支持)
+func (c *clickHouseConnection) ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return c.DeleteContext(ctx, condition, args...)
+}
+
+// AsyncInsert 使用服务端异步插入(async_insert)写入结构体或结构体切片，适用于大量小批次写入的场景
+// wait为false时服务端接收数据后立即返回，数据在服务端缓冲区刷新后才可查询，服务端异常时可能丢失
+func (c *clickHouseConnection) AsyncInsert(ctx context.Context, values interface{}, wait bool) error {
+	rows := make([]interface{}, 0)
+	reflectValue := reflect.ValueOf(values)
+	if reflectValue.Kind() == reflect.Slice {
+		for i := 0; i < reflectValue.Len(); i++ {
+			rows = append(rows, reflectValue.Index(i).Interface())
+		}
+	} else {
+		rows = append(rows, values)
+	}
+	if len(rows) == 0 {
+		return errors.New("异步插入的数据不能为空")
+	}
+
+	model, _, err := parseModel(rows[0])
+	if err != nil {
+		return err
+	}
+
+	waitForAsyncInsert := 0
+	if wait {
+		waitForAsyncInsert = 1
+	}
+	columns := model.columnNames()
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	query := fmt.Sprintf("INSERT INTO %s (%s) SETTINGS async_insert=1, wait_for_async_insert=%d VALUES (%s)",
+		c.tableName, strings.Join(columns, ","), waitForAsyncInsert, placeholders)
+
+	return c.execute(ctx, "async_insert", query, rows, func(ctx context.Context) (int64, error) {
+		tx, err := c.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		defer tx.Rollback()
+
+		if err := writeRows(ctx, tx, query, model, rows); err != nil {
+			return 0, err
+		}
+		return int64(len(rows)), tx.Commit()
+	})
+}
+
+// ColumnarInsert 按列批量插入，所有数据组装为一个Native格式的数据块一次性发送
+// data[i]为columns[i]列的值切片(例如[]uint64、[]string、[]time.Time)，所有切片长度必须一致
+func (c *clickHouseConnection) ColumnarInsert(ctx context.Context, columns []string, data ...interface{}) error {
+	if len(columns) == 0 || len(columns) != len(data) {
+		return fmt.Errorf("列名数量(%d)与列数据数量(%d)不一致或者为0", len(columns), len(data))
+	}
+
+	columnValues := make([]reflect.Value, len(data))
+	rowCount := -1
+	for i, values := range data {
+		if !identifierPattern.MatchString(columns[i]) {
+			return fmt.Errorf("列名(%s)格式错误", columns[i])
+		}
+
+		columnValues[i] = reflect.ValueOf(values)
+		if columnValues[i].Kind() != reflect.Slice {
+			return fmt.Errorf("列(%s)的数据必须是切片", columns[i])
+		}
+		if rowCount >= 0 && columnValues[i].Len() != rowCount {
+			return fmt.Errorf("列(%s)的数据条数(%d)与其他列(%d)不一致", columns[i], columnValues[i].Len(), rowCount)
+		}
+		rowCount = columnValues[i].Len()
+	}
+	if rowCount == 0 {
+		return errors.New("按列插入的数据不能为空")
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.tableName, strings.Join(columns, ","), placeholders)
+
+	return c.execute(ctx, "columnar_insert", query, nil, func(ctx context.Context) (int64, error) {
+		conn, err := c.db.Conn(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		err = conn.Raw(func(driverConn interface{}) error {
+			clickhouseConn, ok := driverConn.(clickhouse.Clickhouse)
+			if !ok {
+				return errors.New("数据库驱动不支持按列写入")
+			}
+
+			if _, err := clickhouseConn.Begin(); err != nil {
+				return err
+			}
+			if _, err := clickhouseConn.Prepare(query); err != nil {
+				clickhouseConn.Rollback()
+				return err
+			}
+			block, err := clickhouseConn.Block()
+			if err != nil {
+				clickhouseConn.Rollback()
+				return err
+			}
+
+			row := make([]driver.Value, len(columns))
+			for i := 0; i < rowCount; i++ {
+				for j := range columnValues {
+					row[j] = columnValues[j].Index(i).Interface()
+				}
+				if err := block.AppendRow(row); err != nil {
+					clickhouseConn.Rollback()
+					return errors.Wrapf(err, "写入第%d行数据失败", i+1)
+				}
+			}
+
+			if err := clickhouseConn.WriteBlock(block); err != nil {
+				clickhouseConn.Rollback()
+				return err
+			}
+			return clickhouseConn.Commit()
+		})
+		return int64(rowCount), err
+	})
+}
+
+// MutateUpdate 提交ALTER TABLE ... UPDATE异步变更，变更在后台执行，可通过WaitMutations等待完成
+func (c *clickHouseConnection) MutateUpdate(ctx context.Context, updateValue map[string]interface{}, condition string, args ...interface{}) error {
+	if condition == "" {
+		return errors.New("变更条件不能为空")
+	}
+	if len(updateValue) == 0 {
+		return errors.New("更新字段不能为空")
+	}
+
+	fields := make([]string, 0, len(updateValue))
+	for field := range updateValue {
+		if !identifierPattern.MatchString(field) {
+			return fmt.Errorf("列名(%s)格式错误", field)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	assignments := make([]string, 0, len(fields))
+	mutateArgs := make([]interface{}, 0, len(fields)+len(args))
+	for _, field := range fields {
+		assignments = append(assignments, field+" = ?")
+		mutateArgs = append(mutateArgs, updateValue[field])
+	}
+	mutateArgs = append(mutateArgs, args...)
+
+	query := fmt.Sprintf("ALTER TABLE %s UPDATE %s WHERE %s", c.tableName, strings.Join(assignments, ", "), condition)
+	_, err := c.exec(ctx, "mutate_update", query, mutateArgs...)
+	return err
+}
+
+// MutateDelete 提交ALTER TABLE ... DELETE异步变更，变更在后台执行，可通过WaitMutations等待完成
+func (c *clickHouseConnection) MutateDelete(ctx context.Context, condition string, args ...interface{}) error {
+	if condition == "" {
+		return errors.New("变更条件不能为空")
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s DELETE WHERE %s", c.tableName, condition)
+	_, err := c.exec(ctx, "mutate_delete", query, args...)
+	return err
+}
+
+// Mutations 查询数据表未完成的变更
+func (c *clickHouseConnection) Mutations(ctx context.Context) ([]common.MutationStatus, error) {
+	mutations := make([]common.MutationStatus, 0)
+	err := c.SelectContext(ctx, &mutations, "SELECT mutation_id, command, create_time, parts_to_do, is_done, latest_fail_reason "+
+		"FROM system.mutations WHERE database = currentDatabase() AND table = ? AND is_done = 0 ORDER BY create_time", c.tableName)
+	return mutations, err
+}
+
+// WaitMutations 轮询等待数据表所有变更完成，有变更执行失败时返回错误
+func (c *clickHouseConnection) WaitMutations(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultMutationPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		mutations, err := c.Mutations(ctx)
+		if err != nil {
+			return err
+		}
+		if len(mutations) == 0 {
+			return nil
+		}
+
+		for _, mutation := range mutations {
+			if mutation.LatestFailReason != "" {
+				return fmt.Errorf("数据表(%s)的变更(%s)执行失败(%s)", c.tableName, mutation.MutationId, mutation.LatestFailReason)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "等待数据表(%s)的变更完成超时(剩余%d个)", c.tableName, len(mutations))
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetWithSettings 使用查询设置查询单个数据，例如限制执行时间和线程数
+func (c *clickHouseConnection) GetWithSettings(ctx context.Context, dest interface{}, settings common.ClickHouseSettings, query string, args ...interface{}) error {
+	clause, err := settingsClause(settings)
+	if err != nil {
+		return err
+	}
+	return c.GetContext(ctx, dest, query+clause, args...)
+}
+
+// SelectWithSettings 使用查询设置查询多个数据，例如限制执行时间和线程数
+func (c *clickHouseConnection) SelectWithSettings(ctx context.Context, dest interface{}, settings common.ClickHouseSettings, query string, args ...interface{}) error {
+	clause, err := settingsClause(settings)
+	if err != nil {
+		return err
+	}
+	return c.SelectContext(ctx, dest, query+clause, args...)
+}
+
+// From 构建带FINAL/SAMPLE修饰的表名，例如："SELECT * FROM " + conn.From(true, 0.1)
+// FINAL用于查询前合并ReplacingMergeTree等引擎的重复数据，SAMPLE需要建表时指定采样键
+func (c *clickHouseConnection) From(final bool, sample float64) string {
+	table := c.tableName
+	if final {
+		table += " FINAL"
+	}
+	if sample > 0 && sample < 1 {
+		table += " SAMPLE " + strconv.FormatFloat(sample, 'f', -1, 64)
+	}
+	return table
+}
+
+// settingsClause 将查询设置转换为SETTINGS子句，设置名称按字母排序
+func settingsClause(settings common.ClickHouseSettings) (string, error) {
+	if len(settings) == 0 {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if !identifierPattern.MatchString(name) {
+			return "", fmt.Errorf("查询设置名称(%s)格式错误", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		var value string
+		switch settingValue := settings[name].(type) {
+		case string:
+			value = "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(settingValue) + "'"
+		case bool:
+			value = "0"
+			if settingValue {
+				value = "1"
+			}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			value = fmt.Sprint(settingValue)
+		case time.Duration:
+			value = strconv.FormatInt(int64(settingValue.Seconds()), 10)
+		default:
+			return "", fmt.Errorf("查询设置(%s)的值类型(%T)不支持", name, settingValue)
+		}
+		items = append(items, name+"="+value)
+	}
+
+	return " SETTINGS " + strings.Join(items, ", "), nil
+}
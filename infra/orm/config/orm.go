@@ -46,23 +46,54 @@ var (
 
 // OrmInfraConfig Orm基础设施配置结构定义
 type OrmInfraConfig struct {
-	Configs         []DataBaseConfig               `mapstructure:"configList"` // 数据库基础设施配置列表
-	LogLevel        string                         `mapstructure:"omit"`       // orm基础设施日志等级
-	UseExternalHost bool                           `mapstructure:"omit"`       // 使用外网地址(默认为false)
+	Configs         []DataBaseConfig               `mapstructure:"configList"`                  // 数据库基础设施配置列表
+	LogLevel        string                         `mapstructure:"omit"`                        // orm基础设施日志等级
+	UseExternalHost bool                           `mapstructure:"omit"`                        // 使用外网地址(默认为false)
+	LogFormat       string                         `mapstructure:"logFormat" default:"console"` // sql日志格式(console:终端样式，json:json格式)
 	base.BaseConfig `mapstructure:"omit" yaml:"-"` // 基础配置信息
 }
 
 // DataBaseConfig 数据库配置结构定义
 type DataBaseConfig struct {
-	Name             string `mapstructure:"name" default:"iotplatform.mysql"`          // 配置信息名称，用于区分不同的数据库实例
-	Type             string `mapstructure:"type" default:"mysql"`                      // 数据库类型
-	HostPort         string `mapstructure:"hostPort" default:"127.0.0.1:3306"`         // 数据库外网主机名称或访问地址和访问端口，例如：127.0.0.1:3306
-	InternalHostPort string `mapstructure:"internalHostPort" default:"127.0.0.1:3306"` // 数据库内网主机名称或访问地址和访问端口，例如：127.0.0.1:3306
-	DatabaseName     string `mapstructure:"databaseName" default:"my-blog"`            // 数据库名称
-	Username         string `mapstructure:"username" default:"main"`                   // 数据库访问用户名
-	Password         string `mapstructure:"password" default:"hongliu-2016"`           // 数据库访问密码
-	TablePrefix      string `mapstructure:"tablePrefix" default:"blog_"`               // 表名前缀
-	ConnectTimeout   int    `mapstructure:"connectTimeout" default:"10"`               // 连接超时时间，单位(秒)
+	Name                 string `mapstructure:"name" default:"iotplatform.mysql"`          // 配置信息名称，用于区分不同的数据库实例
+	Type                 string `mapstructure:"type" default:"mysql"`                      // 数据库类型(mysql、tidb、clickhouse、postgres、sqlite，sqlite需要开启cgo并使用-tags sqlite编译)
+	HostPort             string `mapstructure:"hostPort" default:"127.0.0.1:3306"`         // 数据库外网主机名称或访问地址和访问端口，例如：127.0.0.1:3306
+	InternalHostPort     string `mapstructure:"internalHostPort" default:"127.0.0.1:3306"` // 数据库内网主机名称或访问地址和访问端口，例如：127.0.0.1:3306
+	DatabaseName         string `mapstructure:"databaseName" default:"my-blog"`            // 数据库名称，sqlite为数据库文件路径(或者:memory:)
+	Username             string `mapstructure:"username" default:"main"`                   // 数据库访问用户名
+	Password             string `mapstructure:"password" default:"hongliu-2016"`           // 数据库访问密码
+	TablePrefix          string `mapstructure:"tablePrefix" default:"blog_"`               // 表名前缀
+	ConnectTimeout       int    `mapstructure:"connectTimeout" default:"10"`               // 连接超时时间，单位(秒)
+	UpdateTimeColumn     string `mapstructure:"updateTimeColumn" default:"update_time"`    // 更新数据时自动写入当前时间的列名，为空时不自动写入
+	SoftDelete           bool   `mapstructure:"softDelete" default:"false"`                // 是否开启软删除，开启后Delete只写入删除时间列
+	DeleteTimeColumn     string `mapstructure:"deleteTimeColumn" default:"delete_time"`    // 软删除时写入删除时间的列名，未删除的数据该列为NULL
+	BatchSize            int    `mapstructure:"batchSize" default:"500"`                   // 批量插入时每批数据条数
+	IsolationLevel       string `mapstructure:"isolationLevel" default:""`                 // 事务隔离级别(read committed、repeatable read、serializable等)，为空时使用数据库默认值
+	DeadlockRetry        int    `mapstructure:"deadlockRetry" default:"3"`                 // WithTx遇到死锁时重新执行的最大次数
+	MaxIdleConns         int    `mapstructure:"maxIdleConns" default:"10"`                 // 空闲连接池中连接的最大数量
+	MaxOpenConns         int    `mapstructure:"maxOpenConns" default:"100"`                // 打开数据库连接的最大数量，0表示不限制
+	ConnMaxLifetime      int    `mapstructure:"connMaxLifetime" default:"3600"`            // 连接可复用的最大时间，单位(秒)，0表示不限制
+	ConnMaxIdleTime      int    `mapstructure:"connMaxIdleTime" default:"0"`               // 连接空闲的最大时间，单位(秒)，0表示不限制
+	Charset              string `mapstructure:"charset" default:"utf8mb4"`                 // 连接字符集(MySQL/TiDB)
+	Timezone             string `mapstructure:"timezone" default:"Asia/Shanghai"`          // 时区(MySQL/TiDB/SQLite用于解析时间类型，PostgreSQL用于设置会话时区)
+	TLS                  string `mapstructure:"tls" default:""`                            // TLS模式(MySQL/TiDB: true、skip-verify、preferred；ClickHouse: true、skip-verify；PostgreSQL: true(verify-full)、skip-verify(require)或sslmode取值)，为空时不使用TLS
+	Params               string `mapstructure:"params" default:""`                         // 额外的连接参数，格式为key1=value1&key2=value2，会覆盖同名的默认参数
+	TableRefreshInterval int    `mapstructure:"tableRefreshInterval" default:"0"`          // 定期重新查询表名的间隔，单位(秒)，0表示只在找不到表名时重新查询
+	SlowThreshold        int    `mapstructure:"slowThreshold" default:"500"`               // 慢查询阈值，单位(毫秒)，超过后使用warning等级记录sql日志，0表示不检查
+	RedactColumns        string `mapstructure:"redactColumns" default:"password"`          // sql日志中需要脱敏的列名，多个列名使用逗号分隔
+
+	Replicas             []ReplicaConfig `mapstructure:"replicas"`                           // 只读副本列表，为空时读写都使用主库
+	ReplicaPolicy        string          `mapstructure:"replicaPolicy" default:"roundRobin"` // 只读副本选择策略(roundRobin:轮询，leastLatency:最低延迟)
+	ReplicaMaxLag        int             `mapstructure:"replicaMaxLag" default:"10"`         // 只读副本允许的最大复制延迟，单位(秒)，超过后暂停路由到该副本，0表示不检查延迟
+	ReplicaCheckInterval int             `mapstructure:"replicaCheckInterval" default:"5"`   // 只读副本健康检查间隔，单位(秒)
+}
+
+// ReplicaConfig 只读副本配置结构定义，数据库名称等其余配置与主库一致
+type ReplicaConfig struct {
+	HostPort         string `mapstructure:"hostPort" default:""`         // 副本外网主机名称或访问地址和访问端口
+	InternalHostPort string `mapstructure:"internalHostPort" default:""` // 副本内网主机名称或访问地址和访问端口
+	Username         string `mapstructure:"username" default:""`         // 副本访问用户名，为空时使用主库的用户名
+	Password         string `mapstructure:"password" default:""`         // 副本访问密码，为空时使用主库的密码
 }
 
 // New 创建Orm基础设施配置
@@ -12,25 +12,49 @@
 package orm
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/metrics"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+	"github.com/hongliu9527/common/infra/tracing"
 	"github.com/hongliu9527/common/utils"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// deadlockRetryInterval 死锁重试的基础等待时间，第n次重试等待n倍该时间
+const deadlockRetryInterval = 20 * time.Millisecond
+
+// versionUndo 事务中乐观锁版本号加1的恢复记录
+type versionUndo struct {
+	depth int    // 加1时的嵌套事务层数
+	undo  func() // 恢复原版本号
+}
+
 // dbConnection 查询连接
 type dbConnection struct {
-	db          *sqlx.DB // 查询实例
-	tx          *sqlx.Tx // 事务实例
-	serviceName string   // 服务名称，用于日志记录
-	tableName   string   // 当前需要操作的表名称
-	lastError   error    // 实例的最新错误信息
+	db          *sqlx.DB                 // 查询实例
+	tx          *sqlx.Tx                 // 事务实例
+	replicas    *replicaSet              // 只读副本集合(未配置时为空)
+	logger      *sqlLogger               // sql日志记录器(为空时不记录日志)
+	cache       *queryCache              // 查询结果缓存(数据表未启用缓存时为空)
+	cacheDirty  bool                     // 事务中是否执行过写操作，提交事务后需要使查询缓存失效
+	ctx         context.Context          // 上下文对象
+	serviceName string                   // 服务名称，用于日志记录
+	config      ormConfig.DataBaseConfig // 数据库配置信息
+	tableName   string                   // 当前需要操作的表名称
+	txDepth     int                      // 嵌套事务层数(已创建的保存点数量)
+	txBlock     bool                     // ClickHouse事务中是否已经写入过数据块(驱动限制一个事务只能写入一个数据块)
+	txVersions  []versionUndo            // 事务中已经加1的乐观锁版本号，事务或保存点回滚时恢复
+	lastError   error                    // 实例的最新错误信息
 }
 
 // Conn 获取数据库连接句柄
@@ -38,25 +62,102 @@ func (c *dbConnection) Conn(name string) (common.Orm, error) {
 	return c, nil
 }
 
+// execute 执行一次数据库操作，并记录该操作的监控指标、链路和日志
+// args为绑定参数(用于日志记录)，fn返回影响或者查询到的数据行数(未知时为-1)
+func (c *dbConnection) execute(ctx context.Context, operation string, query string, args interface{}, fn func(ctx context.Context) (int64, error)) error {
+	ctx, span := c.startSpan(ctx, operation, query)
+
+	begin := time.Now()
+	rows, err := fn(ctx)
+	err = wrapError(c.tableName, operation, err)
+	duration := time.Since(begin)
+	metrics.ObserveOrmQuery(c.tableName, operation, duration, err)
+	c.logger.log(c, operation, query, args, rows, duration, err)
+
+	tracing.End(span, err)
+
+	if _, ok := readOperations[operation]; !ok && err == nil {
+		c.invalidateCache()
+	}
+	return err
+}
+
+// affectedRows 获取写操作影响的数据行数，数据库驱动不支持时返回-1
+func affectedRows(result sql.Result) int64 {
+	if result == nil {
+		return -1
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
+
+// toResult 转换数据库驱动的执行结果，不支持的字段(例如ClickHouse的自增主键)保持为0
+func toResult(result sql.Result) common.Result {
+	if result == nil {
+		return common.Result{}
+	}
+
+	lastInsertId, _ := result.LastInsertId()
+	rowsAffected, _ := result.RowsAffected()
+	return common.Result{
+		LastInsertId: lastInsertId,
+		RowsAffected: rowsAffected,
+	}
+}
+
+// readDB 获取执行只读查询的实例，有健康的只读副本时使用副本，否则使用主库
+func (c *dbConnection) readDB() *sqlx.DB {
+	if db := c.replicas.pick(); db != nil {
+		return db
+	}
+	return c.db
+}
+
+// queryDB 获取Get/Select查询使用的实例，数据表启用查询缓存时使用主库
+// 避免写操作使缓存失效后，从延迟的只读副本读到旧数据并写入缓存，在整个缓存有效期内返回旧数据
+func (c *dbConnection) queryDB() *sqlx.DB {
+	if c.cache != nil {
+		return c.db
+	}
+	return c.readDB()
+}
+
 // Get 查询单个数据
 func (c *dbConnection) Get(dest interface{}, query string, args ...interface{}) error {
+	return c.GetContext(c.ctx, dest, query, args...)
+}
+
+// GetContext 查询单个数据(带上下文)
+func (c *dbConnection) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	inSql, inArgs, err := sqlx.In(query, args...)
 	if err != nil {
 		return fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
 	}
 
-	// 如果事务实例存在，则使用事务实例执行查询
-	if c.tx != nil {
-		inSql = c.tx.Rebind(inSql)
-		return c.tx.Get(dest, inSql, inArgs...)
-	}
-
-	inSql = c.db.Rebind(inSql)
-	return c.db.Get(dest, inSql, inArgs...)
+	return c.cachedQuery(ctx, "get", dest, inSql, inArgs, func(ctx context.Context, dest interface{}) error {
+		return c.execute(ctx, "get", inSql, inArgs, func(ctx context.Context) (int64, error) {
+			// 如果事务实例存在，则使用事务实例执行查询
+			if c.tx != nil {
+				return 1, c.tx.GetContext(ctx, dest, c.tx.Rebind(inSql), inArgs...)
+			}
+
+			db := c.queryDB()
+			return 1, db.GetContext(ctx, dest, db.Rebind(inSql), inArgs...)
+		})
+	})
 }
 
 // Select 查询多个数据
 func (c *dbConnection) Select(dest interface{}, query string, args ...interface{}) error {
+	return c.SelectContext(c.ctx, dest, query, args...)
+}
+
+// SelectContext 查询多个数据(带上下文)
+func (c *dbConnection) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	// 传入的参数必须是切片的指针类型
 	if !utils.IsSlicePointer(dest) {
 		return errors.New("传入的参数必须是切片指针")
@@ -67,200 +168,702 @@ func (c *dbConnection) Select(dest interface{}, query string, args ...interface{
 		return fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
 	}
 
-	// 如果事务实例存在，则使用事务实例进行查询
-	if c.tx != nil {
-		inSql = c.tx.Rebind(inSql)
-		return c.tx.Select(dest, inSql, inArgs...)
+	return c.cachedQuery(ctx, "select", dest, inSql, inArgs, func(ctx context.Context, dest interface{}) error {
+		return c.execute(ctx, "select", inSql, inArgs, func(ctx context.Context) (int64, error) {
+			// 如果事务实例存在，则使用事务实例进行查询
+			if c.tx != nil {
+				err := c.tx.SelectContext(ctx, dest, c.tx.Rebind(inSql), inArgs...)
+				return int64(reflect.ValueOf(dest).Elem().Len()), err
+			}
+
+			db := c.queryDB()
+			err := db.SelectContext(ctx, dest, db.Rebind(inSql), inArgs...)
+			return int64(reflect.ValueOf(dest).Elem().Len()), err
+		})
+	})
+}
+
+// QueryRows 查询数据并返回逐行读取的结果集
+func (c *dbConnection) QueryRows(query string, args ...interface{}) (common.Rows, error) {
+	return c.QueryRowsContext(c.ctx, query, args...)
+}
+
+// QueryRowsContext 查询数据并返回逐行读取的结果集(带上下文)，适用于无法一次性加载到内存的大结果集
+// 结果集占用一个数据库连接(或者事务)直到关闭，遍历期间不能在同一个事务中执行其他语句
+func (c *dbConnection) QueryRowsContext(ctx context.Context, query string, args ...interface{}) (common.Rows, error) {
+	inSql, inArgs, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
+	}
+
+	var rows *sqlx.Rows
+	err = c.execute(ctx, "query", inSql, inArgs, func(ctx context.Context) (int64, error) {
+		// 如果事务实例存在，则使用事务实例进行查询
+		if c.tx != nil {
+			rows, err = c.tx.QueryxContext(ctx, c.tx.Rebind(inSql), inArgs...)
+			return -1, err
+		}
+
+		db := c.readDB()
+		rows, err = db.QueryxContext(ctx, db.Rebind(inSql), inArgs...)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	inSql = c.db.Rebind(inSql)
-	return c.db.Select(dest, inSql, inArgs...)
+	return rows, nil
 }
 
 // Insert 插入单个数据
-func (c *dbConnection) Insert(value interface{}) (uint64, error) {
+func (c *dbConnection) Insert(value interface{}) (common.Result, error) {
+	return c.InsertContext(c.ctx, value)
+}
+
+// InsertContext 插入单个数据(带上下文)，自增主键为零值时由数据库生成
+// PostgreSQL驱动不支持LastInsertId，通过RETURNING返回生成的自增主键
+func (c *dbConnection) InsertContext(ctx context.Context, value interface{}) (common.Result, error) {
 	query, err := c.createInsertSql(value)
 	if err != nil {
-		return 0, err
+		return common.Result{}, err
 	}
 
-	// 如果事务实例存在，则使用事务实例进行插入
-	if c.tx != nil {
-		txResult, err := c.tx.NamedExec(query, value)
+	model, _, _ := parseModel(value)
+	if c.config.Type == "postgres" && model.auto != nil {
+		return c.insertReturning(ctx, query+" RETURNING "+model.auto.column, value)
+	}
+
+	var result sql.Result
+	err = c.execute(ctx, "insert", query, value, func(ctx context.Context) (int64, error) {
+		// 如果事务实例存在，则使用事务实例进行插入
+		if c.tx != nil {
+			result, err = c.tx.NamedExecContext(ctx, query, value)
+			return affectedRows(result), err
+		}
+
+		result, err = c.db.NamedExecContext(ctx, query, value)
+		return affectedRows(result), err
+	})
+
+	return toResult(result), err
+}
+
+// insertReturning 执行带RETURNING的插入语句，读取返回的自增主键作为LastInsertId
+func (c *dbConnection) insertReturning(ctx context.Context, query string, value interface{}) (common.Result, error) {
+	var lastInsertId, rowsAffected int64
+	err := c.execute(ctx, "insert", query, value, func(ctx context.Context) (int64, error) {
+		var execer sqlx.ExtContext = c.db
+		if c.tx != nil {
+			execer = c.tx
+		}
+
+		rows, err := sqlx.NamedQueryContext(ctx, execer, query, value)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Scan(&lastInsertId); err != nil {
+				return rowsAffected, err
+			}
+			rowsAffected++
+		}
+		return rowsAffected, rows.Err()
+	})
+
+	return common.Result{LastInsertId: lastInsertId, RowsAffected: rowsAffected}, err
+}
+
+// createInsertSql 根据传入的带db标记的结构体(或结构体指针)生成命名参数的插入语句
+// 传入多个数据时(批量插入)生成所有数据共用的插入语句，自增主键都为零值时不插入该列
+func (c *dbConnection) createInsertSql(values ...interface{}) (string, error) {
+	var model *modelInfo
+	structValues := make([]reflect.Value, 0, len(values))
+	for _, value := range values {
+		info, structValue, err := parseModel(value)
 		if err != nil {
-			return 0, nil
+			return "", err
 		}
-		id, _ := txResult.LastInsertId()
-		return uint64(id), nil
+		model = info
+		structValues = append(structValues, structValue)
 	}
 
-	dbResult, err := c.db.NamedExec(query, value)
+	columns, err := model.insertColumns(structValues)
 	if err != nil {
-		return 0, nil
+		return "", err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.tableName, strings.Join(columns, ","), namedParams(columns))
+	return query, nil
+}
+
+// namedParams 根据列名生成命名参数列表，例如：:id,:name
+func namedParams(columns []string) string {
+	params := make([]string, 0, len(columns))
+	for _, column := range columns {
+		params = append(params, ":"+column)
+	}
+	return strings.Join(params, ",")
+}
+
+// BatchInsert 批量插入
+func (c *dbConnection) BatchInsert(values interface{}) error {
+	return c.BatchInsertContext(c.ctx, values)
+}
+
+// BatchInsertContext 批量插入(带上下文)，数据按配置的批量大小分批插入
+func (c *dbConnection) BatchInsertContext(ctx context.Context, values interface{}) error {
+	return c.BatchInsertWithOptions(ctx, values, common.BatchOptions{})
+}
+
+// Update 更新数据
+func (c *dbConnection) Update(condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return c.UpdateContext(c.ctx, condition, updateValue)
+}
+
+// UpdateContext 更新数据(带上下文)，condition中可以使用:name形式引用updateValue中的值
+func (c *dbConnection) UpdateContext(ctx context.Context, condition string, updateValue map[string]interface{}) (common.Result, error) {
+	if condition == "" {
+		return common.Result{}, errors.New("更新条件不能为空字符串")
+	}
+
+	if len(updateValue) == 0 {
+		return common.Result{}, errors.New("更新的字段不能为空")
+	}
+
+	// 复制一份更新字段，避免修改调用方的数据
+	values := make(map[string]interface{}, len(updateValue)+1)
+	for field, value := range updateValue {
+		values[field] = value
+	}
+	if column := c.config.UpdateTimeColumn; column != "" {
+		if _, ok := values[column]; !ok {
+			values[column] = time.Now()
+		}
+	}
+
+	// 组装更新字段，按字段名排序保证同样的更新生成同样的语句
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, fmt.Sprintf("%s=:%s", field, field))
 	}
-	id, _ := dbResult.LastInsertId()
-	return uint64(id), nil
+	sort.Strings(fields)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(fields, ","), condition)
+
+	var result sql.Result
+	err := c.execute(ctx, "update", query, values, func(ctx context.Context) (_ int64, err error) {
+		// 如果事务实例存在，则使用事务更新
+		if c.tx != nil {
+			result, err = c.tx.NamedExecContext(ctx, query, values)
+			return affectedRows(result), err
+		}
+
+		result, err = c.db.NamedExecContext(ctx, query, values)
+		return affectedRows(result), err
+	})
+
+	return toResult(result), err
 }
 
-// createInsertSql 根据传入的带db标记的结构体指针新增数据
-func (c *dbConnection) createInsertSql(value interface{}) (string, error) {
-	tags, fields, err := dbFields(value)
+// UpdateByPK 根据主键更新结构体数据，columns为空时更新除主键外的所有列
+// 结构体包含orm:"version"标记的版本号字段时启用乐观锁，版本号不一致时返回ErrStaleVersion
+// 更新成功后结构体(传入指针时)中的版本号加1，在事务中时如果事务(或者所在的保存点)回滚或者提交失败，版本号恢复为更新前的值，
+// 因此WithTx因死锁重试时会使用原版本号重新执行
+func (c *dbConnection) UpdateByPK(value interface{}, columns ...string) (common.Result, error) {
+	return c.UpdateByPKContext(c.ctx, value, columns...)
+}
+
+// UpdateByPKContext 根据主键更新结构体数据(带上下文)
+func (c *dbConnection) UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (common.Result, error) {
+	model, structValue, err := parseModel(value)
 	if err != nil {
-		return "", err
+		return common.Result{}, err
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.tableName, strings.Join(tags, ","), strings.Join(fields, ","))
-	return query, nil
+	fields := make([]modelField, 0, len(model.fields))
+	if len(columns) == 0 {
+		// 更新所有列时，更新时间列由updateFields自动写入当前时间
+		for _, field := range model.fields {
+			if !field.primaryKey && field.column != c.config.UpdateTimeColumn {
+				fields = append(fields, field)
+			}
+		}
+	} else {
+		for _, column := range columns {
+			field, ok := model.columns[column]
+			if !ok {
+				return common.Result{}, fmt.Errorf("结构体(%s)中不存在列(%s)", structValue.Type().Name(), column)
+			}
+			if field.primaryKey {
+				return common.Result{}, fmt.Errorf("主键列(%s)不允许更新", column)
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	return c.updateFields(ctx, model, structValue, fields)
 }
 
-// dbFields 根据结构体获取db标签名数组
-func dbFields(value interface{}) ([]string, []string, error) {
-	v := reflect.ValueOf(value)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+// UpdateStruct 根据主键更新结构体中发生变化的字段，origin为修改前的数据，value为修改后的数据
+func (c *dbConnection) UpdateStruct(origin interface{}, value interface{}) (common.Result, error) {
+	return c.UpdateStructContext(c.ctx, origin, value)
+}
+
+// UpdateStructContext 根据主键更新结构体中发生变化的字段(带上下文)，没有字段变化时不执行任何语句
+func (c *dbConnection) UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (common.Result, error) {
+	_, originValue, err := parseModel(origin)
+	if err != nil {
+		return common.Result{}, err
+	}
+
+	model, structValue, err := parseModel(value)
+	if err != nil {
+		return common.Result{}, err
 	}
-	tagNames := []string{}
-	fields := []string{}
-	if v.Kind() != reflect.Struct {
-		return nil, nil, errors.New("参数必须是结构体或者结构体指针")
+
+	if originValue.Type() != structValue.Type() {
+		return common.Result{}, fmt.Errorf("修改前后的数据类型不一致(%s, %s)", originValue.Type(), structValue.Type())
 	}
-	for i := 0; i < v.NumField(); i++ {
-		fieldValue := v.Type().Field(i)
-		tagName := fieldValue.Tag.Get("db")
-		field := fieldValue.Name
-		if tagName != "" {
-			tagNames = append(tagNames, tagName)
-			fields = append(fields, ":"+field)
+
+	fields := make([]modelField, 0, len(model.fields))
+	for _, field := range model.fields {
+		if field.primaryKey {
+			if !fieldEqual(field.fieldValue(originValue), field.fieldValue(structValue)) {
+				return common.Result{}, fmt.Errorf("修改前后的主键(%s)不一致", field.column)
+			}
+			continue
 		}
+
+		if field.column != c.config.UpdateTimeColumn && !field.version && !fieldEqual(field.fieldValue(originValue), field.fieldValue(structValue)) {
+			fields = append(fields, field)
+		}
+	}
+
+	if len(fields) == 0 {
+		return common.Result{}, nil
 	}
-	return tagNames, fields, nil
+
+	return c.updateFields(ctx, model, structValue, fields)
 }
 
-// BatchInsert 批量插入
-func (c *dbConnection) BatchInsert(values interface{}) error {
-	sliceValues := reflect.ValueOf(values)
-	if sliceValues.Kind() != reflect.Slice {
-		return errors.New("批量插入的参数必须为结构体切片")
+// updateFields 根据主键更新指定的字段，结构体包含更新时间列且未显式更新时自动写入当前时间
+func (c *dbConnection) updateFields(ctx context.Context, model *modelInfo, structValue reflect.Value, fields []modelField) (common.Result, error) {
+	condition, conditionArgs, err := model.primaryKeyCondition(structValue)
+	if err != nil {
+		return common.Result{}, err
+	}
+
+	sets := make([]string, 0, len(fields)+2)
+	args := make([]interface{}, 0, len(fields)+len(conditionArgs)+2)
+	updateTimeSet := false
+	for _, field := range fields {
+		// 版本号列由乐观锁自动维护，不允许直接更新
+		if field.version {
+			continue
+		}
+		sets = append(sets, field.column+" = ?")
+		args = append(args, field.value(structValue))
+		if field.column == c.config.UpdateTimeColumn {
+			updateTimeSet = true
+		}
+	}
+
+	if column := c.config.UpdateTimeColumn; column != "" && !updateTimeSet {
+		if _, ok := model.columns[column]; ok {
+			sets = append(sets, column+" = ?")
+			args = append(args, time.Now())
+		}
 	}
 
-	if sliceValues.Len() == 0 {
-		return errors.New("批量插入的参数切片不能为空")
+	if len(sets) == 0 {
+		return common.Result{}, errors.New("没有需要更新的字段")
 	}
 
-	query, err := c.createInsertSql(sliceValues.Index(0).Interface())
+	if model.version == nil {
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(sets, ", "), condition)
+		return c.exec(ctx, "update", query, append(args, conditionArgs...)...)
+	}
+
+	// 启用乐观锁时，版本号与结构体中的版本号一致才更新，并将版本号加1
+	column := model.version.column
+	sets = append(sets, fmt.Sprintf("%s = %s + 1", column, column))
+	condition += " AND " + column + " = ?"
+	conditionArgs = append(conditionArgs, model.version.value(structValue))
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(sets, ", "), condition)
+	result, err := c.exec(ctx, "update", query, append(args, conditionArgs...)...)
 	if err != nil {
-		return err
+		return result, err
+	}
+	if result.RowsAffected == 0 {
+		return result, fmt.Errorf("数据表(%s)更新失败(版本号=%v)(%w)", c.tableName, model.version.value(structValue), ErrStaleVersion)
 	}
 
-	// 如果事务实例存在，则使用事务批量插入
-	if c.tx != nil {
-		_, err = c.tx.NamedExec(query, values)
-		return err
+	if undo := model.version.increaseVersion(structValue); undo != nil && c.tx != nil {
+		c.txVersions = append(c.txVersions, versionUndo{depth: c.txDepth, undo: undo})
 	}
+	return result, nil
+}
 
-	_, err = c.db.NamedExec(query, values)
-	return err
+// fieldEqual 判断两个字段值是否相等，时间类型使用Equal比较以忽略时区和单调时钟的差异
+func fieldEqual(a reflect.Value, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+
+	if at, ok := a.Interface().(time.Time); ok {
+		bt, _ := b.Interface().(time.Time)
+		return at.Equal(bt)
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
 }
 
-// Update 更新数据
-func (c *dbConnection) Update(condition string, updateValue map[string]interface{}) error {
-	updateValue["update_time"] = time.Now()
+// Upsert 插入数据，主键或唯一键冲突时更新除主键外的所有列
+func (c *dbConnection) Upsert(value interface{}) (common.Result, error) {
+	return c.UpsertContext(c.ctx, value)
+}
 
-	// 组装更新函数
-	fields := make([]string, 0, len(updateValue))
-	for field, _ := range updateValue {
-		fields = append(fields, fmt.Sprintf("%s:=%s", field, field))
+// UpsertContext 插入或更新数据(带上下文)
+// MySQL/TiDB使用ON DUPLICATE KEY UPDATE，PostgreSQL/SQLite使用主键作为冲突列的ON CONFLICT
+// ClickHouse没有行级的插入或更新语义，按批量写入方式插入一行，由ReplacingMergeTree表引擎在合并数据分片时按排序键保留最新的一行
+// (合并前查询可能读到多个版本，需要使用FINAL或者argMax读取最新数据)
+func (c *dbConnection) UpsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	if c.config.Type == "clickhouse" {
+		if err := c.insertClickHouseChunk(ctx, []interface{}{value}); err != nil {
+			return common.Result{}, err
+		}
+		return common.Result{RowsAffected: 1}, nil
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(fields, ","), condition)
+	query, err := c.createInsertSql(value)
+	if err != nil {
+		return common.Result{}, err
+	}
 
-	// 如果事务实例存在，则使用事务更新
-	if c.tx != nil {
-		_, err := c.tx.NamedExec(query, updateValue)
-		return err
+	switch c.config.Type {
+	case "mysql", "tidb":
+		model, _, _ := parseModel(value)
+		updates := make([]string, 0, len(model.fields))
+		for _, field := range model.fields {
+			if !field.primaryKey {
+				updates = append(updates, fmt.Sprintf("%s=VALUES(%s)", field.column, field.column))
+			}
+		}
+		// 只有主键列时，冲突后不做任何修改
+		if len(updates) == 0 && len(model.primaryKeys) > 0 {
+			column := model.primaryKeys[0].column
+			updates = append(updates, fmt.Sprintf("%s=%s", column, column))
+		}
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+	case "postgres", "sqlite":
+		model, structValue, _ := parseModel(value)
+		if len(model.primaryKeys) == 0 {
+			return common.Result{}, fmt.Errorf("结构体(%s)缺少主键，无法确定冲突列", structValue.Type().Name())
+		}
+
+		conflicts := make([]string, 0, len(model.primaryKeys))
+		for _, field := range model.primaryKeys {
+			conflicts = append(conflicts, field.column)
+		}
+		updates := make([]string, 0, len(model.fields))
+		for _, field := range model.fields {
+			if !field.primaryKey {
+				updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", field.column, field.column))
+			}
+		}
+
+		if len(updates) == 0 {
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflicts, ","))
+		} else {
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflicts, ","), strings.Join(updates, ","))
+		}
+	default:
+		return common.Result{}, fmt.Errorf("数据库类型(%s)不支持插入或更新操作", c.config.Type)
 	}
 
-	_, err := c.db.NamedExec(query, updateValue)
-	return err
+	var result sql.Result
+	err = c.execute(ctx, "upsert", query, value, func(ctx context.Context) (int64, error) {
+		// 如果事务实例存在，则使用事务实例进行插入
+		if c.tx != nil {
+			result, err = c.tx.NamedExecContext(ctx, query, value)
+			return affectedRows(result), err
+		}
+
+		result, err = c.db.NamedExecContext(ctx, query, value)
+		return affectedRows(result), err
+	})
+
+	return toResult(result), err
 }
 
-// Delete 删除数据,软删除可以使用Update,因此这里是物理删除
-func (c *dbConnection) Delete(condition string, args ...interface{}) error {
+// Delete 删除数据，开启软删除时只写入删除时间列，否则为物理删除
+func (c *dbConnection) Delete(condition string, args ...interface{}) (common.Result, error) {
+	return c.DeleteContext(c.ctx, condition, args...)
+}
+
+// DeleteContext 删除数据(带上下文)
+func (c *dbConnection) DeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	if !c.config.SoftDelete {
+		return c.ForceDeleteContext(ctx, condition, args...)
+	}
+
 	if condition == "" {
-		return errors.New("删除条件不能为空字符串")
+		return common.Result{}, errors.New("删除条件不能为空字符串")
 	}
 
-	query := fmt.Sprintf("DELETE %s WHERE %s", c.tableName, condition)
-	inSql, inArgs, err := sqlx.In(query, args...)
-	if err != nil {
-		return fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
+	column := c.config.DeleteTimeColumn
+	if column == "" {
+		column = defaultDeleteTimeColumn
 	}
 
-	// 如果事务存在则使用事务实例
-	if c.tx != nil {
-		inSql = c.tx.Rebind(inSql)
-		_, err = c.tx.Exec(inSql, inArgs...)
-		return err
+	now := time.Now()
+	sets := []string{column + " = ?"}
+	setArgs := []interface{}{now}
+	if c.config.UpdateTimeColumn != "" {
+		sets = append(sets, c.config.UpdateTimeColumn+" = ?")
+		setArgs = append(setArgs, now)
 	}
 
-	inSql = c.db.Rebind(inSql)
-	_, err = c.db.Exec(inSql, inArgs...)
-	return err
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(sets, ", "), condition)
+	return c.exec(ctx, "delete", query, append(setArgs, args...)...)
+}
+
+// ForceDelete 物理删除数据，忽略软删除配置
+func (c *dbConnection) ForceDelete(condition string, args ...interface{}) (common.Result, error) {
+	return c.ForceDeleteContext(c.ctx, condition, args...)
+}
+
+// ForceDeleteContext 物理删除数据(带上下文)
+func (c *dbConnection) ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	if condition == "" {
+		return common.Result{}, errors.New("删除条件不能为空字符串")
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", c.tableName, condition)
+	return c.exec(ctx, "delete", query, args...)
 }
 
 // Exec 执行原生SQL语句
-func (c *dbConnection) Exec(query string, args ...interface{}) error {
+func (c *dbConnection) Exec(query string, args ...interface{}) (common.Result, error) {
+	return c.ExecContext(c.ctx, query, args...)
+}
+
+// ExecContext 执行原生SQL语句(带上下文)
+func (c *dbConnection) ExecContext(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	return c.exec(ctx, "exec", query, args...)
+}
+
+// exec 展开in条件后执行sql语句
+func (c *dbConnection) exec(ctx context.Context, operation string, query string, args ...interface{}) (common.Result, error) {
 	inSql, inArgs, err := sqlx.In(query, args...)
 	if err != nil {
-		return fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
+		return common.Result{}, fmt.Errorf("sql语句或者参数列表错误(%s)", err.Error())
 	}
 
-	// 如果事务实例存在则使用事务实例
-	if c.tx != nil {
-		inSql = c.tx.Rebind(inSql)
-		_, err = c.tx.Exec(inSql, inArgs...)
-		return err
-	}
+	var result sql.Result
+	err = c.execute(ctx, operation, inSql, inArgs, func(ctx context.Context) (int64, error) {
+		// 如果事务实例存在则使用事务实例
+		if c.tx != nil {
+			result, err = c.tx.ExecContext(ctx, c.tx.Rebind(inSql), inArgs...)
+			return affectedRows(result), err
+		}
 
-	inSql = c.db.Rebind(inSql)
-	_, err = c.db.Exec(inSql, inArgs...)
-	return err
+		result, err = c.db.ExecContext(ctx, c.db.Rebind(inSql), inArgs...)
+		return affectedRows(result), err
+	})
+
+	return toResult(result), err
 }
 
-// Begin 开启事务
+// Begin 开启事务，已经存在事务时创建保存点(嵌套事务)
 func (c *dbConnection) Begin() error {
+	return c.BeginTx(c.ctx, nil)
+}
+
+// BeginTx 开启事务(带上下文)，事务在上下文对象取消时自动回滚
+// opts为空时使用配置的事务隔离级别，已经存在事务时创建保存点，此时opts不生效
+func (c *dbConnection) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 	if c.tx != nil {
-		return errors.New("请先关闭已存在事务")
+		if c.config.Type == "clickhouse" {
+			return errors.New("ClickHouse不支持嵌套事务")
+		}
+
+		savepoint := fmt.Sprintf("SAVEPOINT %s", savepointName(c.txDepth+1))
+		err := c.execute(ctx, "begin", savepoint, nil, func(ctx context.Context) (int64, error) {
+			_, err := c.tx.ExecContext(ctx, savepoint)
+			return -1, err
+		})
+		if err == nil {
+			c.txDepth++
+		}
+		return err
 	}
 
 	if c.db == nil {
 		return errors.New("数据库连接实例不存在")
 	}
 
-	var err error
-	c.tx, err = c.db.Beginx()
-	return err
+	if opts == nil && c.config.IsolationLevel != "" {
+		level, err := isolationLevel(c.config.IsolationLevel)
+		if err != nil {
+			return err
+		}
+		opts = &sql.TxOptions{Isolation: level}
+	}
+
+	return c.execute(ctx, "begin", "BEGIN", nil, func(ctx context.Context) (int64, error) {
+		var err error
+		c.tx, err = c.db.BeginTxx(ctx, opts)
+		return -1, err
+	})
 }
 
-// Rollback 回滚事务
+// Rollback 回滚事务，嵌套事务中回滚到最近的保存点
 func (c *dbConnection) Rollback() error {
 	if c.tx == nil {
 		return errors.New("事务实例不存在")
 	}
 
-	c.tx.Rollback()
+	if c.txDepth > 0 {
+		query := fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", savepointName(c.txDepth))
+		c.undoVersions(c.txDepth)
+		c.txDepth--
+		return c.execute(c.ctx, "rollback", query, nil, func(ctx context.Context) (int64, error) {
+			_, err := c.tx.ExecContext(ctx, query)
+			return -1, err
+		})
+	}
+
+	err := c.execute(c.ctx, "rollback", "ROLLBACK", nil, func(ctx context.Context) (int64, error) {
+		return -1, c.tx.Rollback()
+	})
+	c.undoVersions(0)
 	c.tx = nil
-	return nil
+	c.txBlock = false
+	c.cacheDirty = false
+	return err
 }
 
-// Commit 执行事务
+// Commit 执行事务，嵌套事务中释放最近的保存点
 func (c *dbConnection) Commit() error {
 	if c.tx == nil {
 		return errors.New("事务实例不存在")
 	}
 
-	err := c.tx.Commit()
+	if c.txDepth > 0 {
+		query := fmt.Sprintf("RELEASE SAVEPOINT %s", savepointName(c.txDepth))
+		c.txDepth--
+		// 释放保存点后，保存点中的修改归属于外层事务，随外层事务一起回滚
+		for i := range c.txVersions {
+			if c.txVersions[i].depth > c.txDepth {
+				c.txVersions[i].depth = c.txDepth
+			}
+		}
+		return c.execute(c.ctx, "commit", query, nil, func(ctx context.Context) (int64, error) {
+			_, err := c.tx.ExecContext(ctx, query)
+			return -1, err
+		})
+	}
+
+	err := c.execute(c.ctx, "commit", "COMMIT", nil, func(ctx context.Context) (int64, error) {
+		return -1, c.tx.Commit()
+	})
+	// 提交失败时事务已经被回滚(或者由调用方重试)，恢复事务中加1的版本号
+	if err != nil {
+		c.undoVersions(0)
+	}
+	c.txVersions = nil
 	c.tx = nil
+	c.txBlock = false
+
+	// 事务中执行过写操作时，提交事务后使查询缓存失效(提交失败时数据状态未知，同样使缓存失效)
+	if c.cacheDirty {
+		c.invalidateCache()
+	}
 
 	return err
 }
+
+// WithTx 在事务中执行函数，函数返回错误或者发生panic时回滚事务，否则提交事务
+// 已经存在事务时使用保存点实现嵌套事务；最外层事务遇到死锁时按配置的次数重新执行整个函数
+func (c *dbConnection) WithTx(ctx context.Context, fn func(tx common.Orm) error) error {
+	// 嵌套事务遇到死锁时整个事务已经被数据库回滚，只能由最外层事务重试
+	retries := c.config.DeadlockRetry
+	if c.tx != nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.runTx(ctx, fn)
+		if err == nil || attempt >= retries || !errors.Is(err, ErrDeadlock) {
+			return err
+		}
+
+		// 稍作等待再重试，降低再次死锁的概率
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt+1) * deadlockRetryInterval):
+		}
+	}
+}
+
+// runTx 在事务中执行一次函数
+func (c *dbConnection) runTx(ctx context.Context, fn func(tx common.Orm) error) (err error) {
+	if err = c.BeginTx(ctx, nil); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(c); err != nil {
+		if rollbackErr := c.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w(回滚事务失败: %s)", err, rollbackErr.Error())
+		}
+		return err
+	}
+
+	return c.Commit()
+}
+
+// undoVersions 按照与加1相反的顺序恢复嵌套层数不小于depth的乐观锁版本号
+func (c *dbConnection) undoVersions(depth int) {
+	i := len(c.txVersions)
+	for i > 0 && c.txVersions[i-1].depth >= depth {
+		i--
+		c.txVersions[i].undo()
+	}
+	c.txVersions = c.txVersions[:i]
+}
+
+// savepointName 根据嵌套层数生成保存点名称
+func savepointName(depth int) string {
+	return fmt.Sprintf("sp_%d", depth)
+}
+
+// isolationLevel 将配置的事务隔离级别转换为标准库的隔离级别，例如：read committed、repeatable_read
+func isolationLevel(level string) (sql.IsolationLevel, error) {
+	switch strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(level, "_", " "))), " ") {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read uncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "read committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable read":
+		return sql.LevelRepeatableRead, nil
+	case "snapshot":
+		return sql.LevelSnapshot, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("事务隔离级别(%s)不支持", level)
+	}
+}
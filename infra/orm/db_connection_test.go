@@ -0,0 +1,352 @@
+//go:build sqlite && cgo
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// testArticle 带更新时间和软删除时间列的测试数据模型
+type testArticle struct {
+	ID         int64        `db:"id"`
+	Title      string       `db:"title"`
+	Views      int          `db:"views"`
+	UpdateTime time.Time    `db:"update_time"`
+	DeleteTime sql.NullTime `db:"delete_time"`
+}
+
+// testArticleSchema 测试数据表结构
+const testArticleSchema = `CREATE TABLE user (id INTEGER PRIMARY KEY, title TEXT NOT NULL, views INTEGER NOT NULL DEFAULT 0,
+	update_time DATETIME, delete_time DATETIME)`
+
+// getArticle 根据主键查询文章
+func getArticle(t *testing.T, conn *dbConnection, id int64) testArticle {
+	t.Helper()
+	var article testArticle
+	if err := conn.Get(&article, "SELECT * FROM user WHERE id = ?", id); err != nil {
+		t.Fatalf("查询数据(%d)失败(%s)", id, err)
+	}
+	return article
+}
+
+func TestSQLiteSoftDelete(t *testing.T) {
+	conn := newSQLiteConnection(t, testArticleSchema)
+	conn.config.UpdateTimeColumn = "update_time"
+	for id := int64(1); id <= 3; id++ {
+		if _, err := conn.Insert(testArticle{ID: id, Title: "article"}); err != nil {
+			t.Fatalf("插入数据失败(%s)", err)
+		}
+	}
+
+	// 关闭软删除时为物理删除
+	if result, err := conn.Delete("id = ?", 1); err != nil || result.RowsAffected != 1 {
+		t.Fatalf("物理删除失败(%+v, %v)", result, err)
+	}
+	var count int
+	conn.Get(&count, "SELECT COUNT(*) FROM user")
+	if count != 2 {
+		t.Fatalf("物理删除后数据条数错误(%d)", count)
+	}
+
+	// 开启软删除时只写入删除时间和更新时间
+	conn.config.SoftDelete = true
+	begin := time.Now().Add(-time.Second)
+	if result, err := conn.Delete("id = ?", 2); err != nil || result.RowsAffected != 1 {
+		t.Fatalf("软删除失败(%+v, %v)", result, err)
+	}
+	article := getArticle(t, conn, 2)
+	if !article.DeleteTime.Valid || article.DeleteTime.Time.Before(begin) || article.UpdateTime.Before(begin) {
+		t.Fatalf("软删除应该写入删除时间和更新时间(%+v)", article)
+	}
+	if other := getArticle(t, conn, 3); other.DeleteTime.Valid {
+		t.Fatalf("不满足条件的数据不应该被软删除(%+v)", other)
+	}
+
+	// 软删除时仍然可以强制物理删除
+	if result, err := conn.ForceDelete("id = ?", 2); err != nil || result.RowsAffected != 1 {
+		t.Fatalf("强制删除失败(%+v, %v)", result, err)
+	}
+	if _, err := conn.Delete(""); err == nil {
+		t.Fatal("删除条件为空时应该返回错误")
+	}
+}
+
+func TestSQLiteUpdateByPK(t *testing.T) {
+	conn := newSQLiteConnection(t, testArticleSchema)
+	conn.config.UpdateTimeColumn = "update_time"
+	if _, err := conn.Insert(testArticle{ID: 1, Title: "draft", Views: 1}); err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+
+	// 不指定列时更新除主键外的所有列，并写入更新时间
+	begin := time.Now().Add(-time.Second)
+	if result, err := conn.UpdateByPK(&testArticle{ID: 1, Title: "published", Views: 10}); err != nil || result.RowsAffected != 1 {
+		t.Fatalf("更新所有列失败(%+v, %v)", result, err)
+	}
+	article := getArticle(t, conn, 1)
+	if article.Title != "published" || article.Views != 10 || article.UpdateTime.Before(begin) {
+		t.Fatalf("更新所有列的结果错误(%+v)", article)
+	}
+
+	// 指定列时只更新指定的列
+	if _, err := conn.UpdateByPK(testArticle{ID: 1, Title: "ignored", Views: 20}, "views"); err != nil {
+		t.Fatalf("更新指定列失败(%s)", err)
+	}
+	article = getArticle(t, conn, 1)
+	if article.Title != "published" || article.Views != 20 {
+		t.Fatalf("更新指定列的结果错误(%+v)", article)
+	}
+
+	if _, err := conn.UpdateByPK(testArticle{ID: 1}, "id"); err == nil {
+		t.Fatal("更新主键列应该返回错误")
+	}
+	if _, err := conn.UpdateByPK(testArticle{ID: 1}, "missing"); err == nil {
+		t.Fatal("更新不存在的列应该返回错误")
+	}
+	if result, err := conn.UpdateByPK(testArticle{ID: 2, Title: "missing"}); err != nil || result.RowsAffected != 0 {
+		t.Fatalf("主键不存在时不应该更新数据(%+v, %v)", result, err)
+	}
+}
+
+func TestSQLiteUpdateStruct(t *testing.T) {
+	conn := newSQLiteConnection(t, testArticleSchema)
+	if _, err := conn.Insert(testArticle{ID: 1, Title: "draft", Views: 1}); err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+
+	origin := getArticle(t, conn, 1)
+	value := origin
+	value.Title = "published"
+
+	// 其他请求在读取之后修改了未变化的字段，只更新变化的字段时不会覆盖该修改
+	if _, err := conn.Exec("UPDATE user SET views = 100 WHERE id = 1"); err != nil {
+		t.Fatalf("修改数据失败(%s)", err)
+	}
+	if result, err := conn.UpdateStruct(origin, &value); err != nil || result.RowsAffected != 1 {
+		t.Fatalf("更新变化的字段失败(%+v, %v)", result, err)
+	}
+	article := getArticle(t, conn, 1)
+	if article.Title != "published" || article.Views != 100 {
+		t.Fatalf("只应该更新变化的字段(%+v)", article)
+	}
+
+	// 没有字段变化时不执行任何语句
+	if result, err := conn.UpdateStruct(value, value); err != nil || result.RowsAffected != 0 {
+		t.Fatalf("没有字段变化时不应该更新数据(%+v, %v)", result, err)
+	}
+
+	changed := value
+	changed.ID = 2
+	if _, err := conn.UpdateStruct(value, changed); err == nil {
+		t.Fatal("修改前后主键不一致时应该返回错误")
+	}
+	if _, err := conn.UpdateStruct(testUser{ID: 1}, value); err == nil {
+		t.Fatal("修改前后数据类型不一致时应该返回错误")
+	}
+}
+
+// testDocument 带乐观锁版本号的测试数据模型
+type testDocument struct {
+	ID      int64  `db:"id"`
+	Title   string `db:"title"`
+	Version int64  `db:"version" orm:"version"`
+}
+
+// testDocumentSchema 测试数据表结构
+const testDocumentSchema = `CREATE TABLE user (id INTEGER PRIMARY KEY, title TEXT NOT NULL, version INTEGER NOT NULL DEFAULT 0)`
+
+// getDocumentVersion 查询数据表中的版本号
+func getDocumentVersion(t *testing.T, conn *dbConnection, id int64) int64 {
+	t.Helper()
+	var version int64
+	if err := conn.Get(&version, "SELECT version FROM user WHERE id = ?", id); err != nil {
+		t.Fatalf("查询版本号(%d)失败(%s)", id, err)
+	}
+	return version
+}
+
+func TestSQLiteOptimisticLock(t *testing.T) {
+	conn := newSQLiteConnection(t, testDocumentSchema)
+	if _, err := conn.Insert(testDocument{ID: 1, Title: "draft"}); err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+
+	// 更新成功后数据表和结构体中的版本号都加1
+	document := testDocument{ID: 1, Title: "published"}
+	stale := document
+	if _, err := conn.UpdateByPK(&document); err != nil {
+		t.Fatalf("更新数据失败(%s)", err)
+	}
+	if document.Version != 1 || getDocumentVersion(t, conn, 1) != 1 {
+		t.Fatalf("更新后版本号错误(结构体=%d, 数据表=%d)", document.Version, getDocumentVersion(t, conn, 1))
+	}
+
+	// 使用过期的版本号更新时返回ErrStaleVersion，且不修改数据
+	stale.Title = "stale"
+	if _, err := conn.UpdateByPK(&stale); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("版本号过期时应该返回ErrStaleVersion(%v)", err)
+	}
+	if stale.Version != 0 || getDocumentVersion(t, conn, 1) != 1 {
+		t.Fatalf("版本号过期时不应该修改版本号(结构体=%d, 数据表=%d)", stale.Version, getDocumentVersion(t, conn, 1))
+	}
+
+	// 按值传递时只更新数据表中的版本号
+	if _, err := conn.UpdateByPK(document); err != nil {
+		t.Fatalf("按值传递更新数据失败(%s)", err)
+	}
+	if document.Version != 1 || getDocumentVersion(t, conn, 1) != 2 {
+		t.Fatalf("按值传递更新后版本号错误(结构体=%d, 数据表=%d)", document.Version, getDocumentVersion(t, conn, 1))
+	}
+}
+
+func TestSQLiteOptimisticLockInTx(t *testing.T) {
+	conn := newSQLiteConnection(t, testDocumentSchema)
+	if _, err := conn.Insert(testDocument{ID: 1, Title: "draft"}); err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+
+	// 事务回滚时恢复结构体中的版本号，重新执行时使用原版本号
+	document := testDocument{ID: 1, Title: "published"}
+	rollbackErr := errors.New("rollback")
+	err := conn.WithTx(context.Background(), func(tx common.Orm) error {
+		if _, err := tx.UpdateByPK(&document); err != nil {
+			return err
+		}
+		if _, err := tx.UpdateByPK(&document); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	if !errors.Is(err, rollbackErr) {
+		t.Fatalf("事务应该返回函数的错误(%v)", err)
+	}
+	if document.Version != 0 || getDocumentVersion(t, conn, 1) != 0 {
+		t.Fatalf("事务回滚后版本号错误(结构体=%d, 数据表=%d)", document.Version, getDocumentVersion(t, conn, 1))
+	}
+
+	// 保存点回滚只恢复保存点中的版本号，释放的保存点随外层事务提交
+	other := testDocument{ID: 1, Title: "nested"}
+	err = conn.WithTx(context.Background(), func(tx common.Orm) error {
+		if _, err := tx.UpdateByPK(&document); err != nil {
+			return err
+		}
+		tx.WithTx(context.Background(), func(tx common.Orm) error {
+			other.Version = document.Version
+			if _, err := tx.UpdateByPK(&other); err != nil {
+				return err
+			}
+			return rollbackErr
+		})
+		return tx.WithTx(context.Background(), func(tx common.Orm) error {
+			_, err := tx.UpdateByPK(&document)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("执行事务失败(%s)", err)
+	}
+	if document.Version != 2 || other.Version != 1 || getDocumentVersion(t, conn, 1) != 2 {
+		t.Fatalf("嵌套事务提交后版本号错误(结构体=%d, 回滚的结构体=%d, 数据表=%d)", document.Version, other.Version, getDocumentVersion(t, conn, 1))
+	}
+}
+
+func TestSQLiteWithTxDeadlockRetry(t *testing.T) {
+	conn := newSQLiteConnection(t, testArticleSchema)
+	conn.config.DeadlockRetry = 2
+
+	// 最外层事务遇到死锁时重新执行整个函数，每次执行的写入都会被回滚
+	calls := 0
+	err := conn.WithTx(context.Background(), func(tx common.Orm) error {
+		calls++
+		if _, err := tx.Insert(testArticle{ID: int64(calls), Title: "retry"}); err != nil {
+			return err
+		}
+		return ErrDeadlock
+	})
+	if !errors.Is(err, ErrDeadlock) || calls != 3 {
+		t.Fatalf("死锁重试次数错误(执行次数=%d, 错误=%v)", calls, err)
+	}
+	var count int
+	conn.Get(&count, "SELECT COUNT(*) FROM user")
+	if count != 0 || conn.tx != nil {
+		t.Fatalf("重试失败后事务应该全部回滚(数据条数=%d)", count)
+	}
+
+	// 重试成功时只保留最后一次执行的写入
+	calls = 0
+	err = conn.WithTx(context.Background(), func(tx common.Orm) error {
+		calls++
+		if _, err := tx.Insert(testArticle{ID: int64(calls), Title: "retry"}); err != nil {
+			return err
+		}
+		if calls < 2 {
+			return ErrDeadlock
+		}
+		return nil
+	})
+	conn.Get(&count, "SELECT COUNT(*) FROM user")
+	if err != nil || calls != 2 || count != 1 {
+		t.Fatalf("重试成功后结果错误(执行次数=%d, 数据条数=%d, 错误=%v)", calls, count, err)
+	}
+
+	// 其他错误不重试
+	calls = 0
+	conn.WithTx(context.Background(), func(tx common.Orm) error {
+		calls++
+		return errors.New("other")
+	})
+	if calls != 1 {
+		t.Fatalf("非死锁错误不应该重试(执行次数=%d)", calls)
+	}
+
+	// 嵌套事务中的死锁不单独重试，由最外层事务重新执行整个函数
+	outerCalls, innerCalls := 0, 0
+	conn.WithTx(context.Background(), func(tx common.Orm) error {
+		outerCalls++
+		return tx.WithTx(context.Background(), func(tx common.Orm) error {
+			innerCalls++
+			return ErrDeadlock
+		})
+	})
+	if outerCalls != 3 || innerCalls != 3 {
+		t.Fatalf("嵌套事务死锁重试次数错误(外层=%d, 内层=%d)", outerCalls, innerCalls)
+	}
+}
+
+func TestSQLiteWithTxPanic(t *testing.T) {
+	conn := newSQLiteConnection(t, testArticleSchema)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("应该重新抛出函数中的panic(%v)", r)
+			}
+		}()
+		conn.WithTx(context.Background(), func(tx common.Orm) error {
+			if _, err := tx.Insert(testArticle{ID: 1, Title: "panic"}); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	var count int
+	conn.Get(&count, "SELECT COUNT(*) FROM user")
+	if count != 0 || conn.tx != nil {
+		t.Fatalf("发生panic时应该回滚事务(数据条数=%d)", count)
+	}
+
+	// 回滚后连接可以继续开启新的事务
+	if err := conn.WithTx(context.Background(), func(tx common.Orm) error {
+		_, err := tx.Insert(testArticle{ID: 2, Title: "after"})
+		return err
+	}); err != nil {
+		t.Fatalf("panic后执行事务失败(%s)", err)
+	}
+}
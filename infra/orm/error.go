@@ -0,0 +1,172 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 16:20:12
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 16:20:12
+ * @FilePath: \common\infra\orm\error.go
+ * @Description: orm错误定义与数据库驱动错误分类
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/ClickHouse/clickhouse-go"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// "数据库驱动错误分类"相关定义，可通过errors.Is判断
+var (
+	ErrDuplicateKey    = errors.New("数据重复(唯一键冲突)")
+	ErrDeadlock        = errors.New("出现死锁")
+	ErrLockWaitTimeout = errors.New("等待锁超时")
+	ErrConnectionLost  = errors.New("数据库连接断开")
+)
+
+// ErrUnsupported 数据库不支持该操作(例如在ClickHouse专用连接上开启事务)，可通过errors.Is判断
+var ErrUnsupported = errors.New("数据库不支持该操作")
+
+// ErrStaleVersion 乐观锁版本号已过期(数据已被其他请求修改或者已被删除)，可通过errors.Is判断
+var ErrStaleVersion = errors.New("数据版本已过期")
+
+// MySQL错误码相关定义
+const (
+	mysqlDuplicateEntry        = 1062 // 唯一键冲突
+	mysqlDuplicateEntryWithKey = 1586 // 唯一键冲突(带键名)
+	mysqlLockWaitTimeout       = 1205 // 等待锁超时
+	mysqlDeadlock              = 1213 // 死锁
+	mysqlServerGone            = 2006 // 服务端断开连接
+	mysqlServerLost            = 2013 // 查询过程中连接断开
+)
+
+// ClickHouse错误码相关定义
+const (
+	clickhouseSocketTimeout = 209 // 网络超时
+	clickhouseNetworkError  = 210 // 网络错误
+)
+
+// PostgreSQL错误码相关定义
+const (
+	postgresUniqueViolation     = "23505" // 唯一键冲突
+	postgresDeadlockDetected    = "40P01" // 死锁
+	postgresLockNotAvailable    = "55P03" // 无法获取锁(lock_timeout)
+	postgresAdminShutdown       = "57P01" // 服务端关闭
+	postgresConnectionException = "08"    // 连接异常错误类别
+)
+
+// OrmError orm操作错误，包含出错的数据表和操作类型
+type OrmError struct {
+	Table     string // 数据表名称
+	Operation string // 操作类型
+	Kind      error  // 错误分类(无法分类时为空)
+	Err       error  // 数据库驱动返回的原始错误
+}
+
+// Error 实现error接口
+func (e *OrmError) Error() string {
+	return fmt.Sprintf("数据表(%s)执行%s操作失败(%s)", e.Table, e.Operation, e.Err.Error())
+}
+
+// Unwrap 获取原始错误
+func (e *OrmError) Unwrap() error {
+	return e.Err
+}
+
+// Is 判断错误是否属于指定的错误分类
+func (e *OrmError) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// wrapError 使用数据表和操作类型包装数据库驱动错误，并对错误进行分类
+func wrapError(table string, operation string, err error) error {
+	// 未查询到数据的错误保持原样返回，兼容调用方直接比较sql.ErrNoRows的写法
+	if err == nil || err == sql.ErrNoRows {
+		return err
+	}
+
+	// 已经包装过的错误不再重复包装
+	var ormError *OrmError
+	if errors.As(err, &ormError) {
+		return err
+	}
+
+	return &OrmError{
+		Table:     table,
+		Operation: operation,
+		Kind:      classifyError(err),
+		Err:       err,
+	}
+}
+
+// classifyError 将数据库驱动错误归类为对应的哨兵错误
+func classifyError(err error) error {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		switch mysqlError.Number {
+		case mysqlDuplicateEntry, mysqlDuplicateEntryWithKey:
+			return ErrDuplicateKey
+		case mysqlDeadlock:
+			return ErrDeadlock
+		case mysqlLockWaitTimeout:
+			return ErrLockWaitTimeout
+		case mysqlServerGone, mysqlServerLost:
+			return ErrConnectionLost
+		}
+		return nil
+	}
+
+	var clickhouseError *clickhouse.Exception
+	if errors.As(err, &clickhouseError) {
+		switch clickhouseError.Code {
+		case clickhouseSocketTimeout, clickhouseNetworkError:
+			return ErrConnectionLost
+		}
+		return nil
+	}
+
+	var postgresError *pq.Error
+	if errors.As(err, &postgresError) {
+		switch {
+		case postgresError.Code == postgresUniqueViolation:
+			return ErrDuplicateKey
+		case postgresError.Code == postgresDeadlockDetected:
+			return ErrDeadlock
+		case postgresError.Code == postgresLockNotAvailable:
+			return ErrLockWaitTimeout
+		case postgresError.Code == postgresAdminShutdown, postgresError.Code.Class() == postgresConnectionException:
+			return ErrConnectionLost
+		}
+		return nil
+	}
+
+	if kind, ok := classifySQLiteError(err); ok {
+		return kind
+	}
+
+	// 请求的上下文超时或者取消不是连接错误，不能触发连接断开的重试逻辑(context.DeadlineExceeded也实现了net.Error)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	// 连接类错误
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrConnectionLost
+	}
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return ErrConnectionLost
+	}
+
+	return nil
+}
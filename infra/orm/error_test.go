@@ -0,0 +1,64 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"MySQL唯一键冲突", &mysql.MySQLError{Number: 1062}, ErrDuplicateKey},
+		{"MySQL死锁", &mysql.MySQLError{Number: 1213}, ErrDeadlock},
+		{"MySQL等待锁超时", &mysql.MySQLError{Number: 1205}, ErrLockWaitTimeout},
+		{"MySQL连接断开", &mysql.MySQLError{Number: 2013}, ErrConnectionLost},
+		{"MySQL其他错误", &mysql.MySQLError{Number: 1146}, nil},
+		{"PostgreSQL唯一键冲突", &pq.Error{Code: "23505"}, ErrDuplicateKey},
+		{"PostgreSQL连接异常", &pq.Error{Code: "08006"}, ErrConnectionLost},
+		{"无效连接", driver.ErrBadConn, ErrConnectionLost},
+		{"网络错误", &net.OpError{Op: "read", Err: errors.New("connection reset")}, ErrConnectionLost},
+		{"包装后的网络错误", fmt.Errorf("查询失败(%w)", &net.OpError{Op: "read", Err: errors.New("broken pipe")}), ErrConnectionLost},
+		{"请求超时", context.DeadlineExceeded, nil},
+		{"包装后的请求超时", fmt.Errorf("查询失败(%w)", context.DeadlineExceeded), nil},
+		{"请求取消", context.Canceled, nil},
+		{"未查询到数据", sql.ErrNoRows, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if kind := classifyError(test.err); kind != test.kind {
+				t.Fatalf("错误分类结果错误(%v)，期望(%v)", kind, test.kind)
+			}
+		})
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	if err := wrapError("user", "get", sql.ErrNoRows); err != sql.ErrNoRows {
+		t.Fatalf("未查询到数据的错误应该保持原样(%v)", err)
+	}
+
+	err := wrapError("user", "get", context.DeadlineExceeded)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("请求超时不应该归类为连接断开(%v)", err)
+	}
+
+	err = wrapError("user", "insert", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("包装后的错误应该可以判断分类(%v)", err)
+	}
+	if wrapped := wrapError("user", "insert", err); wrapped != err {
+		t.Fatalf("已经包装过的错误不应该重复包装(%v)", wrapped)
+	}
+}
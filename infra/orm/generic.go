@@ -0,0 +1,132 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-22 09:41:06
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-22 09:41:06
+ * @FilePath: \common\infra\orm\generic.go
+ * @Description: 泛型查询辅助函数
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/pkg/errors"
+)
+
+// scannerType sql.Scanner接口类型
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// GetOne 查询单个数据，T可以是带db标签的结构体或者基础类型(例如COUNT查询)，未查询到数据时返回sql.ErrNoRows
+func GetOne[T any](ctx context.Context, conn common.Orm, query string, args ...interface{}) (T, error) {
+	var item T
+	if err := conn.GetContext(ctx, &item, query, args...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item, nil
+}
+
+// List 查询多个数据，未查询到数据时返回空切片
+func List[T any](ctx context.Context, conn common.Orm, query string, args ...interface{}) ([]T, error) {
+	items := make([]T, 0)
+	if err := conn.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Page 分页查询，返回当前页数据和符合筛选条件的数据总数
+// query为不带WHERE条件的查询语句(例如"SELECT * FROM user")，筛选、排序和分页条件由builder构建
+// 数据总数只统计筛选条件，不包含游标分页条件，因此游标分页时每一页返回的总数一致
+func Page[T any](ctx context.Context, conn common.Orm, query string, builder *QueryBuilder) ([]T, int64, error) {
+	if builder == nil {
+		return nil, 0, errors.New("分页查询的条件构建器不能为空")
+	}
+
+	// 未指定数据库类型时使用连接的数据库类型，保证like条件的转义语法正确
+	if connection, ok := conn.(*dbConnection); ok && builder.dbType == "" {
+		builder.Dialect(connection.config.Type)
+	}
+
+	where, whereArgs, err := builder.buildWhere(false)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := GetOne[int64](ctx, conn, "SELECT COUNT(*) FROM ("+query+where+") page_total", whereArgs...)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "查询数据总数失败")
+	}
+	if total == 0 {
+		return make([]T, 0), 0, nil
+	}
+
+	clause, args, err := builder.Build()
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := List[T](ctx, conn, query+clause, args...)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "查询分页数据失败")
+	}
+
+	return items, total, nil
+}
+
+// Iterate 逐行读取查询结果并回调，不会一次性将结果集加载到内存，适用于导出等大数据量场景
+// T可以是结构体指针，此时每一行读取到新分配的结构体中；回调函数返回错误时停止遍历并返回该错误
+func Iterate[T any](ctx context.Context, conn common.Orm, fn func(item T) error, query string, args ...interface{}) error {
+	rows, err := conn.QueryRowsContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// 结构体指针需要先分配结构体再读取，其他指针类型(例如*int64)按可空的单列读取
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+	structPointer := itemType.Kind() == reflect.Ptr && isStructScan(itemType.Elem())
+	structScan := structPointer || isStructScan(itemType)
+	for rows.Next() {
+		var item T
+		var dest interface{} = &item
+		if structPointer {
+			value := reflect.New(itemType.Elem())
+			item = value.Interface().(T)
+			dest = item
+		}
+
+		if structScan {
+			err = rows.StructScan(dest)
+		} else {
+			err = rows.Scan(dest)
+		}
+		if err != nil {
+			return errors.Wrap(err, "读取查询结果失败")
+		}
+
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// isStructScan 判断是否需要按照db标签读取到结构体，时间类型和实现了sql.Scanner接口的类型按单列读取
+func isStructScan(reflectType reflect.Type) bool {
+	if reflectType.Kind() != reflect.Struct {
+		return false
+	}
+	if reflectType == reflect.TypeOf(time.Time{}) || reflect.PtrTo(reflectType).Implements(scannerType) {
+		return false
+	}
+	return true
+}
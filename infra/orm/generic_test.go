@@ -0,0 +1,80 @@
+//go:build sqlite && cgo
+
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+func TestPageKeysetTotal(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+	for id := int64(1); id <= 5; id++ {
+		mustInsert(t, conn, testUser{ID: id, Name: "user", Age: int(id)})
+	}
+	mustInsert(t, conn, testUser{ID: 6, Name: "other", Age: 6})
+
+	ctx := context.Background()
+	var cursor interface{}
+	pages := make([][]testUser, 0)
+	for {
+		builder, err := NewQueryBuilder(testUser{})
+		if err != nil {
+			t.Fatalf("创建查询条件构建器失败(%s)", err)
+		}
+		builder.Filter(common.FilterParam{Name: "name", Operator: "=", Values: []string{"user"}}).After("id", cursor, DirectionAsc, 2)
+
+		items, total, err := Page[testUser](ctx, conn, "SELECT * FROM user", builder)
+		if err != nil {
+			t.Fatalf("分页查询失败(%s)", err)
+		}
+		// 数据总数只统计筛选条件，每一页都相同
+		if total != 5 {
+			t.Fatalf("第%d页的数据总数错误(%d)", len(pages)+1, total)
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		pages = append(pages, items)
+		cursor = items[len(items)-1].ID
+	}
+
+	if len(pages) != 3 || len(pages[2]) != 1 || pages[2][0].ID != 5 {
+		t.Fatalf("游标分页结果错误(%+v)", pages)
+	}
+}
+
+func TestIterateStructPointer(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+	mustInsert(t, conn, testUser{ID: 1, Name: "alice"}, testUser{ID: 2, Name: "bob", Age: 7})
+
+	ctx := context.Background()
+	users := make([]*testUser, 0)
+	err := Iterate(ctx, conn, func(user *testUser) error {
+		users = append(users, user)
+		return nil
+	}, "SELECT * FROM user ORDER BY id")
+	if err != nil {
+		t.Fatalf("遍历结构体指针失败(%s)", err)
+	}
+	// 每一行读取到新分配的结构体中
+	if len(users) != 2 || users[0] == users[1] || users[0].Name != "alice" || users[1].Name != "bob" {
+		t.Fatalf("遍历结果错误(%+v)", users)
+	}
+
+	// 其他指针类型按可空的单列读取
+	ages := make([]*int64, 0)
+	err = Iterate(ctx, conn, func(age *int64) error {
+		ages = append(ages, age)
+		return nil
+	}, "SELECT NULLIF(age, 0) FROM user ORDER BY id")
+	if err != nil {
+		t.Fatalf("遍历可空的单列失败(%s)", err)
+	}
+	if len(ages) != 2 || ages[0] != nil || ages[1] == nil || *ages[1] != 7 {
+		t.Fatalf("可空单列的遍历结果错误(%v)", ages)
+	}
+}
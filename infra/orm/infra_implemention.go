@@ -13,21 +13,30 @@ package orm
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hongliu9527/common/infra/metrics"
 	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+	"github.com/hongliu9527/common/utils"
 
 	_ "github.com/ClickHouse/clickhouse-go"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 
+	"github.com/hongliu9527/go-tools/logger"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
 // 常量相关定义
 const (
-	ormInfraName string = "orm" // Orm基础设施名称
+	ormInfraName            string = "orm"       // Orm基础设施名称
+	tableRefreshMinInterval        = time.Second // 找不到表名时重新查询表名的最小间隔
 )
 
 func (i *ormInfra) Name() string {
@@ -39,6 +48,15 @@ func (i *ormInfra) start(ctx context.Context) error {
 	// 创建基础设施上下文对象与退出回调函数
 	i.ctx, i.cancel = context.WithCancel(ctx)
 
+	// 创建sql日志记录器(配置重新加载后日志等级和格式同步生效)
+	sqlLogger, err := newSqlLogger(i.config.LogLevel, i.config.LogFormat)
+	if err != nil {
+		return err
+	}
+	i.mutex.Lock()
+	i.logger = sqlLogger
+	i.mutex.Unlock()
+
 	// 初始化
 	return i.init()
 }
@@ -53,25 +71,157 @@ func (i *ormInfra) init() error {
 			return err
 		}
 
-		// 查询该句柄下iot平台相关表名，并添加表名-句柄哈希表
+		// 查询该句柄下iot平台相关表名
 		tableList, err := queryTableNames(db, sqlxConfig.Type, sqlxConfig.DatabaseName, sqlxConfig.TablePrefix)
 		if err != nil {
 			return err
 		}
-		for _, tableName := range tableList {
-			i.tableNameInstance[tableName] = db
+
+		// 初始化只读副本
+		var replicas *replicaSet
+		if len(sqlxConfig.Replicas) > 0 {
+			replicas, err = newReplicaSet(i.ctx, i.config.UseExternalHost, sqlxConfig)
+			if err != nil {
+				return err
+			}
 		}
 
+		i.mutex.Lock()
 		// 添加实例名-配置信息哈希表
 		i.nameConfig[sqlxConfig.Name] = sqlxConfig
 
 		// 添加数据库实例名-数据库实例哈希表
 		i.nameInstance[sqlxConfig.Name] = db
+
+		// 添加表名-句柄哈希表
+		i.setTables(sqlxConfig, db, tableList)
+
+		if replicas != nil {
+			i.nameReplicas[sqlxConfig.Name] = replicas
+		}
+		i.mutex.Unlock()
+
+		// 采集该实例的连接池监控指标
+		metrics.AddDBStats(sqlxConfig.Name, db.DB)
+
+		// 开启副本健康检查协程
+		if replicas != nil {
+			go replicas.run(i.ctx, time.Duration(sqlxConfig.ReplicaCheckInterval)*time.Second)
+		}
+
+		// 开启表名定期刷新协程
+		if sqlxConfig.TableRefreshInterval > 0 {
+			go i.refreshLoop(i.ctx, sqlxConfig.Name, time.Duration(sqlxConfig.TableRefreshInterval)*time.Second)
+		}
+	}
+
+	return nil
+}
+
+// setTables 使用最新的表名列表替换该数据库实例的表名-句柄哈希表，调用方需要持有写锁
+func (i *ormInfra) setTables(dbConfig ormConfig.DataBaseConfig, db *sqlx.DB, tableList []string) {
+	tableSet := make(map[string]struct{}, len(tableList))
+	for _, tableName := range tableList {
+		tableSet[tableName] = struct{}{}
+		i.tableNameInstance[tableName] = db
+		i.tableNameConfig[tableName] = dbConfig
+	}
+
+	// 移除已经被删除的表
+	for tableName, tableConfig := range i.tableNameConfig {
+		if _, ok := tableSet[tableName]; !ok && tableConfig.Name == dbConfig.Name {
+			delete(i.tableNameInstance, tableName)
+			delete(i.tableNameConfig, tableName)
+		}
+	}
+}
+
+// refreshDatabase 重新查询指定数据库实例下的表名，并更新表名-句柄哈希表
+func (i *ormInfra) refreshDatabase(name string) error {
+	i.mutex.RLock()
+	db, ok := i.nameInstance[name]
+	dbConfig := i.nameConfig[name]
+	i.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("数据库实例(%s)不存在", name)
+	}
+
+	tableList, err := queryTableNames(db, dbConfig.Type, dbConfig.DatabaseName, dbConfig.TablePrefix)
+	if err != nil {
+		return err
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	// 基础设施已经停止或者重启时，不再写入旧实例的表名
+	if i.ctx.Err() != nil || i.nameInstance[name] != db {
+		return nil
+	}
+	i.setTables(dbConfig, db, tableList)
+	return nil
+}
+
+// refreshOnMiss 找不到表名时重新查询所有数据库实例的表名，两次刷新的最小间隔为tableRefreshMinInterval
+func (i *ormInfra) refreshOnMiss() error {
+	i.refreshMutex.Lock()
+	defer i.refreshMutex.Unlock()
+
+	if time.Since(i.lastRefresh) < tableRefreshMinInterval {
+		return nil
+	}
+	i.lastRefresh = time.Now()
+
+	i.mutex.RLock()
+	names := make([]string, 0, len(i.nameInstance))
+	for name := range i.nameInstance {
+		names = append(names, name)
+	}
+	i.mutex.RUnlock()
+
+	errs := make([]error, 0)
+	for _, name := range names {
+		if err := i.refreshDatabase(name); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
+	if len(errs) > 0 {
+		return utils.MergeErrors(errs)
+	}
 	return nil
 }
 
+// refreshLoop 定期刷新数据库实例的表名，直到上下文对象取消
+func (i *ormInfra) refreshLoop(ctx context.Context, name string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.refreshMutex.Lock()
+			err := i.refreshDatabase(name)
+			i.refreshMutex.Unlock()
+			if err != nil {
+				logger.Warning("刷新数据库(%s)的表名列表失败(%s)", name, err.Error())
+			}
+		}
+	}
+}
+
+// Open 根据数据库配置创建独立于orm基础设施的sqlx实例并检查连接，用于数据库迁移等工具，使用完毕后由调用方关闭
+func Open(config ormConfig.DataBaseConfig, useExternalHost bool) (*sqlx.DB, error) {
+	return connectOneSqlx("", useExternalHost, config)
+}
+
+// TableNames 查询数据库下所有符合表名前缀的表名，用于代码生成等工具
+func TableNames(db *sqlx.DB, config ormConfig.DataBaseConfig) ([]string, error) {
+	return queryTableNames(db, config.Type, config.DatabaseName, config.TablePrefix)
+}
+
 // connectOneSqlx 初始化1个sqlx连接
 func connectOneSqlx(level string, useExternalHost bool, config ormConfig.DataBaseConfig) (*sqlx.DB, error) {
 
@@ -81,71 +231,187 @@ func connectOneSqlx(level string, useExternalHost bool, config ormConfig.DataBas
 		hostPort = config.HostPort
 	}
 
-	// 生成数据源名称
-	var (
-		dsn string
-		db  *sqlx.DB
-		err error
-	)
-	switch config.Type {
-	case "mysql", "tidb":
-		dsn = fmt.Sprintf("%s:%s@tcp(%s)/%s?%s&timeout=%ds",
-			config.Username,
-			config.Password,
-			hostPort,
-			config.DatabaseName,
-			"charset=utf8mb4&parseTime=true&loc=Asia%2FShanghai",
-			config.ConnectTimeout)
-		db, err = sqlx.Open("mysql", dsn)
-	case "clickhouse":
-		dsn = fmt.Sprintf("tcp://%s?database=%s&username=%s&password=%s&read_timeout=10&write_timeout=20",
-			hostPort,
-			config.DatabaseName,
-			config.Username,
-			config.Password)
-		db, err = sqlx.Open("clickhouse", dsn)
+	db, err := openSqlx(config, hostPort)
+	if err != nil {
+		return nil, err
 	}
 
-	if dsn == "" {
-		return nil, errors.New("数据库基础配置信息缺少")
+	// 确认sqlx实例正常
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "初始化%s-%s连接失败", config.Name, config.Type)
 	}
 
+	return db, nil
+}
+
+// openSqlx 根据配置信息创建sqlx实例并设置连接池(不检查连接是否可用)，配置热更新时基础设施重启会重新执行
+func openSqlx(config ormConfig.DataBaseConfig, hostPort string) (*sqlx.DB, error) {
+	if config.Type == "sqlite" && !sqliteEnabled {
+		return nil, errors.Errorf("初始化%s-%s连接失败(SQLite驱动依赖cgo，需要开启cgo并使用-tags sqlite编译)", config.Name, config.Type)
+	}
+
+	dsn, err := dataSourceName(config, hostPort)
 	if err != nil {
-		return nil, errors.Wrapf(err, "初始化%s-%s连接失败", config.Name, config.Type)
+		return nil, errors.WithMessagef(err, "初始化%s-%s连接失败", config.Name, config.Type)
 	}
 
-	// 确认sqlx实例正常
-	err = db.Ping()
+	db, err := sqlx.Open(driverName(config.Type), dsn)
 	if err != nil {
 		return nil, errors.Wrapf(err, "初始化%s-%s连接失败", config.Name, config.Type)
 	}
 
-	db.SetMaxIdleConns(10)           // 设置空闲连接池中连接的最大数量
-	db.SetMaxOpenConns(100)          // 设置打开数据库连接的最大数量
-	db.SetConnMaxLifetime(time.Hour) // 设置连接可复用最大时间
+	// SQLite内存数据库的数据只存在于单个连接中，只能使用一个永不关闭的连接
+	if config.Type == "sqlite" && isSQLiteMemory(config.DatabaseName) {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		return db, nil
+	}
+
+	db.SetMaxIdleConns(config.MaxIdleConns)                                    // 设置空闲连接池中连接的最大数量
+	db.SetMaxOpenConns(config.MaxOpenConns)                                    // 设置打开数据库连接的最大数量
+	db.SetConnMaxLifetime(time.Duration(config.ConnMaxLifetime) * time.Second) // 设置连接可复用最大时间
+	db.SetConnMaxIdleTime(time.Duration(config.ConnMaxIdleTime) * time.Second) // 设置连接空闲最大时间
 
 	return db, nil
 }
 
+// driverName 根据数据库类型获取注册的驱动名称
+func driverName(dataBaseType string) string {
+	switch dataBaseType {
+	case "tidb": // TiDB兼容MySQL协议，使用MySQL驱动
+		return "mysql"
+	case "sqlite":
+		return "sqlite3"
+	default:
+		return dataBaseType
+	}
+}
+
+// isSQLiteMemory 判断SQLite数据库是否为内存数据库
+func isSQLiteMemory(databaseName string) bool {
+	return databaseName == ":memory:" || strings.Contains(databaseName, "mode=memory")
+}
+
+// dataSourceName 生成数据源名称
+func dataSourceName(config ormConfig.DataBaseConfig, hostPort string) (string, error) {
+	extraParams, err := url.ParseQuery(config.Params)
+	if err != nil {
+		return "", fmt.Errorf("额外的连接参数(%s)格式错误(%s)", config.Params, err.Error())
+	}
+
+	params := url.Values{}
+	switch config.Type {
+	case "mysql", "tidb":
+		params.Set("parseTime", "true")
+		params.Set("timeout", fmt.Sprintf("%ds", config.ConnectTimeout))
+		if config.Charset != "" {
+			params.Set("charset", config.Charset)
+		}
+		if config.Timezone != "" {
+			params.Set("loc", config.Timezone)
+		}
+		if config.TLS != "" {
+			params.Set("tls", config.TLS)
+		}
+	case "clickhouse":
+		params.Set("database", config.DatabaseName)
+		params.Set("username", config.Username)
+		params.Set("password", config.Password)
+		params.Set("read_timeout", "10")
+		params.Set("write_timeout", "20")
+		switch config.TLS {
+		case "":
+		case "true":
+			params.Set("secure", "true")
+		case "skip-verify":
+			params.Set("secure", "true")
+			params.Set("skip_verify", "true")
+		default:
+			return "", fmt.Errorf("ClickHouse不支持TLS模式(%s)", config.TLS)
+		}
+	case "postgres":
+		params.Set("connect_timeout", strconv.Itoa(config.ConnectTimeout))
+		if config.Timezone != "" {
+			params.Set("timezone", config.Timezone)
+		}
+		// 与MySQL的含义保持一致：true校验证书和主机名，skip-verify只加密不校验证书
+		switch config.TLS {
+		case "":
+			params.Set("sslmode", "disable")
+		case "true":
+			params.Set("sslmode", "verify-full")
+		case "skip-verify":
+			params.Set("sslmode", "require")
+		default:
+			params.Set("sslmode", config.TLS)
+		}
+	case "sqlite":
+		params.Set("_busy_timeout", strconv.Itoa(config.ConnectTimeout*1000))
+		if config.Timezone != "" {
+			params.Set("_loc", config.Timezone)
+		}
+	default:
+		return "", errors.New("数据库基础配置信息缺少")
+	}
+
+	// 额外的连接参数覆盖默认参数
+	for key, values := range extraParams {
+		params[key] = values
+	}
+
+	switch config.Type {
+	case "clickhouse":
+		return fmt.Sprintf("tcp://%s?%s", hostPort, params.Encode()), nil
+	case "postgres":
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(config.Username, config.Password),
+			Host:     hostPort,
+			Path:     "/" + config.DatabaseName,
+			RawQuery: params.Encode(),
+		}
+		return dsn.String(), nil
+	case "sqlite":
+		// SQLite的数据库名称为数据库文件路径(或者:memory:)，不需要访问地址和用户信息
+		if strings.Contains(config.DatabaseName, "?") {
+			return "file:" + config.DatabaseName + "&" + params.Encode(), nil
+		}
+		return "file:" + config.DatabaseName + "?" + params.Encode(), nil
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?%s", config.Username, config.Password, hostPort, config.DatabaseName, params.Encode()), nil
+}
+
 // queryTableNames 查询sqlx实例下所有表名
 func queryTableNames(db *sqlx.DB, dataBaseType string, dataBaseName string, tablePrefix string) ([]string, error) {
 	tableNameList := make([]string, 0, 0)
 	tabelPrefix := fmt.Sprintf("%s%%", tablePrefix)
 
-	var querySQL string
+	var (
+		querySQL string
+		args     = []interface{}{dataBaseName, tabelPrefix}
+	)
 	switch dataBaseType {
 	case "mysql", "tidb":
 		querySQL = "select table_name from information_schema.tables where table_schema = ? and table_name like ?"
 	case "clickhouse":
 		querySQL = "select DISTINCT(name) from system.tables where database = ? and name like ?"
+	case "postgres":
+		querySQL = "select table_name from information_schema.tables where table_catalog = ? and table_schema = current_schema() and table_name like ?"
+	case "sqlite":
+		querySQL = "select name from sqlite_master where type = 'table' and name like ?"
+		args = []interface{}{tabelPrefix}
 	default:
 		return nil, fmt.Errorf("数据库类型未知(%s)", dataBaseType)
 	}
 
-	rows, err := db.Query(querySQL, dataBaseName, tabelPrefix)
+	rows, err := db.Query(db.Rebind(querySQL), args...)
 	if err != nil {
 		return nil, fmt.Errorf("查询数据库(%s)的表名列表失败(%s)", dataBaseName, err)
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var tableName string
 		rows.Scan(&tableName)
@@ -157,19 +423,52 @@ func queryTableNames(db *sqlx.DB, dataBaseType string, dataBaseName string, tabl
 
 // stop 关闭orm基础设施
 func (i *ormInfra) stop() error {
+	// 执行退出回调函数，停止副本健康检查和表名刷新协程
+	i.cancel()
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
 	for name, db := range i.nameInstance {
+		metrics.RemoveDBStats(name)
+
 		err := db.Close()
 		if err != nil {
 			return fmt.Errorf("关闭数据库(%s)失败(%s)", name, err.Error())
 		}
 	}
 
+	for name, replicas := range i.nameReplicas {
+		err := replicas.close()
+		if err != nil {
+			return fmt.Errorf("关闭数据库(%s)的只读副本失败(%s)", name, err.Error())
+		}
+	}
+
 	i.nameConfig = make(map[string]ormConfig.DataBaseConfig)
+	i.nameReplicas = make(map[string]*replicaSet)
 	i.tableNameInstance = make(map[string]*sqlx.DB)
+	i.tableNameConfig = make(map[string]ormConfig.DataBaseConfig)
 	i.nameInstance = make(map[string]*sqlx.DB)
 
-	// 执行退出回调函数
-	i.cancel()
-
 	return nil
 }
+
+// Stats 获取所有数据库实例(包括只读副本)的连接池统计信息
+func (i *ormInfra) Stats() map[string]sql.DBStats {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	stats := make(map[string]sql.DBStats, len(i.nameInstance))
+	for name, db := range i.nameInstance {
+		stats[name] = db.Stats()
+	}
+
+	for _, replicas := range i.nameReplicas {
+		for _, r := range replicas.replicas {
+			stats[r.name] = r.db.Stats()
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,278 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 10:12:37
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 10:12:37
+ * @FilePath: \common\infra\orm\logger.go
+ * @Description: sql语句日志与慢查询日志
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// 日志格式相关定义
+const (
+	logFormatConsole = "console" // 终端样式
+	logFormatJson    = "json"    // json格式
+)
+
+// 日志内容相关定义
+const (
+	redactedValue    = "******" // 敏感列绑定参数的脱敏值
+	maxLoggedArgs    = 50       // 单条日志最多记录的绑定参数个数
+	maxLoggedArgSize = 256      // 单个绑定参数最多记录的字符数
+)
+
+// placeholderColumnPattern 匹配占位符前面的"列名 操作符"，用于确定位置参数对应的列名
+var placeholderColumnPattern = regexp.MustCompile(`(?i)([a-z_][\w.]*)\s*(?:=|<>|!=|>=|<=|>|<|\s(?:not\s+)?like|\s(?:not\s+)?in\s*\((?:\?\s*,\s*)*)\s*$`)
+
+// sqlLogger sql语句日志记录器
+// 执行成功的语句使用debug等级，超过慢查询阈值的语句使用warning等级，执行失败的语句使用error等级
+type sqlLogger struct {
+	logger *logrus.Logger // 日志实例
+}
+
+// newSqlLogger 根据日志等级和日志格式创建sql语句日志记录器，日志等级为空时使用info等级(只记录慢查询和错误)
+func newSqlLogger(level string, format string) (*sqlLogger, error) {
+	logLevel := logrus.InfoLevel
+	if level != "" {
+		var err error
+		logLevel, err = logrus.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("orm日志等级(%s)不支持", level)
+		}
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	logger.SetLevel(logLevel)
+
+	switch format {
+	case "", logFormatConsole:
+		formatter := consoleFormatter
+		logger.SetFormatter(&formatter)
+	case logFormatJson:
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: consoleFormatter.TimestampFormat})
+	default:
+		return nil, fmt.Errorf("orm日志格式(%s)不支持", format)
+	}
+
+	return &sqlLogger{logger: logger}, nil
+}
+
+// log 记录一次数据库操作，rows小于0时不记录数据行数
+func (l *sqlLogger) log(c *dbConnection, operation string, query string, args interface{}, rows int64, duration time.Duration, err error) {
+	if l == nil {
+		return
+	}
+
+	slowThreshold := time.Duration(c.config.SlowThreshold) * time.Millisecond
+	slow := slowThreshold > 0 && duration >= slowThreshold
+
+	// 未查询到数据不作为错误记录
+	level := logrus.DebugLevel
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		level = logrus.ErrorLevel
+	} else if slow {
+		level = logrus.WarnLevel
+	}
+	if !l.logger.IsLevelEnabled(level) {
+		return
+	}
+
+	fields := logrus.Fields{
+		"database":  c.config.Name,
+		"table":     c.tableName,
+		"operation": operation,
+		"duration":  duration.String(),
+	}
+	if rows >= 0 && err == nil {
+		fields["rows"] = rows
+	}
+	if slow {
+		fields["slow"] = "慢查询"
+	}
+	if level == logrus.ErrorLevel {
+		fields["error"] = err.Error()
+	}
+
+	message := query
+	if formatted := formatArgs(query, args, redactColumns(c.config.RedactColumns)); formatted != "" {
+		message += " " + formatted
+	}
+	l.logger.WithFields(fields).Log(level, message)
+}
+
+// redactColumns 解析需要脱敏的列名列表(逗号分隔，不区分大小写)
+func redactColumns(columns string) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.ToLower(strings.TrimSpace(column)); column != "" {
+			result[column] = struct{}{}
+		}
+	}
+	return result
+}
+
+// formatArgs 格式化绑定参数，敏感列对应的参数使用脱敏值代替
+// 位置参数根据占位符前面的列名判断是否需要脱敏，无法确定列名且语句中包含敏感列时同样脱敏
+func formatArgs(query string, args interface{}, redact map[string]struct{}) string {
+	if args == nil {
+		return ""
+	}
+
+	switch values := args.(type) {
+	case []interface{}:
+		if len(values) == 0 {
+			return ""
+		}
+
+		// 批量插入的结构体列表只记录数据条数
+		if isModel(values[0]) {
+			return fmt.Sprintf("[%d条数据]", len(values))
+		}
+		return formatPositionalArgs(query, values, redact)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, formatNamedArg(key, values[key], redact))
+		}
+		return joinArgs(items)
+	}
+
+	if isModel(args) {
+		model, structValue, err := parseModel(args)
+		if err != nil {
+			return ""
+		}
+
+		items := make([]string, 0, len(model.fields))
+		for _, field := range model.fields {
+			items = append(items, formatNamedArg(field.column, field.value(structValue), redact))
+		}
+		return joinArgs(items)
+	}
+
+	return "[" + formatArg(args) + "]"
+}
+
+// formatPositionalArgs 格式化位置参数
+func formatPositionalArgs(query string, args []interface{}, redact map[string]struct{}) string {
+	lowerQuery := strings.ToLower(query)
+	containsRedacted := false
+	for column := range redact {
+		if strings.Contains(lowerQuery, column) {
+			containsRedacted = true
+			break
+		}
+	}
+
+	columns := placeholderColumns(query, len(args))
+	items := make([]string, 0, len(args))
+	for index, arg := range args {
+		column := columns[index]
+		_, sensitive := redact[column]
+		if sensitive || (column == "" && containsRedacted) {
+			items = append(items, redactedValue)
+			continue
+		}
+		items = append(items, formatArg(arg))
+	}
+	return joinArgs(items)
+}
+
+// placeholderColumns 依次获取每个占位符对应的列名(不含表名前缀)，无法确定时为空字符串
+func placeholderColumns(query string, count int) []string {
+	columns := make([]string, count)
+	index := 0
+	for position := 0; position < len(query) && index < count; position++ {
+		if query[position] != '?' {
+			continue
+		}
+
+		if match := placeholderColumnPattern.FindStringSubmatch(query[:position]); match != nil {
+			column := match[1]
+			if dot := strings.LastIndex(column, "."); dot >= 0 {
+				column = column[dot+1:]
+			}
+			columns[index] = strings.ToLower(column)
+		}
+		index++
+	}
+	return columns
+}
+
+// formatNamedArg 格式化命名参数
+func formatNamedArg(column string, value interface{}, redact map[string]struct{}) string {
+	if _, ok := redact[strings.ToLower(column)]; ok {
+		return column + "=" + redactedValue
+	}
+	return column + "=" + formatArg(value)
+}
+
+// formatArg 格式化单个参数，过长的参数会被截断
+func formatArg(arg interface{}) string {
+	var text string
+	switch value := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		text = value
+	case []byte:
+		return fmt.Sprintf("<%d字节>", len(value))
+	case time.Time:
+		return value.Format(consoleFormatter.TimestampFormat)
+	default:
+		text = fmt.Sprint(arg)
+	}
+
+	if len(text) > maxLoggedArgSize {
+		text = text[:maxLoggedArgSize] + "..."
+	}
+	if _, ok := arg.(string); ok {
+		return fmt.Sprintf("%q", text)
+	}
+	return text
+}
+
+// joinArgs 拼接格式化后的参数，超过最大个数时只记录前面部分
+func joinArgs(items []string) string {
+	if len(items) > maxLoggedArgs {
+		return "[" + strings.Join(items[:maxLoggedArgs], ", ") + fmt.Sprintf(", ...(共%d个)]", len(items))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// isModel 判断参数是否为带db标签的结构体(或结构体指针)
+func isModel(value interface{}) bool {
+	reflectType := reflect.TypeOf(value)
+	for reflectType != nil && reflectType.Kind() == reflect.Ptr {
+		reflectType = reflectType.Elem()
+	}
+	if reflectType == nil || reflectType.Kind() != reflect.Struct {
+		return false
+	}
+	info, err := modelOf(reflectType)
+	return err == nil && len(info.fields) > 0
+}
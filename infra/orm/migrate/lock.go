@@ -0,0 +1,149 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 15:27:09
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 15:27:09
+ * @FilePath: \common\infra\orm\migrate\lock.go
+ * @Description: 迁移锁实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// 迁移锁相关定义
+const (
+	defaultLockTimeout = time.Minute      // 获取迁移锁的默认超时时间
+	lockRetryInterval  = time.Second      // 非阻塞加锁失败后的重试间隔
+	lockNamePrefix     = "schema_migrate" // 迁移锁名称前缀
+)
+
+// Locker 迁移锁，保证多个服务副本同时启动时只有一个副本执行迁移
+type Locker interface {
+	Lock(ctx context.Context) (unlock func() error, err error) // 加锁，成功后返回解锁回调函数
+}
+
+// mysqlLocker 基于GET_LOCK的MySQL/TiDB迁移锁，锁与连接绑定，连接断开时自动释放
+type mysqlLocker struct {
+	db      *sqlx.DB      // 数据库实例
+	name    string        // 锁名称
+	timeout time.Duration // 加锁超时时间
+}
+
+// Lock 加锁
+func (l *mysqlLocker) Lock(ctx context.Context) (func() error, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取迁移锁连接失败")
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.name, int(l.timeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "获取迁移锁(%s)失败", l.name)
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, errors.Errorf("获取迁移锁(%s)超时(%s)，可能有其他实例正在执行迁移", l.name, l.timeout)
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.name)
+		return err
+	}, nil
+}
+
+// postgresLocker 基于会话级advisory lock的PostgreSQL迁移锁
+type postgresLocker struct {
+	db      *sqlx.DB      // 数据库实例
+	key     int64         // 锁键值
+	timeout time.Duration // 加锁超时时间
+}
+
+// Lock 加锁
+func (l *postgresLocker) Lock(ctx context.Context) (func() error, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取迁移锁连接失败")
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "获取迁移锁(%d)失败", l.key)
+		}
+		if acquired {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			conn.Close()
+			return nil, errors.Errorf("获取迁移锁(%d)超时(%s)，可能有其他实例正在执行迁移", l.key, l.timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", l.key)
+		return err
+	}, nil
+}
+
+// redisLocker 基于Redis分布式锁的迁移锁，用于没有可用数据库锁的ClickHouse，也可以用于其他数据库
+type redisLocker struct {
+	lock common.Lock // Redis分布式锁
+}
+
+// NewRedisLocker 使用Redis分布式锁创建迁移锁，锁对象建议开启看门狗，避免迁移执行时间超过租期后锁被其他实例获取
+func NewRedisLocker(lock common.Lock) Locker {
+	return &redisLocker{lock: lock}
+}
+
+// Lock 加锁
+func (l *redisLocker) Lock(ctx context.Context) (func() error, error) {
+	if err := l.lock.Lock(ctx); err != nil {
+		return nil, errors.WithMessagef(err, "获取迁移锁(%s)失败，可能有其他实例正在执行迁移", l.lock.Key())
+	}
+
+	return func() error {
+		return l.lock.Unlock(context.Background())
+	}, nil
+}
+
+// noopLocker 不加锁(SQLite为单机文件数据库，由文件锁保证写入互斥)
+type noopLocker struct{}
+
+// Lock 加锁
+func (noopLocker) Lock(ctx context.Context) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+// lockKey 将锁名称转换为PostgreSQL advisory lock使用的整数键值
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
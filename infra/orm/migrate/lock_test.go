@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+)
+
+// fakeRedis 记录创建分布式锁参数的Redis实例
+type fakeRedis struct {
+	common.Redis
+	key     string
+	options common.LockOptions
+	lock    *fakeLock
+}
+
+// NewLock 创建分布式锁
+func (r *fakeRedis) NewLock(key string, options common.LockOptions) common.Lock {
+	r.key, r.options = key, options
+	r.lock = &fakeLock{key: key}
+	return r.lock
+}
+
+// baseLock 分布式锁接口(嵌入时字段名与Lock方法同名，通过别名嵌入)
+type baseLock = common.Lock
+
+// fakeLock 记录加锁和解锁次数的分布式锁
+type fakeLock struct {
+	baseLock
+	key      string
+	locked   int
+	unlocked int
+}
+
+// Key 锁的键
+func (l *fakeLock) Key() string { return l.key }
+
+// Lock 加锁
+func (l *fakeLock) Lock(ctx context.Context) error {
+	l.locked++
+	return nil
+}
+
+// Unlock 解锁
+func (l *fakeLock) Unlock(ctx context.Context) error {
+	l.unlocked++
+	return nil
+}
+
+func TestNewLocker(t *testing.T) {
+	source := fstest.MapFS{"events/0001_init.up.sql": &fstest.MapFile{Data: []byte("SELECT 1;")}}
+	config := ormConfig.DataBaseConfig{Name: "events", Type: "clickhouse"}
+
+	// ClickHouse没有可用的数据库锁，未提供迁移锁时不允许创建
+	if _, err := New(nil, config, source, Options{}); err == nil {
+		t.Fatal("ClickHouse未提供迁移锁时应该返回错误")
+	}
+
+	// 提供Redis实例时使用Redis分布式锁
+	redis := &fakeRedis{}
+	migrator, err := New(nil, config, source, Options{Redis: redis, LockTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("创建迁移执行器失败(%s)", err)
+	}
+	if redis.key != "schema_migrate:events" || redis.options.WaitTimeout != 10*time.Second || !redis.options.Watchdog {
+		t.Fatalf("Redis分布式锁参数错误(%s, %+v)", redis.key, redis.options)
+	}
+
+	unlock, err := migrator.locker.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("加锁失败(%s)", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("解锁失败(%s)", err)
+	}
+	if redis.lock.locked != 1 || redis.lock.unlocked != 1 {
+		t.Fatalf("加锁(%d)和解锁(%d)次数错误", redis.lock.locked, redis.lock.unlocked)
+	}
+
+	// 指定迁移锁时优先使用指定的迁移锁
+	migrator, err = New(nil, config, source, Options{Locker: noopLocker{}, Redis: &fakeRedis{}})
+	if err != nil {
+		t.Fatalf("创建迁移执行器失败(%s)", err)
+	}
+	if _, ok := migrator.locker.(noopLocker); !ok {
+		t.Fatalf("应该使用指定的迁移锁(%T)", migrator.locker)
+	}
+
+	// 其他数据库未提供迁移锁时使用数据库锁
+	config.Type = "mysql"
+	if migrator, err = New(nil, config, source, Options{}); err != nil {
+		t.Fatalf("创建迁移执行器失败(%s)", err)
+	}
+	if _, ok := migrator.locker.(*mysqlLocker); !ok {
+		t.Fatalf("MySQL应该使用GET_LOCK迁移锁(%T)", migrator.locker)
+	}
+}
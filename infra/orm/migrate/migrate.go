@@ -0,0 +1,307 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 14:36:18
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 14:36:18
+ * @FilePath: \common\infra\orm\migrate\migrate.go
+ * @Description: 数据库结构迁移
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+
+	"github.com/hongliu9527/go-tools/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// defaultTable 默认的迁移记录表名
+const defaultTable = "schema_migrations"
+
+// Options 迁移选项
+type Options struct {
+	Table       string        // 迁移记录表名，为空时使用schema_migrations
+	Locker      Locker        // 迁移锁，为空时优先使用Redis分布式锁，否则根据数据库类型选择
+	Redis       common.Redis  // 未指定迁移锁时用于创建Redis分布式锁(ClickHouse没有可用的数据库锁，必须提供Locker或Redis)
+	LockTimeout time.Duration // 获取迁移锁的超时时间，为空时为1分钟
+}
+
+// Status 迁移版本状态
+type Status struct {
+	Version   uint64    // 版本号
+	Name      string    // 描述
+	Applied   bool      // 是否已经执行
+	AppliedAt time.Time // 执行时间
+	Missing   bool      // 已经执行但是迁移文件不存在
+}
+
+// appliedRecord 迁移记录
+type appliedRecord struct {
+	Version   uint64    `db:"version"`    // 版本号
+	Name      string    `db:"name"`       // 描述
+	AppliedAt time.Time `db:"applied_at"` // 执行时间
+}
+
+// Migrator 数据库迁移执行器
+// 迁移文件按照数据库配置名称分目录存放，例如：migrations/iotplatform.mysql/0001_create_user.up.sql
+type Migrator struct {
+	db         *sqlx.DB                 // 数据库实例
+	config     ormConfig.DataBaseConfig // 数据库配置
+	migrations []Migration              // 按版本号升序排列的迁移列表
+	table      string                   // 迁移记录表名
+	locker     Locker                   // 迁移锁
+}
+
+// New 创建数据库迁移执行器，source为迁移文件根目录(本地目录使用os.DirFS，也可以使用embed.FS)
+func New(db *sqlx.DB, config ormConfig.DataBaseConfig, source fs.FS, options Options) (*Migrator, error) {
+	dir, err := fs.Sub(source, config.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "获取数据库(%s)的迁移文件目录失败", config.Name)
+	}
+
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "加载数据库(%s)的迁移文件失败", config.Name)
+	}
+
+	if options.Table == "" {
+		options.Table = defaultTable
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = defaultLockTimeout
+	}
+
+	locker := options.Locker
+	lockName := lockNamePrefix + ":" + config.Name
+	if locker == nil && options.Redis != nil {
+		locker = NewRedisLocker(options.Redis.NewLock(lockName, common.LockOptions{
+			WaitTimeout: options.LockTimeout,
+			Watchdog:    true,
+		}))
+	}
+	if locker == nil {
+		switch config.Type {
+		case "mysql", "tidb":
+			locker = &mysqlLocker{db: db, name: lockName, timeout: options.LockTimeout}
+		case "postgres":
+			locker = &postgresLocker{db: db, key: lockKey(lockName), timeout: options.LockTimeout}
+		case "clickhouse":
+			return nil, errors.Errorf("数据库(%s)为ClickHouse，没有可用的数据库锁，需要提供迁移锁(Locker)或Redis实例(Redis)", config.Name)
+		case "sqlite":
+			locker = noopLocker{}
+		default:
+			return nil, errors.Errorf("数据库(%s)的类型(%s)不支持迁移", config.Name, config.Type)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		config:     config,
+		migrations: migrations,
+		table:      options.Table,
+		locker:     locker,
+	}, nil
+}
+
+// Up 按照版本号顺序执行未执行的迁移，steps为最多执行的版本数量(0表示全部)，返回本次执行的迁移列表
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	executed := make([]Migration, 0)
+	err := m.withLock(ctx, func(applied map[uint64]appliedRecord) error {
+		for _, migration := range m.migrations {
+			if steps > 0 && len(executed) >= steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, migration, true); err != nil {
+				return err
+			}
+			executed = append(executed, migration)
+		}
+		return nil
+	})
+	return executed, err
+}
+
+// Down 按照版本号倒序回滚已经执行的迁移，steps为回滚的版本数量(小于1时回滚1个版本)，返回本次回滚的迁移列表
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		steps = 1
+	}
+
+	rolledBack := make([]Migration, 0)
+	err := m.withLock(ctx, func(applied map[uint64]appliedRecord) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.apply(ctx, migration, false); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Redo 回滚最后一个已经执行的迁移并重新执行
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func(applied map[uint64]appliedRecord) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.apply(ctx, migration, false); err != nil {
+				return err
+			}
+			if err := m.apply(ctx, migration, true); err != nil {
+				return err
+			}
+			redone = &migration
+			return nil
+		}
+		return errors.New("没有已经执行的迁移")
+	})
+	return redone, err
+}
+
+// Status 查询所有迁移版本的执行状态，按照版本号升序排列
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+		delete(applied, migration.Version)
+	}
+
+	// 已经执行但是迁移文件已经不存在的版本
+	for _, record := range applied {
+		statuses = append(statuses, Status{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: record.AppliedAt,
+			Missing:   true,
+		})
+	}
+	sortStatuses(statuses)
+
+	return statuses, nil
+}
+
+// withLock 获取迁移锁，创建迁移记录表并查询已经执行的版本后执行回调函数
+func (m *Migrator) withLock(ctx context.Context, fn func(applied map[uint64]appliedRecord) error) error {
+	unlock, err := m.locker.Lock(ctx)
+	if err != nil {
+		return errors.WithMessagef(err, "数据库(%s)获取迁移锁失败", m.config.Name)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			logger.Warning("数据库(%s)释放迁移锁失败(%s)", m.config.Name, err.Error())
+		}
+	}()
+
+	if err := m.createTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := m.appliedRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	return fn(applied)
+}
+
+// apply 执行一个版本的升级(up为true)或者回滚，支持事务性DDL的数据库在同一个事务中执行迁移和记录
+func (m *Migrator) apply(ctx context.Context, migration Migration, up bool) error {
+	action, sqlText := "升级", migration.Up
+	if !up {
+		action, sqlText = "回滚", migration.Down
+		if sqlText == "" {
+			return errors.Errorf("数据库(%s)的版本(%s)没有回滚文件", m.config.Name, migration)
+		}
+	}
+
+	begin := time.Now()
+	err := func() error {
+		if !transactionalDDL(m.config.Type) {
+			if err := execStatements(ctx, m.db, m.config.Type, sqlText); err != nil {
+				return err
+			}
+			return m.record(ctx, m.db, migration, up)
+		}
+
+		tx, err := m.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := execStatements(ctx, tx, m.config.Type, sqlText); err != nil {
+			return err
+		}
+		if err := m.record(ctx, tx, migration, up); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		return errors.WithMessagef(err, "数据库(%s)%s版本(%s)失败", m.config.Name, action, migration)
+	}
+
+	logger.Info("数据库(%s)%s版本(%s)成功(耗时%s)", m.config.Name, action, migration, time.Since(begin))
+	return nil
+}
+
+// execStatements 逐条执行sql文本中的语句
+func execStatements(ctx context.Context, execer sqlx.ExecerContext, dbType string, sqlText string) error {
+	for index, statement := range splitStatements(sqlText, dbType) {
+		if _, err := execer.ExecContext(ctx, statement); err != nil {
+			return errors.Wrapf(err, "执行第%d条语句失败", index+1)
+		}
+	}
+	return nil
+}
+
+// transactionalDDL 判断数据库是否支持在事务中执行DDL
+func transactionalDDL(dbType string) bool {
+	return dbType == "postgres" || dbType == "sqlite"
+}
+
+// String 迁移的展示名称
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
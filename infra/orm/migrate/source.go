@@ -0,0 +1,226 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 15:03:41
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 15:03:41
+ * @FilePath: \common\infra\orm\migrate\source.go
+ * @Description: 迁移文件加载与sql语句拆分
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package migrate
+
+import (
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// 语句块标记相关定义，标记之间的内容作为一条语句执行(例如SQLite/MySQL的CREATE TRIGGER ... BEGIN ...; END;)
+const (
+	statementBeginMarker = "-- +migrate StatementBegin" // 语句块开始标记
+	statementEndMarker   = "-- +migrate StatementEnd"   // 语句块结束标记
+)
+
+// migrationFilePattern 迁移文件名格式：版本号_描述.up.sql或者版本号_描述.down.sql，例如：0001_create_user.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([\w\-]+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version uint64 // 版本号
+	Name    string // 描述
+	Up      string // 升级sql
+	Down    string // 回滚sql(为空时该版本不支持回滚)
+}
+
+// loadMigrations 读取目录下的所有迁移文件，并按照版本号升序排列，不符合命名格式的文件会被忽略
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "读取迁移文件目录失败")
+	}
+
+	versionMigration := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "解析迁移文件(%s)的版本号失败", entry.Name())
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "读取迁移文件(%s)失败", entry.Name())
+		}
+
+		migration, ok := versionMigration[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			versionMigration[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, errors.Errorf("版本(%d)存在多个不同描述的迁移文件(%s、%s)", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(versionMigration))
+	for _, migration := range versionMigration {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, errors.Errorf("版本(%d_%s)缺少升级文件或者升级文件为空", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements 将sql文本按照分号拆分为多条语句(部分数据库驱动不支持一次执行多条语句)
+// 引号、反引号、注释和PostgreSQL的$$或$tag$函数体中的分号不会作为分隔符
+// 单独一行的"-- +migrate StatementBegin"和"-- +migrate StatementEnd"之间的内容不拆分，用于包含分号的触发器和存储过程
+// 只有MySQL/TiDB/ClickHouse的引号中使用反斜杠转义，PostgreSQL(standard_conforming_strings)和SQLite中反斜杠是普通字符，PostgreSQL的转义字符串(E'...')除外
+func splitStatements(sqlText string, dbType string) []string {
+	backslashEscape := dbType == "mysql" || dbType == "tidb" || dbType == "clickhouse"
+
+	statements := make([]string, 0)
+	var builder strings.Builder
+	inBlock := false // 是否处于语句块标记之间
+
+	flush := func() {
+		if statement := strings.TrimSpace(builder.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		builder.Reset()
+	}
+
+	for i := 0; i < len(sqlText); i++ {
+		char := sqlText[i]
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			// 引号内容原样保留，两个连续的引号或者反斜杠转义的引号不作为结束符
+			escape := char != '`' && (backslashEscape || (dbType == "postgres" && char == '\'' && isEscapeStringPrefix(sqlText, i)))
+			end := i + 1
+			for ; end < len(sqlText); end++ {
+				if sqlText[end] == '\\' && escape {
+					end++
+					continue
+				}
+				if sqlText[end] == char {
+					if end+1 < len(sqlText) && sqlText[end+1] == char {
+						end++
+						continue
+					}
+					break
+				}
+			}
+			builder.WriteString(sqlText[i:min(end+1, len(sqlText))])
+			i = end
+		case char == '-' && strings.HasPrefix(sqlText[i:], "--"):
+			end := strings.IndexByte(sqlText[i:], '\n')
+			if end < 0 {
+				end = len(sqlText) - i
+			}
+			switch strings.TrimSpace(sqlText[i : i+end]) {
+			case statementBeginMarker:
+				flush()
+				inBlock = true
+			case statementEndMarker:
+				flush()
+				inBlock = false
+			}
+			i += end
+			if i < len(sqlText) {
+				builder.WriteByte('\n')
+			}
+		case char == '/' && strings.HasPrefix(sqlText[i:], "/*"):
+			end := strings.Index(sqlText[i+2:], "*/")
+			if end < 0 {
+				i = len(sqlText)
+				continue
+			}
+			i += end + 3
+		case char == '$' && dollarQuoteTag(sqlText, i) != "":
+			// 函数体内容原样保留，直到出现相同的结束标签
+			tag := dollarQuoteTag(sqlText, i)
+			end := strings.Index(sqlText[i+len(tag):], tag)
+			if end < 0 {
+				builder.WriteString(sqlText[i:])
+				i = len(sqlText)
+				continue
+			}
+			builder.WriteString(sqlText[i : i+end+2*len(tag)])
+			i += end + 2*len(tag) - 1
+		case char == ';' && inBlock:
+			builder.WriteByte(char)
+		case char == ';':
+			flush()
+		default:
+			builder.WriteByte(char)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarQuoteTag 判断位置i的$是否为PostgreSQL美元引用的开始标签($$或$tag$)，是则返回标签，否则返回空
+// 标签名由字母、数字和下划线组成且不能以数字开头，因此$1这样的占位符不会被识别为标签
+func dollarQuoteTag(sqlText string, i int) string {
+	// $前面是标识符字符时(例如PostgreSQL中包含$的标识符a$b$)不是标签
+	if i > 0 && isIdentifierChar(sqlText[i-1]) {
+		return ""
+	}
+	for end := i + 1; end < len(sqlText); end++ {
+		char := sqlText[end]
+		if char == '$' {
+			return sqlText[i : end+1]
+		}
+		if !isIdentifierChar(char) || (end == i+1 && char >= '0' && char <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+// isIdentifierChar 判断字符是否为标识符字符(字母、数字或者下划线)
+func isIdentifierChar(char byte) bool {
+	return char == '_' || (char >= '0' && char <= '9') || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+// isEscapeStringPrefix 判断位置i的单引号是否为PostgreSQL转义字符串(E'...')的开始
+func isEscapeStringPrefix(sqlText string, i int) bool {
+	if i == 0 || (sqlText[i-1] != 'E' && sqlText[i-1] != 'e') {
+		return false
+	}
+	// E前面是标识符字符时(例如name'...')不是转义字符串前缀
+	return i < 2 || !isIdentifierChar(sqlText[i-2])
+}
+
+// min 返回两个整数中较小的值
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,215 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		dbType     string
+		sqlText    string
+		statements []string
+	}{
+		{
+			name:       "多条语句",
+			dbType:     "mysql",
+			sqlText:    "CREATE TABLE a (id INT);\n\nINSERT INTO a VALUES (1);",
+			statements: []string{"CREATE TABLE a (id INT)", "INSERT INTO a VALUES (1)"},
+		},
+		{
+			name:       "空语句和末尾空白",
+			dbType:     "mysql",
+			sqlText:    ";;  SELECT 1 ;\n\t",
+			statements: []string{"SELECT 1"},
+		},
+		{
+			name:       "引号中的分号",
+			dbType:     "mysql",
+			sqlText:    "INSERT INTO a VALUES ('x;y', \"z;\", `c;`);SELECT 1",
+			statements: []string{"INSERT INTO a VALUES ('x;y', \"z;\", `c;`)", "SELECT 1"},
+		},
+		{
+			name:       "连续引号转义",
+			dbType:     "postgres",
+			sqlText:    "INSERT INTO a VALUES ('it''s;ok');SELECT 1",
+			statements: []string{"INSERT INTO a VALUES ('it''s;ok')", "SELECT 1"},
+		},
+		{
+			name:       "MySQL反斜杠转义",
+			dbType:     "mysql",
+			sqlText:    `INSERT INTO a VALUES ('it\'s;ok');SELECT 1`,
+			statements: []string{`INSERT INTO a VALUES ('it\'s;ok')`, "SELECT 1"},
+		},
+		{
+			name:       "PostgreSQL反斜杠是普通字符",
+			dbType:     "postgres",
+			sqlText:    `INSERT INTO a VALUES ('C:\');SELECT 1`,
+			statements: []string{`INSERT INTO a VALUES ('C:\')`, "SELECT 1"},
+		},
+		{
+			name:       "SQLite反斜杠是普通字符",
+			dbType:     "sqlite",
+			sqlText:    `INSERT INTO a VALUES ('C:\');SELECT 1`,
+			statements: []string{`INSERT INTO a VALUES ('C:\')`, "SELECT 1"},
+		},
+		{
+			name:       "PostgreSQL转义字符串",
+			dbType:     "postgres",
+			sqlText:    `INSERT INTO a VALUES (E'it\'s;ok');SELECT 1`,
+			statements: []string{`INSERT INTO a VALUES (E'it\'s;ok')`, "SELECT 1"},
+		},
+		{
+			name:       "注释中的分号",
+			dbType:     "mysql",
+			sqlText:    "-- comment;\nSELECT 1; /* block; comment */ SELECT 2",
+			statements: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "PostgreSQL函数体",
+			dbType:  "postgres",
+			sqlText: "CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql;SELECT 1",
+			statements: []string{
+				"CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql",
+				"SELECT 1",
+			},
+		},
+		{
+			name:    "PostgreSQL带标签的函数体",
+			dbType:  "postgres",
+			sqlText: "CREATE FUNCTION f() RETURNS text AS $body$ BEGIN RETURN 'a$$;b'; END; $body$ LANGUAGE plpgsql;SELECT $1",
+			statements: []string{
+				"CREATE FUNCTION f() RETURNS text AS $body$ BEGIN RETURN 'a$$;b'; END; $body$ LANGUAGE plpgsql",
+				"SELECT $1",
+			},
+		},
+		{
+			name:    "SQLite触发器语句块",
+			dbType:  "sqlite",
+			sqlText: "CREATE TABLE a (id INT);\n-- +migrate StatementBegin\nCREATE TRIGGER t AFTER INSERT ON a BEGIN\n  UPDATE a SET id = 1;\n  DELETE FROM a;\nEND;\n-- +migrate StatementEnd\nSELECT 1;",
+			statements: []string{
+				"CREATE TABLE a (id INT)",
+				"CREATE TRIGGER t AFTER INSERT ON a BEGIN\n  UPDATE a SET id = 1;\n  DELETE FROM a;\nEND;",
+				"SELECT 1",
+			},
+		},
+		{
+			name:    "MySQL存储过程语句块",
+			dbType:  "mysql",
+			sqlText: "-- +migrate StatementBegin\nCREATE PROCEDURE p() BEGIN SELECT ';'; SELECT 2; END\n-- +migrate StatementEnd",
+			statements: []string{
+				"CREATE PROCEDURE p() BEGIN SELECT ';'; SELECT 2; END",
+			},
+		},
+		{
+			name:       "未结束的引号",
+			dbType:     "mysql",
+			sqlText:    "SELECT 'abc;",
+			statements: []string{"SELECT 'abc;"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements := splitStatements(test.sqlText, test.dbType)
+			if !reflect.DeepEqual(statements, test.statements) {
+				t.Fatalf("拆分结果错误\n实际: %q\n期望: %q", statements, test.statements)
+			}
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name       string
+		files      fstest.MapFS
+		migrations []Migration
+		err        string
+	}{
+		{
+			name: "按版本号排序并合并升级和回滚文件",
+			files: fstest.MapFS{
+				"0010_add_index.up.sql":     file("CREATE INDEX i ON user (name);"),
+				"0002_create_user.up.sql":   file("CREATE TABLE user (id INT);"),
+				"0002_create_user.down.sql": file("DROP TABLE user;"),
+			},
+			migrations: []Migration{
+				{Version: 2, Name: "create_user", Up: "CREATE TABLE user (id INT);", Down: "DROP TABLE user;"},
+				{Version: 10, Name: "add_index", Up: "CREATE INDEX i ON user (name);"},
+			},
+		},
+		{
+			name: "忽略目录和不符合命名格式的文件",
+			files: fstest.MapFS{
+				"README.md":                 file("说明"),
+				"0001_init.sql":             file("SELECT 1;"),
+				"sub/0003_nested.up.sql":    file("SELECT 3;"),
+				"0001_create-user.up.sql":   file("SELECT 1;"),
+				"0001_create-user.down.sql": file(""),
+			},
+			migrations: []Migration{
+				{Version: 1, Name: "create-user", Up: "SELECT 1;"},
+			},
+		},
+		{
+			name:       "空目录",
+			files:      fstest.MapFS{},
+			migrations: []Migration{},
+		},
+		{
+			name: "同一版本存在不同描述",
+			files: fstest.MapFS{
+				"0001_a.up.sql": file("SELECT 1;"),
+				"0001_b.up.sql": file("SELECT 2;"),
+			},
+			err: "存在多个不同描述的迁移文件",
+		},
+		{
+			name: "缺少升级文件",
+			files: fstest.MapFS{
+				"0001_a.down.sql": file("SELECT 1;"),
+			},
+			err: "缺少升级文件或者升级文件为空",
+		},
+		{
+			name: "升级文件为空",
+			files: fstest.MapFS{
+				"0001_a.up.sql": file("  \n"),
+			},
+			err: "缺少升级文件或者升级文件为空",
+		},
+		{
+			name: "版本号溢出",
+			files: fstest.MapFS{
+				"99999999999999999999_a.up.sql": file("SELECT 1;"),
+			},
+			err: "解析迁移文件(99999999999999999999_a.up.sql)的版本号失败",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrations, err := loadMigrations(test.files)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("错误信息不符合预期(%v)，期望包含(%s)", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("读取迁移文件失败(%s)", err)
+			}
+			if !reflect.DeepEqual(migrations, test.migrations) {
+				t.Fatalf("读取结果错误\n实际: %+v\n期望: %+v", migrations, test.migrations)
+			}
+		})
+	}
+}
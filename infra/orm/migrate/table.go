@@ -0,0 +1,104 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 15:48:52
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 15:48:52
+ * @FilePath: \common\infra\orm\migrate\table.go
+ * @Description: 迁移记录表读写
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// createTable 创建迁移记录表(已存在时忽略)
+// ClickHouse不支持直接删除数据，迁移记录只追加，通过applied列和最新的执行时间确定版本是否已经执行
+func (m *Migrator) createTable(ctx context.Context) error {
+	var query string
+	switch m.config.Type {
+	case "clickhouse":
+		query = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version UInt64, name String, applied UInt8, applied_at DateTime64(3)) ENGINE = MergeTree ORDER BY (version, applied_at)", m.table)
+	case "sqlite":
+		query = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER NOT NULL PRIMARY KEY, name TEXT NOT NULL, applied_at DATETIME NOT NULL)", m.table)
+	case "postgres":
+		query = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)", m.table)
+	default:
+		query = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT UNSIGNED NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL)", m.table)
+	}
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return errors.Wrapf(err, "数据库(%s)创建迁移记录表(%s)失败", m.config.Name, m.table)
+	}
+	return nil
+}
+
+// appliedRecords 查询已经执行的版本
+func (m *Migrator) appliedRecords(ctx context.Context) (map[uint64]appliedRecord, error) {
+	query := fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.table)
+	if m.config.Type == "clickhouse" {
+		query = fmt.Sprintf("SELECT version, argMax(name, applied_at) AS name, max(applied_at) AS applied_at FROM %s GROUP BY version HAVING argMax(applied, applied_at) = 1", m.table)
+	}
+
+	records := make([]appliedRecord, 0)
+	if err := m.db.SelectContext(ctx, &records, query); err != nil {
+		return nil, errors.Wrapf(err, "数据库(%s)查询迁移记录失败", m.config.Name)
+	}
+
+	applied := make(map[uint64]appliedRecord, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// record 写入(up为true)或者删除迁移记录
+func (m *Migrator) record(ctx context.Context, ext sqlx.ExtContext, migration Migration, up bool) error {
+	// ClickHouse驱动只能在事务中写入数据，回滚时追加一条applied为0的记录
+	if m.config.Type == "clickhouse" {
+		tx, err := m.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		applied := uint8(0)
+		if up {
+			applied = 1
+		}
+		query := fmt.Sprintf("INSERT INTO %s (version, name, applied, applied_at) VALUES (?, ?, ?, ?)", m.table)
+		if _, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, applied, time.Now()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	var err error
+	if up {
+		query := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (?, ?, ?)", m.table)
+		_, err = ext.ExecContext(ctx, ext.Rebind(query), migration.Version, migration.Name, time.Now())
+	} else {
+		query := fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.table)
+		_, err = ext.ExecContext(ctx, ext.Rebind(query), migration.Version)
+	}
+	if err != nil {
+		return errors.Wrap(err, "写入迁移记录失败")
+	}
+	return nil
+}
+
+// sortStatuses 按照版本号升序排列迁移状态
+func sortStatuses(statuses []Status) {
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+}
@@ -0,0 +1,305 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-20 09:32:10
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-20 09:32:10
+ * @FilePath: \common\infra\orm\model.go
+ * @Description: 数据模型结构体元数据解析
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// 结构体标签相关定义
+const (
+	dbTag         = "db"      // 列名标签
+	ormTag        = "orm"     // orm选项标签，多个选项使用逗号分隔，例如：`orm:"pk"`
+	primaryKeyOpt = "pk"      // 主键选项
+	autoOpt       = "auto"    // 自增主键选项，未标记时只有一个整数类型主键的结构体视为自增主键，例如：`db:"id" orm:"pk,auto"`
+	versionOpt    = "version" // 乐观锁版本号选项，例如：`db:"version" orm:"version"`
+)
+
+// 默认列名相关定义
+const (
+	defaultPrimaryKey       = "id"          // 未使用orm:"pk"标记主键时默认的主键列名
+	defaultDeleteTimeColumn = "delete_time" // 默认的软删除时间列名
+)
+
+// modelField 数据模型字段元数据
+type modelField struct {
+	column     string // 列名
+	index      []int  // 字段在结构体中的索引路径(支持匿名嵌套结构体)
+	primaryKey bool   // 是否为主键
+	auto       bool   // 是否标记为自增列
+	integer    bool   // 是否为整数类型
+	version    bool   // 是否为乐观锁版本号列
+}
+
+// modelInfo 数据模型元数据
+type modelInfo struct {
+	fields      []modelField          // 所有带db标签的字段
+	primaryKeys []modelField          // 主键字段
+	columns     map[string]modelField // 列名-字段哈希表
+	version     *modelField           // 乐观锁版本号字段，为空时不启用乐观锁
+	auto        *modelField           // 自增主键字段，零值时不插入该列，由数据库生成
+}
+
+// modelCache 数据模型元数据缓存
+var modelCache sync.Map
+
+// parseModel 解析带db标签的结构体(或结构体指针)，返回模型元数据和结构体的反射值
+func parseModel(value interface{}) (*modelInfo, reflect.Value, error) {
+	reflectValue := reflect.ValueOf(value)
+	for reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return nil, reflect.Value{}, errors.New("参数不能为空指针")
+		}
+		reflectValue = reflectValue.Elem()
+	}
+	if reflectValue.Kind() != reflect.Struct {
+		return nil, reflect.Value{}, errors.New("参数必须是结构体或者结构体指针")
+	}
+
+	info, err := modelOf(reflectValue.Type())
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+	return info, reflectValue, nil
+}
+
+// modelOf 获取结构体类型的模型元数据，标签配置不合法(例如版本号列不是整数类型)时返回错误
+func modelOf(reflectType reflect.Type) (*modelInfo, error) {
+	if info, ok := modelCache.Load(reflectType); ok {
+		return info.(*modelInfo), nil
+	}
+
+	fields, err := collectFields(reflectType, nil)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "结构体(%s)标签配置错误", reflectType.Name())
+	}
+
+	info := &modelInfo{
+		fields:  fields,
+		columns: make(map[string]modelField),
+	}
+	for _, field := range info.fields {
+		info.columns[field.column] = field
+		if field.primaryKey {
+			info.primaryKeys = append(info.primaryKeys, field)
+		}
+		if field.version {
+			if info.version != nil {
+				return nil, errors.Errorf("结构体(%s)只能有一个乐观锁版本号列(%s, %s)", reflectType.Name(), info.version.column, field.column)
+			}
+			versionField := field
+			info.version = &versionField
+		}
+	}
+
+	// 未显式标记主键时，使用默认主键列
+	if len(info.primaryKeys) == 0 {
+		if field, ok := info.columns[defaultPrimaryKey]; ok {
+			field.primaryKey = true
+			info.columns[defaultPrimaryKey] = field
+			info.primaryKeys = append(info.primaryKeys, field)
+			for i := range info.fields {
+				if info.fields[i].column == defaultPrimaryKey {
+					info.fields[i].primaryKey = true
+				}
+			}
+		}
+	}
+
+	// 显式标记的自增列优先，否则只有一个整数类型主键时视为自增主键
+	for _, field := range info.fields {
+		if field.auto {
+			if !field.integer {
+				return nil, errors.Errorf("结构体(%s)的自增列(%s)必须是整数类型", reflectType.Name(), field.column)
+			}
+			autoField := field
+			info.auto = &autoField
+			break
+		}
+	}
+	if info.auto == nil && len(info.primaryKeys) == 1 && info.primaryKeys[0].integer {
+		autoField := info.primaryKeys[0]
+		info.auto = &autoField
+	}
+
+	modelCache.Store(reflectType, info)
+	return info, nil
+}
+
+// collectFields 递归收集结构体中带db标签的字段，匿名嵌套结构体的字段视为当前结构体的字段
+func collectFields(reflectType reflect.Type, parentIndex []int) ([]modelField, error) {
+	fields := make([]modelField, 0, reflectType.NumField())
+	for i := 0; i < reflectType.NumField(); i++ {
+		field := reflectType.Field(i)
+		column := strings.Split(field.Tag.Get(dbTag), ",")[0]
+		index := append(append([]int{}, parentIndex...), i)
+
+		if field.Anonymous && column == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded, err := collectFields(fieldType, index)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, embedded...)
+			}
+			continue
+		}
+
+		if column == "" || column == "-" || !field.IsExported() {
+			continue
+		}
+
+		version := hasOption(field.Tag.Get(ormTag), versionOpt)
+		if version && !isIntegerKind(field.Type.Kind()) {
+			return nil, errors.Errorf("乐观锁版本号列(%s)必须是整数类型(%s)", column, field.Type)
+		}
+
+		fields = append(fields, modelField{
+			column:     column,
+			index:      index,
+			primaryKey: hasOption(field.Tag.Get(ormTag), primaryKeyOpt),
+			auto:       hasOption(field.Tag.Get(ormTag), autoOpt),
+			integer:    isIntegerKind(field.Type.Kind()),
+			version:    version,
+		})
+	}
+	return fields, nil
+}
+
+// hasOption 判断orm标签中是否包含指定选项
+func hasOption(tag string, option string) bool {
+	for _, item := range strings.Split(tag, ",") {
+		if strings.TrimSpace(item) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isIntegerKind 判断是否为整数类型，只有整数类型的字段可以作为乐观锁版本号
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// increaseVersion 更新成功后将结构体中的版本号加1，并返回恢复原版本号的回调函数(用于事务回滚)
+// 结构体不可寻址(按值传递)时不做任何处理，返回空
+func (f modelField) increaseVersion(structValue reflect.Value) func() {
+	value := f.fieldValue(structValue)
+	if !value.IsValid() || !value.CanSet() {
+		return nil
+	}
+
+	origin := reflect.New(value.Type()).Elem()
+	origin.Set(value)
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(value.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(value.Uint() + 1)
+	}
+
+	return func() {
+		value.Set(origin)
+	}
+}
+
+// fieldValue 获取字段的值，匿名嵌套的结构体指针为空时返回无效值
+func (f modelField) fieldValue(structValue reflect.Value) reflect.Value {
+	value := structValue
+	for _, i := range f.index {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	return value
+}
+
+// value 获取字段的值
+func (f modelField) value(structValue reflect.Value) interface{} {
+	value := f.fieldValue(structValue)
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
+
+// columnNames 获取所有列名
+func (m *modelInfo) columnNames() []string {
+	columns := make([]string, 0, len(m.fields))
+	for _, field := range m.fields {
+		columns = append(columns, field.column)
+	}
+	return columns
+}
+
+// insertColumns 获取插入语句的列名，自增主键在所有数据中都是零值时不插入该列，由数据库生成
+// 同一批数据中自增主键部分为零值时无法使用同一条插入语句，返回错误
+func (m *modelInfo) insertColumns(structValues []reflect.Value) ([]string, error) {
+	if m.auto == nil {
+		return m.columnNames(), nil
+	}
+
+	zeros := 0
+	for _, structValue := range structValues {
+		if value := m.auto.fieldValue(structValue); !value.IsValid() || value.IsZero() {
+			zeros++
+		}
+	}
+	if zeros == 0 {
+		return m.columnNames(), nil
+	}
+	if zeros != len(structValues) {
+		return nil, errors.Errorf("同一批数据中自增主键(%s)不能部分为零值，请分开插入", m.auto.column)
+	}
+
+	columns := make([]string, 0, len(m.fields))
+	for _, field := range m.fields {
+		if field.column != m.auto.column {
+			columns = append(columns, field.column)
+		}
+	}
+	return columns, nil
+}
+
+// primaryKeyCondition 构建主键查询条件和参数
+func (m *modelInfo) primaryKeyCondition(structValue reflect.Value) (string, []interface{}, error) {
+	if len(m.primaryKeys) == 0 {
+		return "", nil, errors.Errorf("结构体(%s)缺少主键，请使用orm:\"pk\"标记主键字段或者定义%s列", structValue.Type().Name(), defaultPrimaryKey)
+	}
+
+	conditions := make([]string, 0, len(m.primaryKeys))
+	args := make([]interface{}, 0, len(m.primaryKeys))
+	for _, field := range m.primaryKeys {
+		conditions = append(conditions, field.column+" = ?")
+		args = append(args, field.value(structValue))
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
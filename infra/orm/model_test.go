@@ -0,0 +1,69 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInsertColumns(t *testing.T) {
+	type implicitAuto struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	type explicitAuto struct {
+		Code string `db:"code" orm:"pk"`
+		Seq  int    `db:"seq" orm:"auto"`
+	}
+	type stringKey struct {
+		ID   string `db:"id"`
+		Name string `db:"name"`
+	}
+	type compositeKey struct {
+		UserID int64 `db:"user_id" orm:"pk"`
+		RoleID int64 `db:"role_id" orm:"pk"`
+	}
+
+	tests := []struct {
+		name    string
+		values  []interface{}
+		columns []string
+	}{
+		{"整数主键为零值", []interface{}{implicitAuto{Name: "a"}}, []string{"name"}},
+		{"整数主键不为零值", []interface{}{&implicitAuto{ID: 1}}, []string{"id", "name"}},
+		{"显式标记的自增列", []interface{}{explicitAuto{Code: "a"}}, []string{"code"}},
+		{"字符串主键", []interface{}{stringKey{}}, []string{"id", "name"}},
+		{"联合主键", []interface{}{compositeKey{}}, []string{"user_id", "role_id"}},
+		{"批量数据都为零值", []interface{}{implicitAuto{Name: "a"}, implicitAuto{Name: "b"}}, []string{"name"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var model *modelInfo
+			structValues := make([]reflect.Value, 0, len(test.values))
+			for _, value := range test.values {
+				info, structValue, err := parseModel(value)
+				if err != nil {
+					t.Fatalf("解析数据模型失败(%s)", err)
+				}
+				model = info
+				structValues = append(structValues, structValue)
+			}
+
+			columns, err := model.insertColumns(structValues)
+			if err != nil {
+				t.Fatalf("获取插入列失败(%s)", err)
+			}
+			if !reflect.DeepEqual(columns, test.columns) {
+				t.Fatalf("插入列错误(%v)，期望(%v)", columns, test.columns)
+			}
+		})
+	}
+
+	type invalidAuto struct {
+		ID string `db:"id" orm:"pk,auto"`
+	}
+	if _, _, err := parseModel(invalidAuto{}); err == nil || !strings.Contains(err.Error(), "自增列(id)必须是整数类型") {
+		t.Fatalf("非整数类型的自增列应该返回错误(%v)", err)
+	}
+}
@@ -12,6 +12,8 @@ package orm
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/hongliu9527/common/infra/base"
 	"github.com/hongliu9527/common/infra/common"
@@ -29,8 +31,17 @@ type ormInfra struct {
 	config            *config.OrmInfraConfig           // 数据库配置信息
 	nameConfig        map[string]config.DataBaseConfig // 数据库实例名-配置信息哈希表
 	tableNameInstance map[string]*sqlx.DB              // 数据库表名-数据库实例哈希表
+	tableNameConfig   map[string]config.DataBaseConfig // 数据库表名-配置信息哈希表
 	nameInstance      map[string]*sqlx.DB              // 数据库实例名-数据库实例哈希表
+	nameReplicas      map[string]*replicaSet           // 数据库实例名-只读副本集合哈希表
+	shardingRules     map[string]common.ShardingRule   // 逻辑表名-分表规则哈希表
+	logger            *sqlLogger                       // sql日志记录器
+	cache             *queryCache                      // 查询结果缓存(未启用时为空)
 	lastError         error                            // 实例的最新错误信息
+	lastRefresh       time.Time                        // 最近一次因找不到表名而刷新表名的时间
+
+	mutex        sync.RWMutex // 实例和表名哈希表的读写锁
+	refreshMutex sync.Mutex   // 表名刷新互斥锁，避免并发刷新
 
 	ctx    context.Context    // 上下文对象
 	cancel context.CancelFunc // 取消回调函数
@@ -45,7 +56,10 @@ func New(ormConfig *config.OrmInfraConfig) common.OrmInfra {
 	singleton.config = ormConfig
 	singleton.nameConfig = make(map[string]config.DataBaseConfig)
 	singleton.tableNameInstance = make(map[string]*sqlx.DB)
+	singleton.tableNameConfig = make(map[string]config.DataBaseConfig)
 	singleton.nameInstance = make(map[string]*sqlx.DB)
+	singleton.nameReplicas = make(map[string]*replicaSet)
+	singleton.shardingRules = make(map[string]common.ShardingRule)
 
 	// 构建基础设施基类
 	singleton.BaseInfra = base.NewBaseInfra(singleton.Name(), ormConfig, singleton.start, singleton.stop)
@@ -11,28 +11,131 @@
 package orm
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/hongliu9527/go-tools/logger"
 )
 
-// Conn 获取数据库查询句柄
+// Conn 获取数据库查询句柄，找不到表名时会重新查询一次表名(表可能是运行期间新建的，例如按月分表)
 func (i *ormInfra) Conn(tableName string) (common.Orm, error) {
+	conn, err := i.connection(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// ConnClickHouse 获取ClickHouse数据表的查询句柄，表所在的数据库不是ClickHouse时返回错误
+func (i *ormInfra) ConnClickHouse(tableName string) (common.ClickHouseOrm, error) {
+	conn, err := i.connection(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.config.Type != "clickhouse" {
+		i.lastError = fmt.Errorf("数据表(%s)所在的数据库(%s)类型为%s，不是ClickHouse", tableName, conn.config.Name, conn.config.Type)
+		return nil, i.lastError
+	}
+
+	return &clickHouseConnection{dbConnection: conn}, nil
+}
+
+// connection 根据表名创建新的查询会话，找不到表名时重新查询一次表名
+func (i *ormInfra) connection(tableName string) (*dbConnection, error) {
 	// 根据表明查询实例
-	db, ok := i.tableNameInstance[tableName]
+	conn, ok := i.lookupTable(tableName)
+	if !ok {
+		if err := i.refreshOnMiss(); err != nil {
+			logger.Warning("刷新表名列表失败(%s)", err.Error())
+		}
+		conn, ok = i.lookupTable(tableName)
+	}
 	if !ok {
 		i.lastError = fmt.Errorf("根据表名(%s)无法找到对应的orm实例", tableName)
 		return nil, i.lastError
 	}
 
+	return conn, nil
+}
+
+// lookupTable 根据表名创建新的查询会话
+func (i *ormInfra) lookupTable(tableName string) (*dbConnection, bool) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	db, ok := i.tableNameInstance[tableName]
+	if !ok {
+		return nil, false
+	}
+	dbConfig := i.tableNameConfig[tableName]
+
+	var cache *queryCache
+	if i.cache.enabled(tableName) {
+		cache = i.cache
+	}
+
 	// 创建新的查询会话
 	return &dbConnection{
 		db:          db,
 		tx:          nil,
+		ctx:         i.ctx,
 		serviceName: i.InfraName,
+		replicas:    i.nameReplicas[dbConfig.Name],
+		logger:      i.logger,
+		cache:       cache,
+		config:      dbConfig,
 		tableName:   tableName,
 		lastError:   nil,
-	}, nil
+	}, true
+}
+
+// RegisterShardingRule 注册逻辑表的分表规则，重复注册时覆盖之前的规则
+func (i *ormInfra) RegisterShardingRule(logicalTable string, rule common.ShardingRule) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.shardingRules[logicalTable] = rule
+}
+
+// EnableCache 启用基于Redis的查询结果缓存，tables为空时所有数据表都启用缓存，redis为空时关闭缓存
+// 启用缓存后，通过Conn获取的连接上的Get/Select查询结果会缓存ttl时长，同一数据表的写操作执行成功后缓存失效
+// 注意：直接在数据库中或者通过其他服务修改数据时缓存不会失效，只适合配置表等主要通过本库修改的数据
+// 启用缓存的数据表的Get/Select查询使用主库(不使用只读副本)，查询结果使用gob编码，只缓存导出字段(与db标签和json标签无关)
+// 包含interface{}字段或者只有未导出字段的自定义类型无法编码，这类查询不缓存
+func (i *ormInfra) EnableCache(redis common.Redis, ttl time.Duration, tables ...string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if redis == nil {
+		i.cache = nil
+		return
+	}
+	i.cache = newQueryCache(redis, ttl, tables)
+}
+
+// ConnShard 根据逻辑表的分表规则和分片键计算物理表名，并获取物理表的数据库查询句柄
+func (i *ormInfra) ConnShard(logicalTable string, shardKey interface{}) (common.Orm, error) {
+	i.mutex.RLock()
+	rule, ok := i.shardingRules[logicalTable]
+	i.mutex.RUnlock()
+	if !ok {
+		i.lastError = fmt.Errorf("逻辑表(%s)未注册分表规则", logicalTable)
+		return nil, i.lastError
+	}
+
+	tableName, err := rule.PhysicalTable(logicalTable, shardKey)
+	if err != nil {
+		i.lastError = fmt.Errorf("计算逻辑表(%s)的物理表名失败(%s)", logicalTable, err.Error())
+		return nil, i.lastError
+	}
+
+	return i.Conn(tableName)
 }
 
 // Get 查询单个数据
@@ -47,10 +150,16 @@ func (i *ormInfra) Select(dest interface{}, query string, args ...interface{}) e
 	return i.lastError
 }
 
+// QueryRows 查询数据并返回逐行读取的结果集
+func (i *ormInfra) QueryRows(query string, args ...interface{}) (common.Rows, error) {
+	i.mustStartWithConn()
+	return nil, i.lastError
+}
+
 // Insert 创建单个数据
-func (i *ormInfra) Insert(value interface{}) (uint64, error) {
+func (i *ormInfra) Insert(value interface{}) (common.Result, error) {
 	i.mustStartWithConn()
-	return 0, i.lastError
+	return common.Result{}, i.lastError
 }
 
 // BatchInsert 批量插入
@@ -60,21 +169,45 @@ func (i *ormInfra) BatchInsert(values interface{}) error {
 }
 
 // Update 更新数据
-func (i *ormInfra) Update(condition string, arg map[string]interface{}) error {
+func (i *ormInfra) Update(condition string, arg map[string]interface{}) (common.Result, error) {
 	i.mustStartWithConn()
-	return i.lastError
+	return common.Result{}, i.lastError
 }
 
 // Exec 执行原生sql
-func (i *ormInfra) Exec(qeury string, args ...interface{}) error {
+func (i *ormInfra) Exec(qeury string, args ...interface{}) (common.Result, error) {
 	i.mustStartWithConn()
-	return i.lastError
+	return common.Result{}, i.lastError
 }
 
 // Delete 删除数据
-func (i *ormInfra) Delete(condition string, args ...interface{}) error {
+func (i *ormInfra) Delete(condition string, args ...interface{}) (common.Result, error) {
 	i.mustStartWithConn()
-	return i.lastError
+	return common.Result{}, i.lastError
+}
+
+// ForceDelete 物理删除数据
+func (i *ormInfra) ForceDelete(condition string, args ...interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// UpdateByPK 根据主键更新结构体数据
+func (i *ormInfra) UpdateByPK(value interface{}, columns ...string) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// UpdateStruct 根据主键更新结构体中发生变化的字段
+func (i *ormInfra) UpdateStruct(origin interface{}, value interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// Upsert 插入或更新数据
+func (i *ormInfra) Upsert(value interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
 }
 
 // Begin 开启事务
@@ -95,6 +228,102 @@ func (i *ormInfra) Commit() error {
 	return i.lastError
 }
 
+// GetContext 查询单个数据(带上下文)
+func (i *ormInfra) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// SelectContext 查询多个数据(带上下文)
+func (i *ormInfra) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// QueryRowsContext 查询数据并返回逐行读取的结果集(带上下文)
+func (i *ormInfra) QueryRowsContext(ctx context.Context, query string, args ...interface{}) (common.Rows, error) {
+	i.mustStartWithConn()
+	return nil, i.lastError
+}
+
+// InsertContext 创建单个数据(带上下文)
+func (i *ormInfra) InsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// BatchInsertContext 批量插入(带上下文)
+func (i *ormInfra) BatchInsertContext(ctx context.Context, values interface{}) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// UpdateContext 更新数据(带上下文)
+func (i *ormInfra) UpdateContext(ctx context.Context, condition string, arg map[string]interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// DeleteContext 删除数据(带上下文)
+func (i *ormInfra) DeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// BatchInsertWithOptions 分批插入切片数据(带上下文和批量选项)
+func (i *ormInfra) BatchInsertWithOptions(ctx context.Context, values interface{}, options common.BatchOptions) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// BatchInsertStream 分批插入通道中的数据
+func (i *ormInfra) BatchInsertStream(ctx context.Context, values <-chan interface{}, options common.BatchOptions) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// ForceDeleteContext 物理删除数据(带上下文)
+func (i *ormInfra) ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// UpdateByPKContext 根据主键更新结构体数据(带上下文)
+func (i *ormInfra) UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// UpdateStructContext 根据主键更新结构体中发生变化的字段(带上下文)
+func (i *ormInfra) UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// UpsertContext 插入或更新数据(带上下文)
+func (i *ormInfra) UpsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// ExecContext 执行原生sql(带上下文)
+func (i *ormInfra) ExecContext(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	i.mustStartWithConn()
+	return common.Result{}, i.lastError
+}
+
+// BeginTx 开启事务(带上下文和事务选项)
+func (i *ormInfra) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
+// WithTx 在事务中执行函数
+func (i *ormInfra) WithTx(ctx context.Context, fn func(tx common.Orm) error) error {
+	i.mustStartWithConn()
+	return i.lastError
+}
+
 func (i *ormInfra) mustStartWithConn() {
 	if i.lastError == nil {
 		i.lastError = fmt.Errorf("数据库查询连接未创建，请检查Orm是否已经最先调用Conn方法")
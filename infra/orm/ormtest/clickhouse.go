@@ -0,0 +1,181 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 11:58:14
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 11:58:14
+ * @FilePath: \common\infra\orm\ormtest\clickhouse.go
+ * @Description: 内存ClickHouse专用连接
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package ormtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/orm"
+
+	"github.com/pkg/errors"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.ClickHouseOrm = (*clickHouseConn)(nil)
+
+// clickHouseConn 内存ClickHouse专用连接，不支持的操作与真实实现一致返回orm.ErrUnsupported
+type clickHouseConn struct {
+	*conn
+}
+
+// unsupported 构建不支持操作的错误
+func (c *clickHouseConn) unsupported(operation string) error {
+	return errors.WithMessagef(orm.ErrUnsupported, "ClickHouse数据表(%s)不支持%s", c.tableName, operation)
+}
+
+// Begin 开启事务(不支持)
+func (c *clickHouseConn) Begin() error {
+	return c.unsupported("事务")
+}
+
+// BeginTx 开启事务(不支持)
+func (c *clickHouseConn) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	return c.Begin()
+}
+
+// Commit 提交事务(不支持)
+func (c *clickHouseConn) Commit() error {
+	return c.Begin()
+}
+
+// Rollback 回滚事务(不支持)
+func (c *clickHouseConn) Rollback() error {
+	return c.Begin()
+}
+
+// WithTx 在事务中执行函数(不支持)
+func (c *clickHouseConn) WithTx(ctx context.Context, fn func(tx common.Orm) error) error {
+	return c.Begin()
+}
+
+// Update 更新数据(不支持)
+func (c *clickHouseConn) Update(condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("Update")
+}
+
+// UpdateContext 更新数据(不支持)
+func (c *clickHouseConn) UpdateContext(ctx context.Context, condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("Update")
+}
+
+// UpdateByPK 根据主键更新结构体数据(不支持)
+func (c *clickHouseConn) UpdateByPK(value interface{}, columns ...string) (common.Result, error) {
+	return common.Result{}, c.unsupported("UpdateByPK")
+}
+
+// UpdateByPKContext 根据主键更新结构体数据(不支持)
+func (c *clickHouseConn) UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (common.Result, error) {
+	return common.Result{}, c.unsupported("UpdateByPK")
+}
+
+// UpdateStruct 根据主键更新结构体中发生变化的字段(不支持)
+func (c *clickHouseConn) UpdateStruct(origin interface{}, value interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("UpdateStruct")
+}
+
+// UpdateStructContext 根据主键更新结构体中发生变化的字段(不支持)
+func (c *clickHouseConn) UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("UpdateStruct")
+}
+
+// Delete 删除数据(不支持)
+func (c *clickHouseConn) Delete(condition string, args ...interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("Delete")
+}
+
+// DeleteContext 删除数据(不支持)
+func (c *clickHouseConn) DeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("Delete")
+}
+
+// ForceDelete 物理删除数据(不支持)
+func (c *clickHouseConn) ForceDelete(condition string, args ...interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("ForceDelete")
+}
+
+// ForceDeleteContext 物理删除数据(不支持)
+func (c *clickHouseConn) ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return common.Result{}, c.unsupported("ForceDelete")
+}
+
+// AsyncInsert 异步插入结构体或结构体切片
+func (c *clickHouseConn) AsyncInsert(ctx context.Context, values interface{}, wait bool) error {
+	_, err := c.write(ctx, "ASYNC INSERT INTO "+c.tableName, values)
+	return err
+}
+
+// ColumnarInsert 按列批量插入
+func (c *clickHouseConn) ColumnarInsert(ctx context.Context, columns []string, data ...interface{}) error {
+	if len(columns) != len(data) {
+		return fmt.Errorf("列数(%d)与数据列数(%d)不一致", len(columns), len(data))
+	}
+
+	_, err := c.write(ctx, fmt.Sprintf("COLUMNAR INSERT INTO %s (%s)", c.tableName, strings.Join(columns, ", ")), data...)
+	return err
+}
+
+// MutateUpdate 提交ALTER TABLE ... UPDATE异步变更
+func (c *clickHouseConn) MutateUpdate(ctx context.Context, updateValue map[string]interface{}, condition string, args ...interface{}) error {
+	columns, values := sortedColumns(updateValue)
+	query := fmt.Sprintf("ALTER TABLE %s UPDATE %s WHERE %s", c.tableName, strings.Join(columns, ", "), condition)
+	_, err := c.write(ctx, query, append(values, args...)...)
+	return err
+}
+
+// MutateDelete 提交ALTER TABLE ... DELETE异步变更
+func (c *clickHouseConn) MutateDelete(ctx context.Context, condition string, args ...interface{}) error {
+	_, err := c.write(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE %s", c.tableName, condition), args...)
+	return err
+}
+
+// Mutations 查询数据表未完成的变更
+func (c *clickHouseConn) Mutations(ctx context.Context) ([]common.MutationStatus, error) {
+	mutations := make([]common.MutationStatus, 0)
+	if err := c.SelectContext(ctx, &mutations, "SELECT MUTATIONS FROM "+c.tableName); err != nil {
+		return nil, err
+	}
+	return mutations, nil
+}
+
+// WaitMutations 等待数据表所有变更完成，内存实现只记录语句并返回期望的错误
+func (c *clickHouseConn) WaitMutations(ctx context.Context, interval time.Duration) error {
+	_, err := c.write(ctx, "WAIT MUTATIONS FROM "+c.tableName)
+	return err
+}
+
+// GetWithSettings 使用查询设置查询单个数据
+func (c *clickHouseConn) GetWithSettings(ctx context.Context, dest interface{}, settings common.ClickHouseSettings, query string, args ...interface{}) error {
+	return c.GetContext(ctx, dest, query, append(args, settings)...)
+}
+
+// SelectWithSettings 使用查询设置查询多个数据
+func (c *clickHouseConn) SelectWithSettings(ctx context.Context, dest interface{}, settings common.ClickHouseSettings, query string, args ...interface{}) error {
+	return c.SelectContext(ctx, dest, query, append(args, settings)...)
+}
+
+// From 构建带FINAL/SAMPLE修饰的表名
+func (c *clickHouseConn) From(final bool, sample float64) string {
+	table := c.tableName
+	if final {
+		table += " FINAL"
+	}
+	if sample > 0 && sample < 1 {
+		table += " SAMPLE " + strconv.FormatFloat(sample, 'f', -1, 64)
+	}
+	return table
+}
@@ -0,0 +1,360 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 10:41:05
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 10:41:05
+ * @FilePath: \common\infra\orm\ormtest\conn.go
+ * @Description: 内存orm数据表连接
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package ormtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.Orm = (*conn)(nil)
+
+// conn 内存orm数据表连接，记录语句并按期望返回结果
+type conn struct {
+	fake      *Fake          // 所属的内存orm基础设施
+	tableName string         // 数据表名称
+	levels    [][]*Statement // 每层事务(保存点)中执行的语句，为空时不在事务中
+}
+
+// newConn 创建数据表连接
+func newConn(fake *Fake, tableName string) *conn {
+	return &conn{fake: fake, tableName: tableName}
+}
+
+// track 将语句加入当前事务(调用者必须持有锁)
+func (c *conn) track(statement *Statement) {
+	if len(c.levels) == 0 {
+		return
+	}
+
+	statement.TxState = TxPending
+	top := len(c.levels) - 1
+	c.levels[top] = append(c.levels[top], statement)
+}
+
+// exec 检查上下文对象后执行语句
+func (c *conn) exec(ctx context.Context, query string, args ...interface{}) (reply, error) {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return reply{}, err
+		}
+	}
+	return c.fake.handle(c, query, args)
+}
+
+// write 执行写操作语句并返回执行结果
+func (c *conn) write(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	reply, err := c.exec(ctx, query, args...)
+	if err != nil {
+		return common.Result{}, err
+	}
+	return reply.result, nil
+}
+
+// Conn 获取数据库连接，与真实实现一致返回当前连接
+func (c *conn) Conn(tableName string) (common.Orm, error) {
+	return c, nil
+}
+
+// Get 查询单个数据
+func (c *conn) Get(dest interface{}, query string, args ...interface{}) error {
+	return c.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext 查询单个数据(带上下文)，没有匹配的期望或者期望未设置返回数据时返回sql.ErrNoRows
+func (c *conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	reply, err := c.exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if reply.rows == nil {
+		return sql.ErrNoRows
+	}
+	return assignOne(dest, reply.rows)
+}
+
+// Select 查询多个数据
+func (c *conn) Select(dest interface{}, query string, args ...interface{}) error {
+	return c.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext 查询多个数据(带上下文)，没有匹配的期望或者期望未设置返回数据时返回空切片
+func (c *conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	reply, err := c.exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return assignAll(dest, reply.rows)
+}
+
+// QueryRows 查询数据并返回逐行读取的结果集
+func (c *conn) QueryRows(query string, args ...interface{}) (common.Rows, error) {
+	return c.QueryRowsContext(context.Background(), query, args...)
+}
+
+// QueryRowsContext 查询数据并返回逐行读取的结果集(带上下文)
+func (c *conn) QueryRowsContext(ctx context.Context, query string, args ...interface{}) (common.Rows, error) {
+	reply, err := c.exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(reply.rows), nil
+}
+
+// Insert 插入单个数据
+func (c *conn) Insert(value interface{}) (common.Result, error) {
+	return c.InsertContext(context.Background(), value)
+}
+
+// InsertContext 插入单个数据(带上下文)
+func (c *conn) InsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	return c.write(ctx, "INSERT INTO "+c.tableName, value)
+}
+
+// BatchInsert 批量插入
+func (c *conn) BatchInsert(values interface{}) error {
+	return c.BatchInsertContext(context.Background(), values)
+}
+
+// BatchInsertContext 批量插入(带上下文)
+func (c *conn) BatchInsertContext(ctx context.Context, values interface{}) error {
+	return c.BatchInsertWithOptions(ctx, values, common.BatchOptions{})
+}
+
+// BatchInsertWithOptions 批量插入(带上下文和批量选项)，整个切片记录为一条语句，批量选项只用于回调进度
+func (c *conn) BatchInsertWithOptions(ctx context.Context, values interface{}, options common.BatchOptions) error {
+	sliceValue := reflect.ValueOf(values)
+	if sliceValue.Kind() != reflect.Slice {
+		return errors.New("批量插入的数据必须是切片")
+	}
+	if sliceValue.Len() == 0 {
+		return nil
+	}
+
+	_, err := c.write(ctx, "BATCH INSERT INTO "+c.tableName, values)
+	if options.Progress != nil {
+		progress := common.BatchProgress{Chunk: 1, Rows: sliceValue.Len(), Err: err}
+		if err == nil {
+			progress.Total = sliceValue.Len()
+		}
+		options.Progress(progress)
+	}
+	return err
+}
+
+// BatchInsertStream 读取通道中的所有数据后作为一个切片批量插入
+func (c *conn) BatchInsertStream(ctx context.Context, values <-chan interface{}, options common.BatchOptions) error {
+	items := make([]interface{}, 0)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value, ok := <-values:
+			if !ok {
+				return c.BatchInsertWithOptions(ctx, items, options)
+			}
+			items = append(items, value)
+		}
+	}
+}
+
+// Update 更新数据
+func (c *conn) Update(condition string, updateValue map[string]interface{}) (common.Result, error) {
+	return c.UpdateContext(context.Background(), condition, updateValue)
+}
+
+// UpdateContext 更新数据(带上下文)
+func (c *conn) UpdateContext(ctx context.Context, condition string, updateValue map[string]interface{}) (common.Result, error) {
+	columns, values := sortedColumns(updateValue)
+	return c.write(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.tableName, strings.Join(columns, ", "), condition), values...)
+}
+
+// UpdateByPK 根据主键更新结构体数据
+func (c *conn) UpdateByPK(value interface{}, columns ...string) (common.Result, error) {
+	return c.UpdateByPKContext(context.Background(), value, columns...)
+}
+
+// UpdateByPKContext 根据主键更新结构体数据(带上下文)
+func (c *conn) UpdateByPKContext(ctx context.Context, value interface{}, columns ...string) (common.Result, error) {
+	args := make([]interface{}, 0, len(columns)+1)
+	args = append(args, value)
+	for _, column := range columns {
+		args = append(args, column)
+	}
+	return c.write(ctx, "UPDATE "+c.tableName+" BY PK", args...)
+}
+
+// UpdateStruct 根据主键更新结构体中发生变化的字段
+func (c *conn) UpdateStruct(origin interface{}, value interface{}) (common.Result, error) {
+	return c.UpdateStructContext(context.Background(), origin, value)
+}
+
+// UpdateStructContext 根据主键更新结构体中发生变化的字段(带上下文)
+func (c *conn) UpdateStructContext(ctx context.Context, origin interface{}, value interface{}) (common.Result, error) {
+	return c.write(ctx, "UPDATE "+c.tableName+" BY STRUCT", origin, value)
+}
+
+// Upsert 插入数据，主键或唯一键冲突时更新
+func (c *conn) Upsert(value interface{}) (common.Result, error) {
+	return c.UpsertContext(context.Background(), value)
+}
+
+// UpsertContext 插入或更新数据(带上下文)
+func (c *conn) UpsertContext(ctx context.Context, value interface{}) (common.Result, error) {
+	return c.write(ctx, "UPSERT INTO "+c.tableName, value)
+}
+
+// Delete 删除数据
+func (c *conn) Delete(condition string, args ...interface{}) (common.Result, error) {
+	return c.DeleteContext(context.Background(), condition, args...)
+}
+
+// DeleteContext 删除数据(带上下文)
+func (c *conn) DeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return c.write(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", c.tableName, condition), args...)
+}
+
+// ForceDelete 物理删除数据
+func (c *conn) ForceDelete(condition string, args ...interface{}) (common.Result, error) {
+	return c.ForceDeleteContext(context.Background(), condition, args...)
+}
+
+// ForceDeleteContext 物理删除数据(带上下文)
+func (c *conn) ForceDeleteContext(ctx context.Context, condition string, args ...interface{}) (common.Result, error) {
+	return c.write(ctx, fmt.Sprintf("FORCE DELETE FROM %s WHERE %s", c.tableName, condition), args...)
+}
+
+// Exec 执行原生sql
+func (c *conn) Exec(query string, args ...interface{}) (common.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext 执行原生sql(带上下文)
+func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	return c.write(ctx, query, args...)
+}
+
+// Begin 开启事务，已经存在事务时创建保存点(嵌套事务)
+func (c *conn) Begin() error {
+	return c.BeginTx(context.Background(), nil)
+}
+
+// BeginTx 开启事务(带上下文和事务选项)，期望返回错误时不开启事务
+func (c *conn) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	if _, err := c.exec(ctx, "BEGIN"); err != nil {
+		return err
+	}
+
+	c.fake.mutex.Lock()
+	defer c.fake.mutex.Unlock()
+
+	c.levels = append(c.levels, nil)
+	return nil
+}
+
+// Commit 提交事务，嵌套事务中释放最近的保存点；最外层事务提交失败时事务中的语句视为已回滚
+func (c *conn) Commit() error {
+	if !c.inTx() {
+		return errors.New("事务实例不存在")
+	}
+
+	_, err := c.exec(context.Background(), "COMMIT")
+	state := TxCommitted
+	if err != nil {
+		state = TxRolledBack
+	}
+	c.finish(state)
+	return err
+}
+
+// Rollback 回滚事务，嵌套事务中回滚到最近的保存点
+func (c *conn) Rollback() error {
+	if !c.inTx() {
+		return errors.New("事务实例不存在")
+	}
+
+	_, err := c.exec(context.Background(), "ROLLBACK")
+	c.finish(TxRolledBack)
+	return err
+}
+
+// inTx 判断是否在事务中
+func (c *conn) inTx() bool {
+	c.fake.mutex.Lock()
+	defer c.fake.mutex.Unlock()
+
+	return len(c.levels) > 0
+}
+
+// finish 结束最近一层事务，提交的保存点中的语句并入上一层事务，等待最外层事务结束
+func (c *conn) finish(state TxState) {
+	c.fake.mutex.Lock()
+	defer c.fake.mutex.Unlock()
+
+	top := len(c.levels) - 1
+	statements := c.levels[top]
+	c.levels = c.levels[:top]
+
+	if top > 0 && state == TxCommitted {
+		c.levels[top-1] = append(c.levels[top-1], statements...)
+		return
+	}
+	for _, statement := range statements {
+		statement.TxState = state
+	}
+}
+
+// WithTx 在事务中执行函数，函数返回错误或者发生panic时回滚事务，否则提交事务
+func (c *conn) WithTx(ctx context.Context, fn func(tx common.Orm) error) (err error) {
+	if err = c.BeginTx(ctx, nil); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(c); err != nil {
+		if rollbackErr := c.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w(回滚事务失败: %s)", err, rollbackErr.Error())
+		}
+		return err
+	}
+
+	return c.Commit()
+}
+
+// sortedColumns 按列名排序返回更新的列名和值
+func sortedColumns(updateValue map[string]interface{}) ([]string, []interface{}) {
+	columns := make([]string, 0, len(updateValue))
+	for column := range updateValue {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	values := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		values = append(values, updateValue[column])
+	}
+	return columns, values
+}
@@ -0,0 +1,130 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 10:08:52
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 10:08:52
+ * @FilePath: \common\infra\orm\ormtest\expectation.go
+ * @Description: 语句执行期望
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package ormtest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// Expectation 语句执行期望，描述匹配条件以及匹配后返回的结果
+type Expectation struct {
+	fake    *Fake          // 所属的内存orm基础设施
+	pattern *regexp.Regexp // 匹配sql语句的正则表达式
+	table   string         // 匹配的数据表名称，为空时匹配所有数据表
+	args    []interface{}  // 匹配的参数，为空时不检查参数
+	hasArgs bool           // 是否检查参数
+	times   int            // 期望执行的次数，0表示至少执行一次且不限次数
+	rows    interface{}    // 查询返回的数据(结构体、结构体切片或者基础类型)
+	result  common.Result  // 写操作返回的执行结果
+	err     error          // 返回的错误
+	calls   int            // 已经匹配的次数
+}
+
+// OnTable 只匹配指定数据表上执行的语句
+func (e *Expectation) OnTable(tableName string) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.table = tableName
+	return e
+}
+
+// WithArgs 只匹配参数完全相同(reflect.DeepEqual)的语句
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// Times 设置期望执行的次数，匹配次数达到后不再匹配该期望
+func (e *Expectation) Times(times int) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.times = times
+	return e
+}
+
+// WillReturnRows 设置查询返回的数据，Get使用切片的第一个元素(空切片返回sql.ErrNoRows)，Select使用切片的所有元素
+func (e *Expectation) WillReturnRows(rows interface{}) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult 设置写操作返回的执行结果
+func (e *Expectation) WillReturnResult(result common.Result) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.result = result
+	return e
+}
+
+// WillReturnError 设置返回的错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.fake.mutex.Lock()
+	defer e.fake.mutex.Unlock()
+
+	e.err = err
+	return e
+}
+
+// match 判断语句是否匹配该期望(调用者必须持有锁)
+func (e *Expectation) match(tableName string, query string, args []interface{}) bool {
+	if e.times > 0 && e.calls >= e.times {
+		return false
+	}
+	if e.table != "" && e.table != tableName {
+		return false
+	}
+	if !e.pattern.MatchString(query) {
+		return false
+	}
+	if e.hasArgs && !argsEqual(e.args, args) {
+		return false
+	}
+	return true
+}
+
+// unmet 期望未满足时返回原因(调用者必须持有锁)
+func (e *Expectation) unmet() string {
+	if e.times > 0 && e.calls != e.times {
+		return fmt.Sprintf("期望(%s)应执行%d次，实际执行%d次", e.pattern, e.times, e.calls)
+	}
+	if e.times == 0 && e.calls == 0 {
+		return fmt.Sprintf("期望(%s)没有执行", e.pattern)
+	}
+	return ""
+}
+
+// argsEqual 比较参数是否相同，nil和空切片视为相同
+func argsEqual(expected []interface{}, actual []interface{}) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(expected[i], actual[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,344 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 09:36:18
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 09:36:18
+ * @FilePath: \common\infra\orm\ormtest\fake.go
+ * @Description: 单元测试使用的内存orm基础设施
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+// Package ormtest 提供不依赖真实数据库的common.OrmInfra实现，用于业务代码的单元测试
+//
+// Fake记录所有执行过的语句，并按照预设的期望(Expect)返回查询结果、执行结果或者错误。
+// 原生sql按原样记录；结构体相关的操作没有真实的sql语句，按以下简化语句记录，可以使用正则表达式匹配：
+//
+//	Insert                  INSERT INTO 表名                       参数：[结构体]
+//	BatchInsert             BATCH INSERT INTO 表名                 参数：[结构体切片]
+//	Update                  UPDATE 表名 SET 列1, 列2 WHERE 条件     参数：按列名排序的更新值
+//	UpdateByPK              UPDATE 表名 BY PK                      参数：[结构体, 列名...]
+//	UpdateStruct            UPDATE 表名 BY STRUCT                  参数：[修改前结构体, 修改后结构体]
+//	Upsert                  UPSERT INTO 表名                       参数：[结构体]
+//	Delete                  DELETE FROM 表名 WHERE 条件             参数：条件参数
+//	ForceDelete             FORCE DELETE FROM 表名 WHERE 条件       参数：条件参数
+//	Begin/Commit/Rollback   BEGIN、COMMIT、ROLLBACK
+//
+// ClickHouse专用连接的语句见ConnClickHouse的说明。
+package ormtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.OrmInfra = (*Fake)(nil)
+
+// 常量相关定义
+const (
+	FakeInfraName = "OrmFake" // 基础设施名称
+)
+
+// TxState 语句所在事务的状态
+type TxState int
+
+// "事务状态"相关定义
+const (
+	TxNone       TxState = iota // 不在事务中执行
+	TxPending                   // 事务尚未结束
+	TxCommitted                 // 事务已提交
+	TxRolledBack                // 事务(或者保存点)已回滚
+)
+
+// String 事务状态名称
+func (s TxState) String() string {
+	switch s {
+	case TxNone:
+		return "none"
+	case TxPending:
+		return "pending"
+	case TxCommitted:
+		return "committed"
+	case TxRolledBack:
+		return "rolled back"
+	default:
+		return fmt.Sprintf("TxState(%d)", int(s))
+	}
+}
+
+// Statement 执行过的语句
+type Statement struct {
+	Table   string        // 数据表名称，在Fake上直接执行时为空
+	Query   string        // sql语句(结构体操作为简化语句)
+	Args    []interface{} // 参数
+	TxState TxState       // 所在事务的状态
+	Err     error         // 返回给调用者的错误
+	matched bool          // 是否匹配到期望
+}
+
+// String 语句的字符串形式，用于错误信息
+func (s Statement) String() string {
+	if len(s.Args) == 0 {
+		return fmt.Sprintf("[%s] %s", s.Table, s.Query)
+	}
+	return fmt.Sprintf("[%s] %s %v", s.Table, s.Query, s.Args)
+}
+
+// Fake 单元测试使用的orm基础设施，所有方法都是并发安全的
+// 直接在Fake上调用Orm的方法时数据表名称为空，建议通过Conn获取数据表的连接后再操作
+type Fake struct {
+	*conn // 数据表名称为空的连接
+
+	mutex         sync.Mutex                     // 语句和期望的互斥锁
+	strict        bool                           // 是否为严格模式
+	expectations  []*Expectation                 // 期望列表
+	statements    []*Statement                   // 执行过的语句
+	shardingRules map[string]common.ShardingRule // 逻辑表名-分表规则哈希表
+	tables        map[string]struct{}            // 允许连接的数据表，为空时允许连接所有数据表
+}
+
+// New 创建单元测试使用的orm基础设施
+func New() *Fake {
+	fake := &Fake{
+		shardingRules: make(map[string]common.ShardingRule),
+		tables:        make(map[string]struct{}),
+	}
+	fake.conn = newConn(fake, "")
+	return fake
+}
+
+// Strict 设置严格模式，严格模式下没有匹配到期望的语句返回错误，否则查询返回空结果、写操作返回零值结果
+// 事务控制语句(BEGIN、COMMIT、ROLLBACK)不受严格模式限制，需要模拟事务失败时可以为其添加期望
+func (f *Fake) Strict(strict bool) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.strict = strict
+	return f
+}
+
+// Tables 限制可以连接的数据表，连接其余数据表时Conn返回错误，不调用时允许连接所有数据表
+func (f *Fake) Tables(tableNames ...string) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, tableName := range tableNames {
+		f.tables[tableName] = struct{}{}
+	}
+	return f
+}
+
+// Expect 添加期望，pattern为匹配sql语句的正则表达式，按添加顺序匹配第一个符合条件的期望
+func (f *Fake) Expect(pattern string) *Expectation {
+	expectation := &Expectation{
+		fake:    f,
+		pattern: regexp.MustCompile(pattern),
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.expectations = append(f.expectations, expectation)
+	return expectation
+}
+
+// Statements 获取执行过的所有语句
+func (f *Fake) Statements() []Statement {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	statements := make([]Statement, 0, len(f.statements))
+	for _, statement := range f.statements {
+		statements = append(statements, *statement)
+	}
+	return statements
+}
+
+// Reset 清空执行过的语句和所有期望
+func (f *Fake) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.expectations = nil
+	f.statements = nil
+}
+
+// ExpectationsWereMet 检查所有期望是否都已满足，严格模式下同时检查是否有未匹配到期望的语句
+func (f *Fake) ExpectationsWereMet() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	problems := make([]string, 0)
+	for _, expectation := range f.expectations {
+		if problem := expectation.unmet(); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if f.strict {
+		for _, statement := range f.statements {
+			if !statement.matched && !isTxControl(statement.Query) {
+				problems = append(problems, fmt.Sprintf("语句没有匹配的期望(%s)", statement))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("期望未满足(%s)", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// TestingT 测试对象接口，*testing.T和*testing.B都实现了该接口
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertExpectations 期望未满足时使测试失败
+func (f *Fake) AssertExpectations(t TestingT) {
+	t.Helper()
+	if err := f.ExpectationsWereMet(); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+}
+
+// reply 匹配到的期望需要返回的结果
+type reply struct {
+	matched bool          // 是否匹配到期望
+	rows    interface{}   // 查询返回的数据
+	result  common.Result // 写操作返回的执行结果
+}
+
+// handle 记录语句并查找匹配的期望，返回匹配的期望需要返回的结果和错误
+func (f *Fake) handle(c *conn, query string, args []interface{}) (reply, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	statement := &Statement{
+		Table: c.tableName,
+		Query: query,
+		Args:  args,
+	}
+	c.track(statement)
+	f.statements = append(f.statements, statement)
+
+	for _, expectation := range f.expectations {
+		if expectation.match(c.tableName, query, args) {
+			expectation.calls++
+			statement.matched = true
+			statement.Err = expectation.err
+			return reply{matched: true, rows: expectation.rows, result: expectation.result}, expectation.err
+		}
+	}
+
+	if f.strict && !isTxControl(query) {
+		statement.Err = fmt.Errorf("语句没有匹配的期望(%s)", statement)
+	}
+	return reply{}, statement.Err
+}
+
+// isTxControl 判断是否为事务控制语句
+func isTxControl(query string) bool {
+	return query == "BEGIN" || query == "COMMIT" || query == "ROLLBACK"
+}
+
+// Name 查询基础设施名称
+func (f *Fake) Name() string {
+	return FakeInfraName
+}
+
+// Start 启动基础设施，内存实现不需要启动
+func (f *Fake) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop 停止基础设施，内存实现不需要停止
+func (f *Fake) Stop() error {
+	return nil
+}
+
+// Restart 重启基础设施，内存实现不需要重启
+func (f *Fake) Restart(ctx context.Context) error {
+	return nil
+}
+
+// Stats 获取连接池统计信息，内存实现没有连接池，返回空的哈希表
+func (f *Fake) Stats() map[string]sql.DBStats {
+	return make(map[string]sql.DBStats)
+}
+
+// Conn 获取数据表的连接，每次调用返回新的连接(事务状态不共享)
+func (f *Fake) Conn(tableName string) (common.Orm, error) {
+	if err := f.checkTable(tableName); err != nil {
+		return nil, err
+	}
+	return newConn(f, tableName), nil
+}
+
+// ConnClickHouse 获取ClickHouse数据表的专用连接，与真实实现一致，事务以及行级更新删除操作返回错误
+//
+// ClickHouse专用操作按以下简化语句记录：
+//
+//	AsyncInsert          ASYNC INSERT INTO 表名                  参数：[结构体或结构体切片]
+//	ColumnarInsert       COLUMNAR INSERT INTO 表名 (列1, 列2)     参数：按列排列的值切片
+//	MutateUpdate         ALTER TABLE 表名 UPDATE 列1, 列2 WHERE 条件   参数：按列名排序的更新值和条件参数
+//	MutateDelete         ALTER TABLE 表名 DELETE WHERE 条件      参数：条件参数
+//	Mutations            SELECT MUTATIONS FROM 表名             使用WillReturnRows返回[]common.MutationStatus
+//	WaitMutations        WAIT MUTATIONS FROM 表名
+//	GetWithSettings      原样记录sql语句，设置作为最后一个参数
+//	SelectWithSettings   原样记录sql语句，设置作为最后一个参数
+func (f *Fake) ConnClickHouse(tableName string) (common.ClickHouseOrm, error) {
+	if err := f.checkTable(tableName); err != nil {
+		return nil, err
+	}
+	return &clickHouseConn{conn: newConn(f, tableName)}, nil
+}
+
+// checkTable 检查数据表是否允许连接
+func (f *Fake) checkTable(tableName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.tables[tableName]; len(f.tables) > 0 && !ok {
+		return fmt.Errorf("根据表名(%s)无法找到对应的orm实例", tableName)
+	}
+	return nil
+}
+
+// RegisterShardingRule 注册逻辑表的分表规则，重复注册时覆盖之前的规则
+func (f *Fake) RegisterShardingRule(logicalTable string, rule common.ShardingRule) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.shardingRules[logicalTable] = rule
+}
+
+// ConnShard 根据逻辑表的分表规则和分片键计算物理表名，并获取物理表的连接
+func (f *Fake) ConnShard(logicalTable string, shardKey interface{}) (common.Orm, error) {
+	f.mutex.Lock()
+	rule, ok := f.shardingRules[logicalTable]
+	f.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("逻辑表(%s)未注册分表规则", logicalTable)
+	}
+
+	tableName, err := rule.PhysicalTable(logicalTable, shardKey)
+	if err != nil {
+		return nil, fmt.Errorf("计算逻辑表(%s)的物理表名失败(%s)", logicalTable, err.Error())
+	}
+
+	return f.Conn(tableName)
+}
+
+// EnableCache 启用查询结果缓存，内存实现不缓存任何数据，所有查询都会匹配期望
+func (f *Fake) EnableCache(redis common.Redis, ttl time.Duration, tables ...string) {
+}
@@ -0,0 +1,252 @@
+package ormtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// user 测试数据模型
+type user struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// recorder 记录错误信息的测试对象
+type recorder struct {
+	errors []string
+}
+
+// Helper 标记辅助函数
+func (r *recorder) Helper() {}
+
+// Errorf 记录错误信息
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectationOrder(t *testing.T) {
+	fake := New()
+	fake.Expect(`^SELECT \* FROM user WHERE id = \?$`).WithArgs(int64(2)).WillReturnRows(user{ID: 2, Name: "second"})
+	fake.Expect(`^SELECT \* FROM user`).WillReturnRows([]user{{ID: 1, Name: "first"}})
+
+	conn, err := fake.Conn("user")
+	if err != nil {
+		t.Fatalf("获取连接失败(%s)", err)
+	}
+
+	// 按添加顺序匹配第一个符合条件的期望，参数不同时匹配后面的期望
+	var result user
+	if err := conn.Get(&result, "SELECT * FROM user WHERE id = ?", int64(2)); err != nil || result.Name != "second" {
+		t.Fatalf("应该匹配带参数的期望(%+v, %v)", result, err)
+	}
+	if err := conn.Get(&result, "SELECT * FROM user WHERE id = ?", int64(1)); err != nil || result.Name != "first" {
+		t.Fatalf("参数不同时应该匹配后面的期望(%+v, %v)", result, err)
+	}
+
+	fake.AssertExpectations(t)
+}
+
+func TestExpectationTimes(t *testing.T) {
+	fake := New()
+	limitErr := errors.New("限流")
+	fake.Expect(`^INSERT INTO user$`).Times(2).WillReturnResult(common.Result{LastInsertId: 1, RowsAffected: 1})
+	fake.Expect(`^INSERT INTO user$`).WillReturnError(limitErr)
+
+	conn, _ := fake.Conn("user")
+	for i := 0; i < 2; i++ {
+		result, err := conn.Insert(user{Name: "a"})
+		if err != nil || result.RowsAffected != 1 {
+			t.Fatalf("第%d次插入应该返回预设的执行结果(%+v, %v)", i+1, result, err)
+		}
+	}
+
+	// 匹配次数达到后不再匹配该期望
+	if _, err := conn.Insert(user{Name: "b"}); !errors.Is(err, limitErr) {
+		t.Fatalf("第3次插入应该匹配后面的期望(%v)", err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Fatalf("期望应该已经满足(%s)", err)
+	}
+}
+
+func TestExpectationOnTable(t *testing.T) {
+	fake := New()
+	fake.Expect(`^DELETE FROM`).OnTable("order").WillReturnResult(common.Result{RowsAffected: 3})
+
+	userConn, _ := fake.Conn("user")
+	orderConn, _ := fake.Conn("order")
+	if result, _ := userConn.Delete("id = ?", 1); result.RowsAffected != 0 {
+		t.Fatalf("其他数据表不应该匹配期望(%+v)", result)
+	}
+	if result, _ := orderConn.Delete("id = ?", 1); result.RowsAffected != 3 {
+		t.Fatalf("指定的数据表应该匹配期望(%+v)", result)
+	}
+
+	statements := fake.Statements()
+	if len(statements) != 2 || statements[0].Table != "user" || statements[1].Query != "DELETE FROM order WHERE id = ?" {
+		t.Fatalf("记录的语句错误(%v)", statements)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	fake := New()
+	conn, _ := fake.Conn("user")
+
+	// 非严格模式下未匹配的查询返回空结果
+	users := []user{{ID: 1}}
+	if err := conn.Select(&users, "SELECT * FROM user"); err != nil || len(users) != 0 {
+		t.Fatalf("非严格模式应该返回空结果(%v, %v)", users, err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Fatalf("非严格模式不检查未匹配的语句(%s)", err)
+	}
+
+	fake.Reset()
+	fake.Strict(true)
+	fake.Expect(`^UPDATE user SET name WHERE id = :id$`)
+
+	// 事务控制语句不受严格模式限制
+	err := conn.WithTx(context.Background(), func(tx common.Orm) error {
+		if _, err := tx.Update("id = :id", map[string]interface{}{"name": "a"}); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DROP TABLE user")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "语句没有匹配的期望([user] DROP TABLE user)") {
+		t.Fatalf("严格模式下未匹配的语句应该返回错误(%v)", err)
+	}
+
+	err = fake.ExpectationsWereMet()
+	if err == nil || !strings.Contains(err.Error(), "DROP TABLE user") || strings.Contains(err.Error(), "BEGIN") {
+		t.Fatalf("严格模式下应该只报告未匹配的非事务语句(%v)", err)
+	}
+}
+
+func TestTxState(t *testing.T) {
+	fake := New()
+	conn, _ := fake.Conn("user")
+	ctx := context.Background()
+	innerErr := errors.New("内层失败")
+
+	err := conn.WithTx(ctx, func(tx common.Orm) error {
+		tx.Exec("outer")
+
+		// 提交的保存点并入外层事务，随外层事务结束
+		tx.WithTx(ctx, func(tx common.Orm) error {
+			_, err := tx.Exec("committed savepoint")
+			return err
+		})
+
+		// 回滚的保存点立即标记为已回滚
+		tx.WithTx(ctx, func(tx common.Orm) error {
+			tx.Exec("rolled back savepoint")
+			return innerErr
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("外层事务失败(%s)", err)
+	}
+
+	conn.WithTx(ctx, func(tx common.Orm) error {
+		tx.Exec("rolled back outer")
+		return innerErr
+	})
+	conn.Exec("no tx")
+
+	expected := map[string]TxState{
+		"outer":                 TxCommitted,
+		"committed savepoint":   TxCommitted,
+		"rolled back savepoint": TxRolledBack,
+		"rolled back outer":     TxRolledBack,
+		"no tx":                 TxNone,
+	}
+	for _, statement := range fake.Statements() {
+		state, ok := expected[statement.Query]
+		if !ok {
+			continue
+		}
+		if statement.TxState != state {
+			t.Errorf("语句(%s)的事务状态为%s，期望为%s", statement.Query, statement.TxState, state)
+		}
+		delete(expected, statement.Query)
+	}
+	if len(expected) > 0 {
+		t.Fatalf("部分语句没有记录(%v)", expected)
+	}
+}
+
+func TestCommitFailure(t *testing.T) {
+	fake := New()
+	fake.Expect(`^COMMIT$`).WillReturnError(errors.New("提交失败"))
+	conn, _ := fake.Conn("user")
+
+	err := conn.WithTx(context.Background(), func(tx common.Orm) error {
+		_, err := tx.Exec("write")
+		return err
+	})
+	if err == nil {
+		t.Fatal("提交失败时应该返回错误")
+	}
+
+	// 最外层事务提交失败时，事务中的语句视为已回滚
+	for _, statement := range fake.Statements() {
+		if statement.Query == "write" && statement.TxState != TxRolledBack {
+			t.Fatalf("提交失败后语句的事务状态错误(%s)", statement.TxState)
+		}
+	}
+}
+
+func TestExpectationsWereMetMessages(t *testing.T) {
+	fake := New()
+	fake.Expect(`^SELECT 1$`)
+	fake.Expect(`^SELECT 2$`).Times(2)
+	fake.Expect(`^SELECT 3$`).Times(1)
+
+	conn, _ := fake.Conn("user")
+	conn.Exec("SELECT 2")
+	conn.Exec("SELECT 3")
+
+	err := fake.ExpectationsWereMet()
+	if err == nil {
+		t.Fatal("期望未满足时应该返回错误")
+	}
+	expected := "期望未满足(期望(^SELECT 1$)没有执行; 期望(^SELECT 2$)应执行2次，实际执行1次)"
+	if err.Error() != expected {
+		t.Fatalf("错误信息不符合预期\n实际: %s\n期望: %s", err, expected)
+	}
+
+	// AssertExpectations将同样的错误信息报告给测试对象
+	r := &recorder{}
+	fake.AssertExpectations(r)
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Fatalf("报告的错误信息不符合预期(%v)", r.errors)
+	}
+
+	conn.Exec("SELECT 1")
+	conn.Exec("SELECT 2")
+	r = &recorder{}
+	fake.AssertExpectations(r)
+	if len(r.errors) != 0 {
+		t.Fatalf("期望已满足时不应该报告错误(%v)", r.errors)
+	}
+}
+
+func TestTables(t *testing.T) {
+	fake := New().Tables("user")
+	if _, err := fake.Conn("user"); err != nil {
+		t.Fatalf("允许的数据表应该可以连接(%s)", err)
+	}
+	if _, err := fake.Conn("order"); err == nil {
+		t.Fatal("未允许的数据表应该返回错误")
+	}
+	if _, err := fake.ConnClickHouse("order"); err == nil {
+		t.Fatal("未允许的ClickHouse数据表应该返回错误")
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 11:20:37
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 11:20:37
+ * @FilePath: \common\infra\orm\ormtest\rows.go
+ * @Description: 预设查询结果的赋值与逐行读取
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package ormtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.Rows = (*rows)(nil)
+
+// assignOne 将预设数据的第一行赋值给查询结果，预设数据为空切片时返回sql.ErrNoRows
+func assignOne(dest interface{}, data interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return errors.New("传入的参数必须是非空指针")
+	}
+
+	source := reflect.ValueOf(data)
+	if source.Kind() == reflect.Slice && !source.Type().AssignableTo(destValue.Elem().Type()) {
+		if source.Len() == 0 {
+			return sql.ErrNoRows
+		}
+		source = source.Index(0)
+	}
+
+	return assignValue(destValue.Elem(), source)
+}
+
+// assignAll 将预设数据的所有行赋值给切片指针，预设数据为空时赋值为空切片，预设数据不是切片时视为只有一行
+func assignAll(dest interface{}, data interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() || destValue.Elem().Kind() != reflect.Slice {
+		return errors.New("传入的参数必须是切片指针")
+	}
+
+	sliceType := destValue.Elem().Type()
+	result := reflect.MakeSlice(sliceType, 0, 0)
+	for _, source := range sourceRows(data) {
+		item := reflect.New(sliceType.Elem()).Elem()
+		if err := assignValue(item, source); err != nil {
+			return err
+		}
+		result = reflect.Append(result, item)
+	}
+
+	destValue.Elem().Set(result)
+	return nil
+}
+
+// sourceRows 将预设数据转换为行列表
+func sourceRows(data interface{}) []reflect.Value {
+	if data == nil {
+		return nil
+	}
+
+	source := reflect.ValueOf(data)
+	if source.Kind() != reflect.Slice || source.Type().Elem().Kind() == reflect.Uint8 {
+		return []reflect.Value{source}
+	}
+
+	values := make([]reflect.Value, 0, source.Len())
+	for i := 0; i < source.Len(); i++ {
+		values = append(values, source.Index(i))
+	}
+	return values
+}
+
+// assignValue 将预设数据赋值给目标值，支持值和指针之间的转换以及数值类型之间的转换
+func assignValue(dest reflect.Value, source reflect.Value) error {
+	for source.Kind() == reflect.Interface && !source.IsNil() {
+		source = source.Elem()
+	}
+	if !source.IsValid() || (source.Kind() == reflect.Interface && source.IsNil()) {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+
+	switch {
+	case source.Type().AssignableTo(dest.Type()):
+		dest.Set(source)
+		return nil
+	case source.Kind() == reflect.Ptr && source.Type().Elem().AssignableTo(dest.Type()):
+		if source.IsNil() {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		dest.Set(source.Elem())
+		return nil
+	case dest.Kind() == reflect.Ptr && source.Type().AssignableTo(dest.Type().Elem()):
+		value := reflect.New(dest.Type().Elem())
+		value.Elem().Set(source)
+		dest.Set(value)
+		return nil
+	case isNumber(source.Kind()) && isNumber(dest.Kind()):
+		dest.Set(source.Convert(dest.Type()))
+		return nil
+	default:
+		return fmt.Errorf("预设数据类型(%s)无法赋值给(%s)", source.Type(), dest.Type())
+	}
+}
+
+// isNumber 判断是否为数值类型
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// rows 逐行读取预设数据的结果集
+type rows struct {
+	values []reflect.Value // 所有行
+	index  int             // 当前行序号
+	closed bool            // 是否已经关闭
+}
+
+// newRows 创建预设数据的结果集
+func newRows(data interface{}) *rows {
+	return &rows{values: sourceRows(data), index: -1}
+}
+
+// Next 移动到下一行
+func (r *rows) Next() bool {
+	if r.closed || r.index+1 >= len(r.values) {
+		return false
+	}
+
+	r.index++
+	return true
+}
+
+// Scan 按列顺序读取当前行，预设数据的行为[]interface{}时按元素赋值，否则当前行视为只有一列
+func (r *rows) Scan(dest ...interface{}) error {
+	value, err := r.current()
+	if err != nil {
+		return err
+	}
+
+	columns := []reflect.Value{value}
+	if items, ok := value.Interface().([]interface{}); ok {
+		columns = make([]reflect.Value, 0, len(items))
+		for _, item := range items {
+			columns = append(columns, reflect.ValueOf(item))
+		}
+	}
+	if len(columns) != len(dest) {
+		return fmt.Errorf("预设数据的列数(%d)与读取的列数(%d)不一致", len(columns), len(dest))
+	}
+
+	for i := range dest {
+		target := reflect.ValueOf(dest[i])
+		if target.Kind() != reflect.Ptr || target.IsNil() {
+			return errors.New("传入的参数必须是非空指针")
+		}
+		if err := assignValue(target.Elem(), columns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StructScan 将当前行读取到结构体
+func (r *rows) StructScan(dest interface{}) error {
+	value, err := r.current()
+	if err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("传入的参数必须是非空指针")
+	}
+	return assignValue(target.Elem(), value)
+}
+
+// current 获取当前行
+func (r *rows) current() (reflect.Value, error) {
+	if r.closed {
+		return reflect.Value{}, errors.New("结果集已经关闭")
+	}
+	if r.index < 0 || r.index >= len(r.values) {
+		return reflect.Value{}, errors.New("没有可以读取的数据行，请先调用Next")
+	}
+	return r.values[r.index], nil
+}
+
+// Err 遍历过程中出现的错误，预设数据不会出错
+func (r *rows) Err() error {
+	return nil
+}
+
+// Close 关闭结果集
+func (r *rows) Close() error {
+	r.closed = true
+	return nil
+}
@@ -0,0 +1,129 @@
+package ormtest
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAssignOne(t *testing.T) {
+	name := "alice"
+	tests := []struct {
+		name   string
+		dest   interface{}
+		data   interface{}
+		expect interface{}
+		err    error
+	}{
+		{"结构体", &user{}, user{ID: 1}, user{ID: 1}, nil},
+		{"结构体指针", &user{}, &user{ID: 2}, user{ID: 2}, nil},
+		{"切片取第一行", &user{}, []user{{ID: 3}, {ID: 4}}, user{ID: 3}, nil},
+		{"空切片", &user{}, []user{}, user{}, sql.ErrNoRows},
+		{"数值转换", new(int64), 5, int64(5), nil},
+		{"浮点数转换", new(float64), int32(7), float64(7), nil},
+		{"值转换为指针", new(*string), name, &name, nil},
+		{"字节切片作为单个值", new([]byte), []byte("raw"), []byte("raw"), nil},
+		{"切片整体赋值", new([]int), []int{1, 2}, []int{1, 2}, nil},
+		{"空值", new(*string), (*string)(nil), (*string)(nil), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := assignOne(test.dest, test.data)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("返回的错误不符合预期(%v)，期望(%v)", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+
+			actual := reflect.ValueOf(test.dest).Elem().Interface()
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("赋值结果错误(%#v)，期望(%#v)", actual, test.expect)
+			}
+		})
+	}
+
+	if err := assignOne(user{}, user{}); err == nil {
+		t.Fatal("非指针参数应该返回错误")
+	}
+	if err := assignOne(new(int), "text"); err == nil {
+		t.Fatal("类型不兼容时应该返回错误")
+	}
+}
+
+func TestAssignAll(t *testing.T) {
+	tests := []struct {
+		name   string
+		dest   interface{}
+		data   interface{}
+		expect interface{}
+	}{
+		{"结构体切片", &[]user{}, []user{{ID: 1}, {ID: 2}}, []user{{ID: 1}, {ID: 2}}},
+		{"结构体指针切片", &[]*user{}, []user{{ID: 1}}, []*user{{ID: 1}}},
+		{"单个结构体视为一行", &[]user{}, user{ID: 3}, []user{{ID: 3}}},
+		{"数值转换", &[]int64{}, []int{1, 2}, []int64{1, 2}},
+		{"接口切片", &[]string{}, []interface{}{"a", "b"}, []string{"a", "b"}},
+		{"空数据", &[]user{{ID: 9}}, nil, []user{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := assignAll(test.dest, test.data); err != nil {
+				t.Fatalf("赋值失败(%s)", err)
+			}
+
+			actual := reflect.ValueOf(test.dest).Elem().Interface()
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("赋值结果错误(%#v)，期望(%#v)", actual, test.expect)
+			}
+		})
+	}
+
+	if err := assignAll(&user{}, []user{}); err == nil {
+		t.Fatal("非切片指针参数应该返回错误")
+	}
+	if err := assignAll(&[]int{}, []string{"a"}); err == nil {
+		t.Fatal("元素类型不兼容时应该返回错误")
+	}
+}
+
+func TestRows(t *testing.T) {
+	rows := newRows([]interface{}{
+		[]interface{}{int64(1), "alice"},
+		[]interface{}{int64(2), "bob"},
+	})
+
+	if err := rows.Scan(new(int64), new(string)); err == nil {
+		t.Fatal("调用Next之前读取应该返回错误")
+	}
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("读取数据行失败(%s)", err)
+		}
+		ids = append(ids, id)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Fatalf("读取结果错误(%v)", ids)
+	}
+
+	rows = newRows([]user{{ID: 3}})
+	rows.Next()
+	if err := rows.Scan(new(int64), new(string)); err == nil {
+		t.Fatal("列数不一致时应该返回错误")
+	}
+	var item user
+	if err := rows.StructScan(&item); err != nil || item.ID != 3 {
+		t.Fatalf("读取结构体失败(%+v, %v)", item, err)
+	}
+
+	rows.Close()
+	if rows.Next() {
+		t.Fatal("关闭后不应该有数据行")
+	}
+}
@@ -0,0 +1,340 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 17:05:33
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 17:05:33
+ * @FilePath: \common\infra\orm\query_builder.go
+ * @Description: 参数化查询条件构建器
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/utils"
+
+	"github.com/pkg/errors"
+)
+
+// 筛选条件操作相关定义
+const (
+	OperatorEqual        = "="           // 等于
+	OperatorNotEqual     = "<>"          // 不等于
+	OperatorGreater      = ">"           // 大于
+	OperatorLess         = "<"           // 小于
+	OperatorGreaterEqual = ">="          // 大于等于
+	OperatorLessEqual    = "<="          // 小于等于
+	OperatorIn           = "in"          // 在列表中
+	OperatorNotIn        = "not in"      // 不在列表中
+	OperatorLike         = "like"        // 包含
+	OperatorPrefix       = "prefix"      // 前缀匹配
+	OperatorBetween      = "between"     // 在区间内(闭区间)
+	OperatorIsNull       = "is null"     // 为空
+	OperatorIsNotNull    = "is not null" // 不为空
+)
+
+// 排序方向相关定义
+const (
+	DirectionAsc  = "ASC"  // 升序
+	DirectionDesc = "DESC" // 降序
+)
+
+// likeEscaper like条件中通配符的转义
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeEscapeMarker like条件中转义子句的占位符，构建语句时根据数据库类型替换
+// MySQL/TiDB/ClickHouse默认使用反斜杠作为转义字符，SQLite没有默认转义字符，PostgreSQL需要显式指定才能保证与standard_conforming_strings无关
+const likeEscapeMarker = "{like_escape}"
+
+// keysetCursor 游标分页条件
+type keysetCursor struct {
+	column    string      // 游标列名
+	value     interface{} // 上一页最后一条数据的游标值
+	direction string      // 排序方向
+}
+
+// QueryBuilder 参数化查询条件构建器
+// 将筛选参数和排序参数转换为WHERE/ORDER BY/LIMIT语句片段和绑定参数，列名必须在结构体db标签的白名单中
+type QueryBuilder struct {
+	columns     map[string]struct{} // 列名白名单
+	tablePrefix string              // 列名前缀(表名或者表别名)
+	dbType      string              // 数据库类型，用于生成不同数据库的语法差异部分
+	conditions  []string            // 查询条件列表
+	args        []interface{}       // 查询条件绑定参数列表
+	sorts       []string            // 排序条件列表
+	keyset      *keysetCursor       // 游标分页条件
+	limit       int                 // 每页数据条数(0表示不限制)
+	offset      int                 // 偏移数据条数
+	errs        []error             // 构建过程中出现的错误列表
+}
+
+// NewQueryBuilder 根据带db标签的结构体(或结构体指针)创建查询条件构建器，tableName用于给列名添加表名前缀
+func NewQueryBuilder(model interface{}, tableName ...string) (*QueryBuilder, error) {
+	columns, err := columnNames(model)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := &QueryBuilder{
+		columns: make(map[string]struct{}, len(columns)),
+	}
+	for _, column := range columns {
+		builder.columns[column] = struct{}{}
+	}
+	if len(tableName) > 0 && tableName[0] != "" {
+		builder.tablePrefix = tableName[0] + "."
+	}
+
+	return builder, nil
+}
+
+// column 校验列名是否在白名单中，并返回带前缀的列名
+func (b *QueryBuilder) column(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if _, ok := b.columns[name]; !ok {
+		b.errs = append(b.errs, fmt.Errorf("列名(%s)不存在或者不允许作为查询条件", name))
+		return "", false
+	}
+	return b.tablePrefix + name, true
+}
+
+// Filter 添加筛选条件，多个筛选条件之间使用AND连接，值列表为空的条件会被忽略(与FilterParam.ToSql保持一致)
+func (b *QueryBuilder) Filter(params ...common.FilterParam) *QueryBuilder {
+	for _, param := range params {
+		operator := strings.ToLower(strings.Join(strings.Fields(param.Operator), " "))
+		if operator == "!=" {
+			operator = OperatorNotEqual
+		}
+
+		// 除空值判断外，其余操作都需要值列表
+		if len(param.Values) == 0 && operator != OperatorIsNull && operator != OperatorIsNotNull {
+			continue
+		}
+
+		column, ok := b.column(param.Name)
+		if !ok {
+			continue
+		}
+
+		switch operator {
+		case OperatorEqual, OperatorNotEqual, OperatorGreater, OperatorLess, OperatorGreaterEqual, OperatorLessEqual:
+			b.conditions = append(b.conditions, fmt.Sprintf("%s %s ?", column, operator))
+			b.args = append(b.args, param.Values[0])
+		case OperatorIn, OperatorNotIn:
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(param.Values)), ",")
+			b.conditions = append(b.conditions, fmt.Sprintf("%s %s (%s)", column, strings.ToUpper(operator), placeholders))
+			for _, value := range param.Values {
+				b.args = append(b.args, value)
+			}
+		case OperatorLike:
+			b.conditions = append(b.conditions, fmt.Sprintf("%s LIKE ?%s", column, likeEscapeMarker))
+			b.args = append(b.args, "%"+likeEscaper.Replace(param.Values[0])+"%")
+		case OperatorPrefix:
+			b.conditions = append(b.conditions, fmt.Sprintf("%s LIKE ?%s", column, likeEscapeMarker))
+			b.args = append(b.args, likeEscaper.Replace(param.Values[0])+"%")
+		case OperatorBetween:
+			if len(param.Values) != 2 {
+				b.errs = append(b.errs, fmt.Errorf("列(%s)的between条件需要2个值，实际为%d个", param.Name, len(param.Values)))
+				continue
+			}
+			b.conditions = append(b.conditions, fmt.Sprintf("%s BETWEEN ? AND ?", column))
+			b.args = append(b.args, param.Values[0], param.Values[1])
+		case OperatorIsNull, OperatorIsNotNull:
+			b.conditions = append(b.conditions, fmt.Sprintf("%s %s", column, strings.ToUpper(operator)))
+		default:
+			b.errs = append(b.errs, fmt.Errorf("列(%s)的筛选条件操作(%s)不支持", param.Name, param.Operator))
+		}
+	}
+
+	return b
+}
+
+// ExcludeDeleted 添加排除软删除数据的条件(删除时间列为NULL)，column为空时使用默认的delete_time列
+func (b *QueryBuilder) ExcludeDeleted(column string) *QueryBuilder {
+	if column == "" {
+		column = defaultDeleteTimeColumn
+	}
+
+	prefixedColumn, ok := b.column(column)
+	if !ok {
+		return b
+	}
+
+	b.conditions = append(b.conditions, prefixedColumn+" IS NULL")
+	return b
+}
+
+// Sort 添加排序条件，排序方向只允许asc和desc(为空时默认为升序)
+func (b *QueryBuilder) Sort(params ...common.SortParm) *QueryBuilder {
+	for _, param := range params {
+		column, ok := b.column(param.Name)
+		if !ok {
+			continue
+		}
+
+		direction, err := normalizeDirection(param.Direction)
+		if err != nil {
+			b.errs = append(b.errs, errors.WithMessagef(err, "列(%s)的排序条件错误", param.Name))
+			continue
+		}
+
+		b.sorts = append(b.sorts, column+" "+direction)
+	}
+
+	return b
+}
+
+// normalizeDirection 校验并统一排序方向
+func normalizeDirection(direction string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(direction)) {
+	case "", DirectionAsc:
+		return DirectionAsc, nil
+	case DirectionDesc:
+		return DirectionDesc, nil
+	default:
+		return "", fmt.Errorf("排序方向(%s)不支持", direction)
+	}
+}
+
+// Limit 设置每页数据条数和偏移量(LIMIT/OFFSET分页)
+func (b *QueryBuilder) Limit(limit int, offset int) *QueryBuilder {
+	if limit < 0 || offset < 0 {
+		b.errs = append(b.errs, fmt.Errorf("分页参数不能为负数(limit=%d, offset=%d)", limit, offset))
+		return b
+	}
+
+	b.limit = limit
+	b.offset = offset
+	return b
+}
+
+// Page 按页码设置分页，页码从1开始
+func (b *QueryBuilder) Page(page int, pageSize int) *QueryBuilder {
+	if page < 1 || pageSize < 1 {
+		b.errs = append(b.errs, fmt.Errorf("页码和每页数据条数必须大于0(page=%d, pageSize=%d)", page, pageSize))
+		return b
+	}
+
+	return b.Limit(pageSize, (page-1)*pageSize)
+}
+
+// Dialect 设置数据库类型(mysql、tidb、clickhouse、postgres、sqlite)，SQLite和PostgreSQL的like条件需要显式指定转义字符
+// 未设置时按MySQL语法构建，Page会根据连接的数据库类型自动设置
+func (b *QueryBuilder) Dialect(dbType string) *QueryBuilder {
+	b.dbType = dbType
+	return b
+}
+
+// After 设置游标分页(keyset)条件，查询排在游标值之后的pageSize条数据
+// 游标列需要具有唯一性(例如自增主键)，游标列的排序条件会作为第一个排序条件
+func (b *QueryBuilder) After(column string, value interface{}, direction string, pageSize int) *QueryBuilder {
+	prefixedColumn, ok := b.column(column)
+	if !ok {
+		return b
+	}
+
+	normalized, err := normalizeDirection(direction)
+	if err != nil {
+		b.errs = append(b.errs, errors.WithMessagef(err, "列(%s)的游标分页条件错误", column))
+		return b
+	}
+
+	b.keyset = &keysetCursor{
+		column:    prefixedColumn,
+		value:     value,
+		direction: normalized,
+	}
+	return b.Limit(pageSize, 0)
+}
+
+// BuildWhere 构建WHERE语句片段(包含游标分页条件)，没有筛选条件时返回空字符串
+func (b *QueryBuilder) BuildWhere() (string, []interface{}, error) {
+	return b.buildWhere(true)
+}
+
+// buildWhere 构建WHERE语句片段和绑定参数，withKeyset为false时不包含游标分页条件(用于统计符合筛选条件的数据总数)
+func (b *QueryBuilder) buildWhere(withKeyset bool) (string, []interface{}, error) {
+	if len(b.errs) > 0 {
+		return "", nil, errors.WithMessage(utils.MergeErrors(b.errs), "构建查询条件失败")
+	}
+
+	conditions := b.conditions
+	args := append([]interface{}{}, b.args...)
+	if withKeyset && b.keyset != nil && b.keyset.value != nil {
+		operator := ">"
+		if b.keyset.direction == DirectionDesc {
+			operator = "<"
+		}
+		conditions = append(append([]string{}, conditions...), fmt.Sprintf("%s %s ?", b.keyset.column, operator))
+		args = append(args, b.keyset.value)
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+
+	escape := ""
+	if b.dbType == "sqlite" || b.dbType == "postgres" {
+		escape = ` ESCAPE '\'`
+	}
+	where := strings.ReplaceAll(strings.Join(conditions, " AND "), likeEscapeMarker, escape)
+	return " WHERE " + where, args, nil
+}
+
+// Build 构建WHERE/ORDER BY/LIMIT语句片段和绑定参数，可直接拼接在"SELECT ... FROM table"之后
+func (b *QueryBuilder) Build() (string, []interface{}, error) {
+	where, args, err := b.BuildWhere()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var builder strings.Builder
+	builder.WriteString(where)
+
+	sorts := b.sorts
+	if b.keyset != nil {
+		sorts = append([]string{b.keyset.column + " " + b.keyset.direction}, sorts...)
+	}
+	if len(sorts) > 0 {
+		builder.WriteString(" ORDER BY ")
+		builder.WriteString(strings.Join(sorts, ", "))
+	}
+
+	if b.limit > 0 {
+		builder.WriteString(" LIMIT ?")
+		args = append(args, b.limit)
+		if b.offset > 0 {
+			builder.WriteString(" OFFSET ?")
+			args = append(args, b.offset)
+		}
+	}
+
+	return builder.String(), args, nil
+}
+
+// columnNames 获取结构体(或结构体切片)中所有db标签对应的列名，支持匿名嵌套结构体
+func columnNames(model interface{}) ([]string, error) {
+	if model == nil {
+		return nil, errors.New("参数必须是结构体或者结构体指针")
+	}
+
+	reflectType := reflect.TypeOf(model)
+	for reflectType.Kind() == reflect.Ptr || reflectType.Kind() == reflect.Slice {
+		reflectType = reflectType.Elem()
+	}
+	if reflectType.Kind() != reflect.Struct {
+		return nil, errors.New("参数必须是结构体或者结构体指针")
+	}
+
+	info, err := modelOf(reflectType)
+	if err != nil {
+		return nil, err
+	}
+	return info.columnNames(), nil
+}
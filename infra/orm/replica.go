@@ -0,0 +1,309 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-20 14:21:52
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-20 14:21:52
+ * @FilePath: \common\infra\orm\replica.go
+ * @Description: 只读副本路由与健康检查
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hongliu9527/common/infra/metrics"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+	"github.com/hongliu9527/common/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/hongliu9527/go-tools/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// 只读副本选择策略相关定义
+const (
+	replicaPolicyRoundRobin   = "roundRobin"   // 轮询
+	replicaPolicyLeastLatency = "leastLatency" // 最低延迟
+)
+
+// 只读副本健康检查相关定义
+const (
+	replicaCheckTimeout         = 3 * time.Second // 单个副本单次健康检查的超时时间
+	defaultReplicaCheckInterval = 5 * time.Second // 未配置检查间隔时的默认值
+)
+
+// mysqlAccessDenied MySQL没有执行该操作的权限(查询复制状态需要REPLICATION CLIENT权限)
+const mysqlAccessDenied = 1227
+
+// errLagUnknown 无法查询复制延迟(例如没有查询复制状态的权限)，此时不根据复制延迟判断副本是否健康
+var errLagUnknown = errors.New("无法查询复制延迟")
+
+// replica 只读副本
+type replica struct {
+	name      string       // 副本名称，用于日志和监控指标
+	db        *sqlx.DB     // 副本实例
+	healthy   atomic.Bool  // 是否健康(可连接且复制延迟未超过阈值)
+	latency   atomic.Int64 // 最近一次健康检查的耗时，单位(纳秒)
+	lagWarned atomic.Bool  // 是否已经打印过无法查询复制延迟的警告
+}
+
+// replicaSet 数据库实例的只读副本集合
+type replicaSet struct {
+	dbType   string                                                                       // 数据库类型
+	policy   string                                                                       // 副本选择策略
+	maxLag   time.Duration                                                                // 允许的最大复制延迟，0表示不检查
+	replicas []*replica                                                                   // 副本列表
+	counter  atomic.Uint64                                                                // 轮询计数
+	lag      func(ctx context.Context, db *sqlx.DB, dbType string) (time.Duration, error) // 查询复制延迟
+}
+
+// newReplicaSet 根据数据库配置创建只读副本集合，并同步执行一次健康检查
+// 副本在启动时不可用不会导致创建失败，只是暂时不会被路由到
+func newReplicaSet(ctx context.Context, useExternalHost bool, config ormConfig.DataBaseConfig) (*replicaSet, error) {
+	switch config.ReplicaPolicy {
+	case replicaPolicyRoundRobin, replicaPolicyLeastLatency:
+	default:
+		return nil, fmt.Errorf("数据库(%s)的只读副本选择策略(%s)不支持", config.Name, config.ReplicaPolicy)
+	}
+
+	set := &replicaSet{
+		dbType:   config.Type,
+		policy:   config.ReplicaPolicy,
+		maxLag:   time.Duration(config.ReplicaMaxLag) * time.Second,
+		replicas: make([]*replica, 0, len(config.Replicas)),
+		lag:      replicationLag,
+	}
+
+	for index, replicaConfig := range config.Replicas {
+		// 判断是否使用外网地址
+		hostPort := replicaConfig.InternalHostPort
+		if useExternalHost {
+			hostPort = replicaConfig.HostPort
+		}
+		if hostPort == "" {
+			set.close()
+			return nil, fmt.Errorf("数据库(%s)的第%d个只读副本缺少访问地址", config.Name, index+1)
+		}
+
+		// 副本的用户名和密码未配置时与主库一致
+		dbConfig := config
+		if replicaConfig.Username != "" {
+			dbConfig.Username = replicaConfig.Username
+		}
+		if replicaConfig.Password != "" {
+			dbConfig.Password = replicaConfig.Password
+		}
+
+		db, err := openSqlx(dbConfig, hostPort)
+		if err != nil {
+			set.close()
+			return nil, errors.WithMessagef(err, "初始化数据库(%s)的只读副本(%s)失败", config.Name, hostPort)
+		}
+
+		r := &replica{
+			name: fmt.Sprintf("%s/replica-%d", config.Name, index+1),
+			db:   db,
+		}
+		set.replicas = append(set.replicas, r)
+		metrics.AddDBStats(r.name, db.DB)
+	}
+
+	set.check(ctx)
+	return set, nil
+}
+
+// pick 按照选择策略选择一个健康的副本，没有健康副本时返回空(由调用方使用主库)
+func (s *replicaSet) pick() *sqlx.DB {
+	if s == nil || len(s.replicas) == 0 {
+		return nil
+	}
+
+	if s.policy == replicaPolicyLeastLatency {
+		var best *replica
+		for _, r := range s.replicas {
+			if r.healthy.Load() && (best == nil || r.latency.Load() < best.latency.Load()) {
+				best = r
+			}
+		}
+		if best == nil {
+			return nil
+		}
+		return best.db
+	}
+
+	count := uint64(len(s.replicas))
+	start := s.counter.Add(1)
+	for i := uint64(0); i < count; i++ {
+		if r := s.replicas[(start+i)%count]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// run 定期执行健康检查，直到上下文对象取消
+func (s *replicaSet) run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+// check 并发检查所有副本
+func (s *replicaSet) check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			s.checkOne(ctx, r)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// checkOne 检查单个副本的连通性和复制延迟，并在健康状态变化时打印日志
+func (s *replicaSet) checkOne(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	err := func() error {
+		begin := time.Now()
+		if err := r.db.PingContext(ctx); err != nil {
+			return err
+		}
+		r.latency.Store(int64(time.Since(begin)))
+
+		if s.maxLag <= 0 {
+			return nil
+		}
+		lag, err := s.lag(ctx, r.db, s.dbType)
+		if errors.Is(err, errLagUnknown) {
+			// 无法查询复制延迟时只检查连通性，避免所有副本都被判定为不可用而使读请求全部回到主库
+			if !r.lagWarned.Swap(true) {
+				logger.Warning("只读副本(%s)无法查询复制延迟，不再根据复制延迟判断副本是否可用(%s)", r.name, err.Error())
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if lag > s.maxLag {
+			return fmt.Errorf("复制延迟(%s)超过阈值(%s)", lag, s.maxLag)
+		}
+		return nil
+	}()
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) != healthy {
+		if healthy {
+			logger.Info("只读副本(%s)可用，开始路由到该副本", r.name)
+		} else {
+			logger.Warning("只读副本(%s)不可用，暂停路由到该副本(%s)", r.name, err.Error())
+		}
+	}
+}
+
+// replicationLag 查询副本的复制延迟，不支持查询的数据库类型返回0
+func replicationLag(ctx context.Context, db *sqlx.DB, dbType string) (time.Duration, error) {
+	switch dbType {
+	case "mysql":
+		// MySQL 8.0.22开始使用SHOW REPLICA STATUS(8.4移除了SHOW SLAVE STATUS)，旧版本不支持新语句时使用旧语句
+		lag, err := mysqlReplicationLag(ctx, db, "SHOW REPLICA STATUS", "Seconds_Behind_Source")
+		if err != nil && !errors.Is(err, errLagUnknown) {
+			lag, err = mysqlReplicationLag(ctx, db, "SHOW SLAVE STATUS", "Seconds_Behind_Master")
+		}
+		return lag, err
+	case "clickhouse":
+		var seconds int64
+		err := db.GetContext(ctx, &seconds, "SELECT toInt64(max(absolute_delay)) FROM system.replicas")
+		return time.Duration(seconds) * time.Second, err
+	case "postgres":
+		// 主库上最后回放时间为NULL，此时延迟为0
+		var seconds int64
+		err := db.GetContext(ctx, &seconds, "SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)::bigint")
+		return time.Duration(seconds) * time.Second, err
+	default:
+		return 0, nil
+	}
+}
+
+// mysqlReplicationLag 执行复制状态查询语句，读取复制延迟列，没有权限时返回errLagUnknown
+func mysqlReplicationLag(ctx context.Context, db *sqlx.DB, query string, column string) (time.Duration, error) {
+	rows, err := db.QueryxContext(ctx, query)
+	if err != nil {
+		var mysqlError *mysql.MySQLError
+		if errors.As(err, &mysqlError) && mysqlError.Number == mysqlAccessDenied {
+			return 0, fmt.Errorf("%w(%s)", errLagUnknown, err.Error())
+		}
+		return 0, err
+	}
+	defer rows.Close()
+
+	// 没有复制状态时(例如通过代理访问)不检查延迟
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	status := make(map[string]interface{})
+	if err := rows.MapScan(status); err != nil {
+		return 0, err
+	}
+	return parseReplicationLag(status, column)
+}
+
+// parseReplicationLag 从复制状态中解析复制延迟列，值为NULL表示复制已中断
+func parseReplicationLag(status map[string]interface{}, column string) (time.Duration, error) {
+	var seconds int64
+	var err error
+	switch value := status[column].(type) {
+	case nil:
+		return 0, fmt.Errorf("复制已中断(%s为NULL)", column)
+	case int64:
+		seconds = value
+	case []byte:
+		seconds, err = strconv.ParseInt(string(value), 10, 64)
+	default:
+		seconds, err = strconv.ParseInt(fmt.Sprint(value), 10, 64)
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "解析复制延迟失败")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// close 关闭所有副本
+func (s *replicaSet) close() error {
+	errs := make([]error, 0)
+	for _, r := range s.replicas {
+		metrics.RemoveDBStats(r.name)
+		if err := r.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("关闭只读副本(%s)失败(%s)", r.name, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return utils.MergeErrors(errs)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build sqlite && cgo
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestReplicaCheck(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("连接SQLite失败(%s)", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		maxLag  time.Duration
+		lag     time.Duration
+		err     error
+		healthy bool
+	}{
+		{"不检查复制延迟", 0, time.Hour, nil, true},
+		{"复制延迟未超过阈值", 10 * time.Second, 3 * time.Second, nil, true},
+		{"复制延迟超过阈值", 10 * time.Second, 11 * time.Second, nil, false},
+		{"查询复制延迟失败", 10 * time.Second, 0, errors.New("复制已中断"), false},
+		{"没有权限查询复制延迟", 10 * time.Second, 0, fmt.Errorf("%w(Error 1227)", errLagUnknown), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			set := &replicaSet{
+				maxLag: test.maxLag,
+				lag: func(ctx context.Context, db *sqlx.DB, dbType string) (time.Duration, error) {
+					calls++
+					return test.lag, test.err
+				},
+			}
+			r := &replica{name: "test/replica-1", db: db}
+			r.healthy.Store(!test.healthy)
+
+			set.checkOne(context.Background(), r)
+			if r.healthy.Load() != test.healthy {
+				t.Fatalf("副本健康状态错误(%v)，期望(%v)", r.healthy.Load(), test.healthy)
+			}
+			if test.maxLag == 0 && calls != 0 {
+				t.Fatal("未配置最大复制延迟时不应该查询复制延迟")
+			}
+
+			// 无法查询复制延迟的警告只打印一次
+			if errors.Is(test.err, errLagUnknown) {
+				set.checkOne(context.Background(), r)
+				if !r.lagWarned.Load() || !r.healthy.Load() {
+					t.Fatal("无法查询复制延迟时副本应该保持可用")
+				}
+			}
+		})
+	}
+
+	// 无法连接的副本不可用
+	closed, _ := sqlx.Open("sqlite3", ":memory:")
+	closed.Close()
+	set := &replicaSet{lag: replicationLag}
+	r := &replica{name: "test/replica-2", db: closed}
+	r.healthy.Store(true)
+	set.checkOne(context.Background(), r)
+	if r.healthy.Load() {
+		t.Fatal("无法连接的副本应该不可用")
+	}
+}
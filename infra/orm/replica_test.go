@@ -0,0 +1,75 @@
+package orm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestReplicaSet 创建测试用只读副本集合，latencies为各副本的延迟，负数表示副本不健康
+func newTestReplicaSet(policy string, latencies ...time.Duration) *replicaSet {
+	set := &replicaSet{policy: policy}
+	for _, latency := range latencies {
+		r := &replica{db: &sqlx.DB{}}
+		r.healthy.Store(latency >= 0)
+		r.latency.Store(int64(latency))
+		set.replicas = append(set.replicas, r)
+	}
+	return set
+}
+
+func TestReplicaPick(t *testing.T) {
+	var empty *replicaSet
+	if empty.pick() != nil {
+		t.Fatal("没有副本时应该使用主库")
+	}
+
+	// 轮询时跳过不健康的副本
+	set := newTestReplicaSet(replicaPolicyRoundRobin, time.Millisecond, -1, 3*time.Millisecond)
+	counts := make(map[*sqlx.DB]int)
+	for i := 0; i < 10; i++ {
+		counts[set.pick()]++
+	}
+	if counts[set.replicas[0].db] == 0 || counts[set.replicas[2].db] == 0 || counts[set.replicas[1].db] != 0 {
+		t.Fatalf("轮询结果错误(%v)", counts)
+	}
+
+	// 最低延迟时选择延迟最低的健康副本
+	set = newTestReplicaSet(replicaPolicyLeastLatency, 5*time.Millisecond, -1, 2*time.Millisecond)
+	set.replicas[1].latency.Store(int64(time.Microsecond))
+	if db := set.pick(); db != set.replicas[2].db {
+		t.Fatal("应该选择延迟最低的健康副本")
+	}
+
+	// 没有健康副本时使用主库
+	for _, policy := range []string{replicaPolicyRoundRobin, replicaPolicyLeastLatency} {
+		if newTestReplicaSet(policy, -1, -1).pick() != nil {
+			t.Fatalf("没有健康副本时应该使用主库(%s)", policy)
+		}
+	}
+}
+
+func TestParseReplicationLag(t *testing.T) {
+	tests := []struct {
+		name   string
+		status map[string]interface{}
+		lag    time.Duration
+		ok     bool
+	}{
+		{"整数", map[string]interface{}{"Seconds_Behind_Source": int64(3)}, 3 * time.Second, true},
+		{"字节切片", map[string]interface{}{"Seconds_Behind_Source": []byte("12")}, 12 * time.Second, true},
+		{"复制中断", map[string]interface{}{"Seconds_Behind_Source": nil}, 0, false},
+		{"缺少延迟列", map[string]interface{}{"Seconds_Behind_Master": int64(1)}, 0, false},
+		{"无法解析", map[string]interface{}{"Seconds_Behind_Source": "abc"}, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lag, err := parseReplicationLag(test.status, "Seconds_Behind_Source")
+			if (err == nil) != test.ok || lag != test.lag {
+				t.Fatalf("解析结果错误(%s, %v)，期望(%s)", lag, err, test.lag)
+			}
+		})
+	}
+}
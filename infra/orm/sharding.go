@@ -0,0 +1,126 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-21 09:47:13
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-21 09:47:13
+ * @FilePath: \common\infra\orm\sharding.go
+ * @Description: 分表规则实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/pkg/errors"
+)
+
+// 编译期保证接口实现的一致性
+var (
+	_ common.ShardingRule = (*TimeShardingRule)(nil)
+	_ common.ShardingRule = (*HashShardingRule)(nil)
+)
+
+// 分表规则相关定义
+const (
+	ShardLayoutDaily   = "20060102" // 按天分表
+	ShardLayoutMonthly = "200601"   // 按月分表
+	ShardLayoutYearly  = "2006"     // 按年分表
+)
+
+// TimeShardingRule 按时间分表规则，物理表名为"逻辑表名_时间"，例如：blog_log_202610
+type TimeShardingRule struct {
+	Layout   string         // 时间格式，为空时按月分表
+	Location *time.Location // 计算分表时间使用的时区，为空时使用分片键自身的时区
+}
+
+// NewTimeShardingRule 创建按时间分表规则
+func NewTimeShardingRule(layout string) *TimeShardingRule {
+	return &TimeShardingRule{Layout: layout}
+}
+
+// PhysicalTable 根据时间分片键计算物理表名，分片键必须是time.Time或者*time.Time
+func (r *TimeShardingRule) PhysicalTable(logicalTable string, shardKey interface{}) (string, error) {
+	var shardTime time.Time
+	switch key := shardKey.(type) {
+	case time.Time:
+		shardTime = key
+	case *time.Time:
+		if key == nil {
+			return "", errors.New("分片键不能为空指针")
+		}
+		shardTime = *key
+	default:
+		return "", fmt.Errorf("按时间分表的分片键必须是时间类型，实际为(%T)", shardKey)
+	}
+
+	if r.Location != nil {
+		shardTime = shardTime.In(r.Location)
+	}
+
+	layout := r.Layout
+	if layout == "" {
+		layout = ShardLayoutMonthly
+	}
+
+	return logicalTable + "_" + shardTime.Format(layout), nil
+}
+
+// HashShardingRule 按哈希分表规则，物理表名为"逻辑表名_序号"，序号从0开始，例如：blog_user_3
+// 整数分片键(任意位宽的有符号和无符号整数)直接取模，其余类型的分片键取字符串形式的FNV-1a哈希值后取模
+// 指针类型的分片键按指向的值计算，保证同一个值无论以何种类型传入都落在同一张表
+type HashShardingRule struct {
+	Shards int // 分表数量
+}
+
+// NewHashShardingRule 创建按哈希分表规则
+func NewHashShardingRule(shards int) *HashShardingRule {
+	return &HashShardingRule{Shards: shards}
+}
+
+// PhysicalTable 根据分片键计算物理表名
+func (r *HashShardingRule) PhysicalTable(logicalTable string, shardKey interface{}) (string, error) {
+	if r.Shards <= 0 {
+		return "", fmt.Errorf("分表数量必须大于0，实际为(%d)", r.Shards)
+	}
+
+	key := reflect.ValueOf(shardKey)
+	for key.Kind() == reflect.Ptr {
+		if key.IsNil() {
+			return "", errors.New("分片键不能为空指针")
+		}
+		key = key.Elem()
+	}
+	if !key.IsValid() {
+		return "", errors.New("分片键不能为空")
+	}
+
+	var hash uint64
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		hash = absUint64(key.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hash = key.Uint()
+	default:
+		hasher := fnv.New64a()
+		hasher.Write([]byte(fmt.Sprint(key.Interface())))
+		hash = hasher.Sum64()
+	}
+
+	return fmt.Sprintf("%s_%d", logicalTable, hash%uint64(r.Shards)), nil
+}
+
+// absUint64 获取整数的绝对值
+func absUint64(value int64) uint64 {
+	if value < 0 {
+		return uint64(-value)
+	}
+	return uint64(value)
+}
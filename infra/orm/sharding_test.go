@@ -0,0 +1,80 @@
+package orm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeShardingRule(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	shardTime := time.Date(2026, 10, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     *TimeShardingRule
+		shardKey interface{}
+		table    string
+		ok       bool
+	}{
+		{"默认按月分表", NewTimeShardingRule(""), shardTime, "blog_log_202610", true},
+		{"按天分表", NewTimeShardingRule(ShardLayoutDaily), shardTime, "blog_log_20261031", true},
+		{"按年分表", NewTimeShardingRule(ShardLayoutYearly), &shardTime, "blog_log_2026", true},
+		{"指定时区", &TimeShardingRule{Layout: ShardLayoutDaily, Location: shanghai}, shardTime, "blog_log_20261101", true},
+		{"空指针", NewTimeShardingRule(""), (*time.Time)(nil), "", false},
+		{"非时间类型", NewTimeShardingRule(""), "2026-10-31", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table, err := test.rule.PhysicalTable("blog_log", test.shardKey)
+			if (err == nil) != test.ok || table != test.table {
+				t.Fatalf("分表结果错误(%s, %v)，期望(%s)", table, err, test.table)
+			}
+		})
+	}
+}
+
+func TestHashShardingRule(t *testing.T) {
+	type userID int64
+	type userName string
+
+	id := int64(13)
+	name := "alice"
+	namePointer := &name
+
+	tests := []struct {
+		name     string
+		shards   int
+		shardKey interface{}
+		table    string
+		ok       bool
+	}{
+		{"int", 4, 13, "blog_user_1", true},
+		{"int8", 4, int8(13), "blog_user_1", true},
+		{"int16", 4, int16(13), "blog_user_1", true},
+		{"int32", 4, int32(13), "blog_user_1", true},
+		{"int64", 4, int64(13), "blog_user_1", true},
+		{"uint8", 4, uint8(13), "blog_user_1", true},
+		{"uint16", 4, uint16(13), "blog_user_1", true},
+		{"uint64", 4, uint64(13), "blog_user_1", true},
+		{"负数取绝对值", 4, -13, "blog_user_1", true},
+		{"自定义整数类型", 4, userID(13), "blog_user_1", true},
+		{"整数指针", 4, &id, "blog_user_1", true},
+		{"字符串", 4, "alice", "blog_user_3", true},
+		{"自定义字符串类型", 4, userName("alice"), "blog_user_3", true},
+		{"字符串指针", 4, &name, "blog_user_3", true},
+		{"多级指针", 4, &namePointer, "blog_user_3", true},
+		{"空值", 4, nil, "", false},
+		{"空指针", 4, (*int64)(nil), "", false},
+		{"分表数量为0", 0, 13, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table, err := NewHashShardingRule(test.shards).PhysicalTable("blog_user", test.shardKey)
+			if (err == nil) != test.ok || table != test.table {
+				t.Fatalf("分表结果错误(%s, %v)，期望(%s)", table, err, test.table)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//go:build sqlite && cgo
+
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-20 16:02:37
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-20 16:02:37
+ * @FilePath: \common\infra\orm\sqlite.go
+ * @Description: SQLite驱动注册与错误分类(SQLite驱动依赖cgo，使用-tags sqlite编译时才启用，避免所有引用orm的服务都需要cgo)
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteEnabled 是否已注册SQLite驱动
+const sqliteEnabled = true
+
+// classifySQLiteError 将SQLite驱动错误归类为对应的哨兵错误，第二个返回值表示是否为SQLite驱动错误
+func classifySQLiteError(err error) (error, bool) {
+	var sqliteError sqlite3.Error
+	if !errors.As(err, &sqliteError) {
+		return nil, false
+	}
+
+	switch {
+	case sqliteError.ExtendedCode == sqlite3.ErrConstraintUnique, sqliteError.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return ErrDuplicateKey, true
+	case sqliteError.Code == sqlite3.ErrBusy, sqliteError.Code == sqlite3.ErrLocked:
+		return ErrLockWaitTimeout, true
+	}
+	return nil, true
+}
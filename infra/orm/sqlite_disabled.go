@@ -0,0 +1,22 @@
+//go:build !sqlite || !cgo
+
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-20 16:02:37
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-20 16:02:37
+ * @FilePath: \common\infra\orm\sqlite_disabled.go
+ * @Description: 未使用-tags sqlite编译或者未开启cgo时SQLite驱动不可用，不需要错误分类
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+// sqliteEnabled 是否已注册SQLite驱动
+const sqliteEnabled = false
+
+// classifySQLiteError 未启用SQLite驱动时不存在SQLite驱动错误
+func classifySQLiteError(err error) (error, bool) {
+	return nil, false
+}
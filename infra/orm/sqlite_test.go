@@ -0,0 +1,316 @@
+//go:build sqlite && cgo
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/common"
+	ormConfig "github.com/hongliu9527/common/infra/orm/config"
+)
+
+// testUser 测试数据模型
+type testUser struct {
+	ID   int64  `db:"id" orm:"pk"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+// testUserSchema 测试数据表结构
+const testUserSchema = `CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL, age INTEGER NOT NULL DEFAULT 0)`
+
+// newSQLiteConnection 创建内存SQLite数据库连接，并执行建表语句
+func newSQLiteConnection(t *testing.T, schema ...string) *dbConnection {
+	t.Helper()
+
+	config := ormConfig.DataBaseConfig{
+		Name:         "test.sqlite",
+		Type:         "sqlite",
+		DatabaseName: ":memory:",
+		BatchSize:    2,
+	}
+	db, err := connectOneSqlx("", false, config)
+	if err != nil {
+		t.Fatalf("连接SQLite失败(%s)", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, query := range schema {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("执行建表语句失败(%s)", err)
+		}
+	}
+
+	return &dbConnection{db: db, ctx: context.Background(), config: config, tableName: "user"}
+}
+
+// mustInsert 插入测试数据
+func mustInsert(t *testing.T, conn *dbConnection, users ...testUser) {
+	t.Helper()
+	for _, user := range users {
+		if _, err := conn.Insert(user); err != nil {
+			t.Fatalf("插入数据(%d)失败(%s)", user.ID, err)
+		}
+	}
+}
+
+// countUsers 查询数据条数
+func countUsers(t *testing.T, conn *dbConnection) int {
+	t.Helper()
+	var count int
+	if err := conn.Get(&count, "SELECT COUNT(*) FROM user"); err != nil {
+		t.Fatalf("查询数据条数失败(%s)", err)
+	}
+	return count
+}
+
+func TestSQLiteCRUD(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+
+	result, err := conn.Insert(testUser{ID: 1, Name: "alice", Age: 20})
+	if err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+	if result.RowsAffected != 1 || result.LastInsertId != 1 {
+		t.Fatalf("插入结果错误(%+v)", result)
+	}
+	mustInsert(t, conn, testUser{ID: 2, Name: "bob", Age: 30})
+
+	var user testUser
+	if err := conn.Get(&user, "SELECT * FROM user WHERE id = ?", 1); err != nil {
+		t.Fatalf("查询数据失败(%s)", err)
+	}
+	if user != (testUser{ID: 1, Name: "alice", Age: 20}) {
+		t.Fatalf("查询结果错误(%+v)", user)
+	}
+
+	if _, err := conn.Update("id = :id", map[string]interface{}{"id": 1, "age": 21}); err != nil {
+		t.Fatalf("更新数据失败(%s)", err)
+	}
+	if _, err := conn.UpdateByPK(&testUser{ID: 2, Name: "bobby", Age: 31}, "name"); err != nil {
+		t.Fatalf("根据主键更新数据失败(%s)", err)
+	}
+
+	users := make([]testUser, 0)
+	if err := conn.Select(&users, "SELECT * FROM user WHERE id IN (?) ORDER BY id", []int64{1, 2}); err != nil {
+		t.Fatalf("查询多个数据失败(%s)", err)
+	}
+	expected := []testUser{{ID: 1, Name: "alice", Age: 21}, {ID: 2, Name: "bobby", Age: 30}}
+	if len(users) != len(expected) || users[0] != expected[0] || users[1] != expected[1] {
+		t.Fatalf("更新后查询结果错误(%+v)", users)
+	}
+
+	result, err = conn.Delete("id = ?", 1)
+	if err != nil {
+		t.Fatalf("删除数据失败(%s)", err)
+	}
+	if result.RowsAffected != 1 {
+		t.Fatalf("删除结果错误(%+v)", result)
+	}
+	if err := conn.Get(&user, "SELECT * FROM user WHERE id = ?", 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("删除后应该查询不到数据(%v)", err)
+	}
+}
+
+func TestSQLiteAutoIncrement(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+
+	// 自增主键为零值时不插入该列，由数据库生成
+	for i, name := range []string{"alice", "bob"} {
+		result, err := conn.Insert(testUser{Name: name})
+		if err != nil {
+			t.Fatalf("插入第%d条数据失败(%s)", i+1, err)
+		}
+		if result.LastInsertId != int64(i+1) {
+			t.Fatalf("第%d条数据的自增主键错误(%+v)", i+1, result)
+		}
+	}
+
+	if err := conn.BatchInsert([]testUser{{Name: "c"}, {Name: "d"}, {Name: "e"}}); err != nil {
+		t.Fatalf("批量插入自增主键为零值的数据失败(%s)", err)
+	}
+	var ids []int64
+	if err := conn.Select(&ids, "SELECT id FROM user ORDER BY id"); err != nil {
+		t.Fatalf("查询数据失败(%s)", err)
+	}
+	if len(ids) != 5 || ids[4] != 5 {
+		t.Fatalf("自增主键错误(%v)", ids)
+	}
+
+	// 零值主键插入或更新时同样由数据库生成
+	result, err := conn.Upsert(testUser{Name: "f"})
+	if err != nil || result.LastInsertId != 6 {
+		t.Fatalf("插入或更新自增主键为零值的数据失败(%+v, %v)", result, err)
+	}
+
+	// 同一批数据中自增主键部分为零值时返回错误
+	if err := conn.BatchInsert([]testUser{{ID: 10, Name: "g"}, {Name: "h"}}); err == nil {
+		t.Fatal("同一批数据中自增主键部分为零值时应该返回错误")
+	}
+}
+
+func TestSQLiteUpsert(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+
+	if _, err := conn.Upsert(testUser{ID: 1, Name: "alice", Age: 20}); err != nil {
+		t.Fatalf("插入数据失败(%s)", err)
+	}
+	if _, err := conn.Upsert(testUser{ID: 1, Name: "alice", Age: 25}); err != nil {
+		t.Fatalf("冲突时更新数据失败(%s)", err)
+	}
+
+	var user testUser
+	if err := conn.Get(&user, "SELECT * FROM user WHERE id = ?", 1); err != nil {
+		t.Fatalf("查询数据失败(%s)", err)
+	}
+	if user.Age != 25 || countUsers(t, conn) != 1 {
+		t.Fatalf("插入或更新结果错误(%+v)", user)
+	}
+}
+
+func TestSQLiteBatchInsert(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+
+	users := []testUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}, {ID: 5, Name: "e"}}
+	progresses := make([]common.BatchProgress, 0)
+	err := conn.BatchInsertWithOptions(context.Background(), users, common.BatchOptions{
+		Progress: func(progress common.BatchProgress) {
+			progresses = append(progresses, progress)
+		},
+	})
+	if err != nil {
+		t.Fatalf("批量插入失败(%s)", err)
+	}
+	if countUsers(t, conn) != len(users) {
+		t.Fatalf("批量插入的数据条数错误(%d)", countUsers(t, conn))
+	}
+
+	// 配置的批量大小为2，5条数据分3批插入
+	if len(progresses) != 3 || progresses[2].Rows != 1 || progresses[2].Total != len(users) {
+		t.Fatalf("批量插入进度错误(%+v)", progresses)
+	}
+
+	// 同一个事务中插入时，某一批失败后所有批次都回滚
+	conflicts := []testUser{{ID: 6, Name: "f"}, {ID: 7, Name: "g"}, {ID: 1, Name: "duplicate"}}
+	err = conn.BatchInsertWithOptions(context.Background(), conflicts, common.BatchOptions{InTransaction: true})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("主键冲突时应该返回ErrDuplicateKey(%v)", err)
+	}
+	if countUsers(t, conn) != len(users) {
+		t.Fatalf("事务回滚后数据条数错误(%d)", countUsers(t, conn))
+	}
+}
+
+func TestSQLiteWithTxSavepoint(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+	ctx := context.Background()
+
+	innerErr := errors.New("内层事务失败")
+	err := conn.WithTx(ctx, func(tx common.Orm) error {
+		if _, err := tx.InsertContext(ctx, testUser{ID: 1, Name: "outer"}); err != nil {
+			return err
+		}
+
+		// 内层事务回滚到保存点，不影响外层事务已经写入的数据
+		err := tx.WithTx(ctx, func(tx common.Orm) error {
+			if _, err := tx.InsertContext(ctx, testUser{ID: 2, Name: "inner"}); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Fatalf("内层事务应该返回函数的错误(%v)", err)
+		}
+
+		return tx.WithTx(ctx, func(tx common.Orm) error {
+			_, err := tx.InsertContext(ctx, testUser{ID: 3, Name: "committed"})
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("外层事务失败(%s)", err)
+	}
+
+	ids := make([]int64, 0)
+	if err := conn.Select(&ids, "SELECT id FROM user ORDER BY id"); err != nil {
+		t.Fatalf("查询数据失败(%s)", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Fatalf("嵌套事务提交结果错误(%v)", ids)
+	}
+	if conn.tx != nil || conn.txDepth != 0 {
+		t.Fatalf("事务结束后事务状态没有清除(depth=%d)", conn.txDepth)
+	}
+
+	// 外层事务失败时所有数据都回滚
+	err = conn.WithTx(ctx, func(tx common.Orm) error {
+		if _, err := tx.InsertContext(ctx, testUser{ID: 4, Name: "rollback"}); err != nil {
+			return err
+		}
+		return innerErr
+	})
+	if !errors.Is(err, innerErr) || countUsers(t, conn) != 2 {
+		t.Fatalf("外层事务回滚结果错误(%v)", err)
+	}
+}
+
+func TestSQLiteQueryBuilder(t *testing.T) {
+	conn := newSQLiteConnection(t, testUserSchema)
+	mustInsert(t, conn,
+		testUser{ID: 1, Name: "100%", Age: 10},
+		testUser{ID: 2, Name: "1000", Age: 20},
+		testUser{ID: 3, Name: "a_b", Age: 30},
+		testUser{ID: 4, Name: "axb", Age: 40},
+	)
+
+	tests := []struct {
+		name    string
+		filters []common.FilterParam
+		ids     []int64
+	}{
+		{"转义百分号", []common.FilterParam{{Name: "name", Operator: "like", Values: []string{"0%"}}}, []int64{1}},
+		{"转义下划线", []common.FilterParam{{Name: "name", Operator: "like", Values: []string{"_"}}}, []int64{3}},
+		{"前缀匹配", []common.FilterParam{{Name: "name", Operator: "prefix", Values: []string{"a_"}}}, []int64{3}},
+		{"区间", []common.FilterParam{{Name: "age", Operator: "between", Values: []string{"15", "35"}}}, []int64{2, 3}},
+		{"列表", []common.FilterParam{{Name: "id", Operator: "in", Values: []string{"1", "4"}}}, []int64{1, 4}},
+		{"组合条件", []common.FilterParam{
+			{Name: "name", Operator: "prefix", Values: []string{"a"}},
+			{Name: "age", Operator: ">", Values: []string{"35"}},
+		}, []int64{4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder, err := NewQueryBuilder(testUser{})
+			if err != nil {
+				t.Fatalf("创建查询条件构建器失败(%s)", err)
+			}
+			clause, args, err := builder.Dialect("sqlite").Filter(test.filters...).Sort(common.SortParm{Name: "id"}).Build()
+			if err != nil {
+				t.Fatalf("构建查询条件失败(%s)", err)
+			}
+
+			ids := make([]int64, 0)
+			if err := conn.Select(&ids, "SELECT id FROM user"+clause, args...); err != nil {
+				t.Fatalf("查询数据失败(%s)", err)
+			}
+			if len(ids) != len(test.ids) {
+				t.Fatalf("查询结果错误(%v)，期望(%v)", ids, test.ids)
+			}
+			for i := range ids {
+				if ids[i] != test.ids[i] {
+					t.Fatalf("查询结果错误(%v)，期望(%v)", ids, test.ids)
+				}
+			}
+		})
+	}
+
+	// 不允许的列名会作为构建错误返回
+	builder, _ := NewQueryBuilder(testUser{})
+	if _, _, err := builder.Filter(common.FilterParam{Name: "password", Operator: "=", Values: []string{"x"}}).Build(); err == nil {
+		t.Fatal("白名单以外的列名应该返回错误")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 14:31:05
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 14:31:05
+ * @FilePath: \common\infra\orm\trace.go
+ * @Description: orm链路追踪
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package orm
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sql语句脱敏相关正则表达式
+var (
+	stringLiteralRegexp  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)       // 字符串常量
+	numericLiteralRegexp = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)          // 数字常量
+	inListRegexp         = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`) // in条件展开后的占位符列表
+)
+
+// sanitizeSQL 将sql语句中的常量替换为占位符，避免在链路中泄露业务数据
+func sanitizeSQL(query string) string {
+	query = stringLiteralRegexp.ReplaceAllString(query, "?")
+	query = numericLiteralRegexp.ReplaceAllString(query, "?")
+	return inListRegexp.ReplaceAllString(query, "(?)")
+}
+
+// startSpan 创建数据库操作链路
+func (c *dbConnection) startSpan(ctx context.Context, operation string, query string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemKey.String(c.config.Type),
+		semconv.DBNameKey.String(c.config.DatabaseName),
+		semconv.DBSQLTableKey.String(c.tableName),
+		semconv.DBOperationKey.String(operation),
+	}
+	if query != "" {
+		attributes = append(attributes, semconv.DBStatementKey.String(sanitizeSQL(query)))
+	}
+
+	return tracing.Start(ctx, operation+" "+c.tableName, trace.SpanKindClient, attributes...)
+}
@@ -0,0 +1,88 @@
+//go:build sqlite && cgo
+
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSpanExporter 使用内存链路导出器替换基础设施的链路追踪提供者
+func newSpanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { tracing.SetTracerProvider(nil) })
+	return exporter
+}
+
+// spanAttributes 将链路属性转换为键值映射
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestOrmSpan(t *testing.T) {
+	exporter := newSpanExporter(t)
+	conn := newSQLiteConnection(t, testUserSchema)
+
+	ctx, parent := tracing.Start(context.Background(), "parent", trace.SpanKindInternal)
+	_, err := conn.ExecContext(ctx, "INSERT INTO user (id, name, age) VALUES (1, 'secret', 18)")
+	if err != nil {
+		t.Fatalf("执行语句失败(%s)", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("链路数量错误(%d)", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "exec user" || span.SpanKind != trace.SpanKindClient {
+		t.Fatalf("链路名称或类型错误(%s, %s)", span.Name, span.SpanKind)
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("数据库操作链路应该是上下文中链路的子链路")
+	}
+	if span.Status.Code != codes.Unset {
+		t.Fatalf("执行成功时链路状态错误(%v)", span.Status)
+	}
+
+	// 语句中的常量需要脱敏，不能出现在链路中
+	attributes := spanAttributes(span)
+	expected := map[attribute.Key]string{
+		semconv.DBSystemKey:    "sqlite",
+		semconv.DBNameKey:      ":memory:",
+		semconv.DBSQLTableKey:  "user",
+		semconv.DBOperationKey: "exec",
+		semconv.DBStatementKey: "INSERT INTO user (id, name, age) VALUES (?)",
+	}
+	for key, value := range expected {
+		if actual := attributes[key].AsString(); actual != value {
+			t.Errorf("链路属性(%s)错误(%s)，期望(%s)", key, actual, value)
+		}
+	}
+
+	// 执行失败时记录错误
+	exporter.Reset()
+	if _, err := conn.ExecContext(context.Background(), "SELECT * FROM missing"); err == nil {
+		t.Fatal("查询不存在的数据表应该返回错误")
+	}
+	spans = exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error || len(spans[0].Events) != 1 {
+		t.Fatalf("执行失败时应该记录错误(%+v)", spans)
+	}
+}
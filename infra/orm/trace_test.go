@@ -0,0 +1,24 @@
+package orm
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query  string
+		expect string
+	}{
+		{"SELECT * FROM user WHERE id = ?", "SELECT * FROM user WHERE id = ?"},
+		{"SELECT * FROM user WHERE name = 'alice' AND age > 18", "SELECT * FROM user WHERE name = ? AND age > ?"},
+		{`SELECT * FROM user WHERE name = 'it''s' OR name = 'a\'b'`, "SELECT * FROM user WHERE name = ? OR name = ?"},
+		{"SELECT * FROM user WHERE score = 9.5 LIMIT 10", "SELECT * FROM user WHERE score = ? LIMIT ?"},
+		{"SELECT * FROM user WHERE id IN (?, ?, ?)", "SELECT * FROM user WHERE id IN (?)"},
+		{"SELECT * FROM user WHERE id IN (1, 2, 3)", "SELECT * FROM user WHERE id IN (?)"},
+		{"SELECT * FROM user_2022", "SELECT * FROM user_2022"},
+	}
+
+	for _, test := range tests {
+		if actual := sanitizeSQL(test.query); actual != test.expect {
+			t.Errorf("脱敏结果错误\n语句: %s\n实际: %s\n期望: %s", test.query, actual, test.expect)
+		}
+	}
+}
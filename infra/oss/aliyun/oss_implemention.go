@@ -17,10 +17,13 @@ import (
 	"io"
 	"io/ioutil"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hongliu9527/common/infra/metrics"
+
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/hongliu9527/go-tools/logger"
@@ -29,6 +32,7 @@ import (
 
 // Publish 发布文件
 func (i *aliyunOssInfra) Publish(ctx context.Context, sourcePath string, targetPath string) error {
+	begin := time.Now()
 
 	retryCount := i.config.RetryCount
 	// Oss分片上传文件方式(该方式对网络的要求相对更低，避免了大文件上传超时导致的失败)
@@ -47,19 +51,28 @@ func (i *aliyunOssInfra) Publish(ctx context.Context, sourcePath string, targetP
 
 	logger.Info("上传文件到阿里云Oss成功(%s)", targetPath)
 
+	var size int64
+	if fileInfo, err := os.Stat(sourcePath); err == nil {
+		size = fileInfo.Size()
+	}
+	metrics.ObserveOssTransfer(AliyunOssInfraName, "upload", size, time.Since(begin))
+
 	return nil
 }
 
 // PublishFromReader 从io.Reader上传文件到oss
 func (i *aliyunOssInfra) PublishFromReader(ctx context.Context, filename string, reader io.Reader) (string, error) {
+	begin := time.Now()
 
-	err := i.bucket.PutObject(filename, reader)
+	countingReader := &metrics.CountingReader{Reader: reader}
+	err := i.bucket.PutObject(filename, countingReader)
 	if err != nil {
 		logger.Error("上传文件(%s)到桶(%s)失败(%s)", filename, i.config.Bucket, err.Error())
 
 		return "", errors.Wrap(err, "上传文件到阿里云Oss失败")
 	}
 	logger.Info("文件(%s)上传成功", filename)
+	metrics.ObserveOssTransfer(AliyunOssInfraName, "upload", countingReader.Count, time.Since(begin))
 
 	host := strings.Trim(i.config.Endpoint, "http://")
 	return fmt.Sprintf("http://%s.%s/%s", i.config.Bucket, host, filename), nil
@@ -74,6 +87,8 @@ func (i *aliyunOssInfra) Copy(ctx context.Context, sourcePath string, destPath s
 
 // Get 从oss下载文件
 func (i *aliyunOssInfra) Get(ctx context.Context, filePath string) ([]byte, error) {
+	begin := time.Now()
+
 	originUrl, err := i.GetOriginUrl(ctx, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("获取原始url失败(%s)", err.Error())
@@ -89,6 +104,9 @@ func (i *aliyunOssInfra) Get(ctx context.Context, filePath string) ([]byte, erro
 	defer reader.Close()
 
 	content, err := ioutil.ReadAll(reader)
+	if err == nil {
+		metrics.ObserveOssTransfer(AliyunOssInfraName, "download", int64(len(content)), time.Since(begin))
+	}
 	return content, errors.Wrapf(err, "阿里云oss下载文件后,读取失败(%s)", originUrl)
 }
 
@@ -218,6 +236,7 @@ func (i *aliyunOssInfra) GetOriginUrl(ctx context.Context, signatureURL string)
 func (i *aliyunOssInfra) retransmission(sourcePath string, targetPath string, maxRetryCount int) error {
 	for retryCount := 0; retryCount < maxRetryCount; retryCount++ {
 		time.Sleep(time.Duration(500*(retryCount+1)) * time.Millisecond)
+		metrics.IncOssRetry(AliyunOssInfraName, "upload")
 
 		err := i.bucket.UploadFile(targetPath, sourcePath, 100*1024, oss.Routines(3), oss.Checkpoint(true, ""))
 		if err != nil {
@@ -19,7 +19,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/hongliu9527/common/infra/metrics"
 	"github.com/hongliu9527/common/utils"
 
 	"github.com/hongliu9527/go-tools/logger"
@@ -27,6 +29,8 @@ import (
 
 // Publish 发布文件,在local存储为
 func (i *localOssInfra) Publish(ctx context.Context, sourcePath string, targetPath string) error {
+	begin := time.Now()
+
 	if !utils.IsFilePathExist(sourcePath) {
 		logger.Error("文件路径(%s)不存在", sourcePath)
 		return fmt.Errorf("文件路径(%s)不存在", sourcePath)
@@ -80,12 +84,15 @@ func (i *localOssInfra) Publish(ctx context.Context, sourcePath string, targetPa
 	if isBackup {
 		os.Remove(targetFilePath + ".bak")
 	}
+	metrics.ObserveOssTransfer(LocalOssInfraName, "upload", int64(len(content)), time.Since(begin))
 
 	return nil
 }
 
 // PublishFromReader 从缓存向oss发布数据,这里的
 func (i *localOssInfra) PublishFromReader(ctx context.Context, filename string, reader io.Reader) (string, error) {
+	begin := time.Now()
+
 	// 如果目标文件存在，则重命名目标文件
 	isBackup := false
 	if utils.IsFilePathExist(filename) {
@@ -121,7 +128,7 @@ func (i *localOssInfra) PublishFromReader(ctx context.Context, filename string,
 		return "", err
 	}
 
-	_, err = io.Copy(targetFile, reader)
+	size, err := io.Copy(targetFile, reader)
 	if err != nil {
 		if isBackup {
 			os.Rename(targetFilePath+".bak", targetFilePath)
@@ -135,6 +142,7 @@ func (i *localOssInfra) PublishFromReader(ctx context.Context, filename string,
 		os.Remove(targetFilePath + ".bak")
 	}
 	targetFile.Close()
+	metrics.ObserveOssTransfer(LocalOssInfraName, "upload", size, time.Since(begin))
 
 	return filename, nil
 }
@@ -212,12 +220,15 @@ func (i *localOssInfra) Copy(ctx context.Context, sourcePath string, destPath st
 
 // Get 从oss下载文件
 func (i *localOssInfra) Get(ctx context.Context, filePath string) ([]byte, error) {
+	begin := time.Now()
+
 	sourceFilePath := i.config.Endpoint + "/" + filePath
 	content, err := ioutil.ReadFile(sourceFilePath)
 	if err != nil {
 		logger.Error("读取文件(%s)内容失败(%s)", sourceFilePath, err.Error())
 		return nil, err
 	}
+	metrics.ObserveOssTransfer(LocalOssInfraName, "download", int64(len(content)), time.Since(begin))
 
 	return content, nil
 }
@@ -33,7 +33,11 @@ func New(ossConfig *config.OssInfraConfig) common.OssInfra {
 		ossInfra = local.New(ossConfig)
 	default:
 		logger.Error("创建Oss基础设施失败(不支持的Oss厂商类型：%s)", ossConfig.ServiceVendor)
+		return ossInfra
 	}
 
+	// 为Oss基础设施添加链路追踪
+	ossInfra = newTracedOssInfra(ossInfra, string(ossConfig.ServiceVendor))
+
 	return ossInfra
 }
@@ -0,0 +1,87 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 15:12:40
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 15:12:40
+ * @FilePath: \common\infra\oss\trace.go
+ * @Description: oss链路追踪
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package oss
+
+import (
+	"context"
+	"io"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 编译期保证接口实现的一致性
+var _ common.OssInfra = (*tracedOssInfra)(nil)
+
+// tracedOssInfra 带链路追踪的Oss基础设施，基础设施相关接口直接由厂商实现处理
+type tracedOssInfra struct {
+	common.OssInfra        // 厂商Oss基础设施
+	vendor          string // Oss厂商类型
+}
+
+// newTracedOssInfra 创建带链路追踪的Oss基础设施
+func newTracedOssInfra(ossInfra common.OssInfra, vendor string) common.OssInfra {
+	return &tracedOssInfra{
+		OssInfra: ossInfra,
+		vendor:   vendor,
+	}
+}
+
+// startSpan 创建oss操作链路
+func (i *tracedOssInfra) startSpan(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, attribute.String("oss.vendor", i.vendor))
+	return tracing.Start(ctx, "oss."+operation, trace.SpanKindClient, attributes...)
+}
+
+// Publish 向oss发布文件
+func (i *tracedOssInfra) Publish(ctx context.Context, sourcePath string, destPath string) error {
+	ctx, span := i.startSpan(ctx, "Publish", attribute.String("oss.path", destPath))
+	err := i.OssInfra.Publish(ctx, sourcePath, destPath)
+	tracing.End(span, err)
+	return err
+}
+
+// PublishFromReader 从缓存向oss发布文件
+func (i *tracedOssInfra) PublishFromReader(ctx context.Context, filename string, reader io.Reader) (string, error) {
+	ctx, span := i.startSpan(ctx, "PublishFromReader", attribute.String("oss.path", filename))
+	url, err := i.OssInfra.PublishFromReader(ctx, filename, reader)
+	tracing.End(span, err)
+	return url, err
+}
+
+// Get 从oss下载文件
+func (i *tracedOssInfra) Get(ctx context.Context, filePath string) ([]byte, error) {
+	ctx, span := i.startSpan(ctx, "Get", attribute.String("oss.path", filePath))
+	content, err := i.OssInfra.Get(ctx, filePath)
+	span.SetAttributes(attribute.Int("oss.size", len(content)))
+	tracing.End(span, err)
+	return content, err
+}
+
+// ConstructTemporarySignatureUrl 构建临时签名Url
+func (i *tracedOssInfra) ConstructTemporarySignatureUrl(ctx context.Context, originUrl string) (string, error) {
+	ctx, span := i.startSpan(ctx, "ConstructTemporarySignatureUrl")
+	url, err := i.OssInfra.ConstructTemporarySignatureUrl(ctx, originUrl)
+	tracing.End(span, err)
+	return url, err
+}
+
+// ConstructTemporarySignatureUrls 批量构建临时签名Url
+func (i *tracedOssInfra) ConstructTemporarySignatureUrls(ctx context.Context, urlMap map[uint]string) (map[uint]string, error) {
+	ctx, span := i.startSpan(ctx, "ConstructTemporarySignatureUrls", attribute.Int("oss.count", len(urlMap)))
+	signatureMap, err := i.OssInfra.ConstructTemporarySignatureUrls(ctx, urlMap)
+	tracing.End(span, err)
+	return signatureMap, err
+}
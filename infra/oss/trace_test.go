@@ -0,0 +1,82 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeOssInfra 测试用Oss基础设施，只实现链路追踪测试用到的接口
+type fakeOssInfra struct {
+	common.OssInfra
+	content []byte // Get返回的文件内容
+	err     error  // Get返回的错误
+}
+
+// Get 返回预设的文件内容和错误
+func (i *fakeOssInfra) Get(ctx context.Context, filePath string) ([]byte, error) {
+	return i.content, i.err
+}
+
+func TestTracedOssInfra(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+
+	tests := []struct {
+		name    string
+		content []byte
+		err     error
+		code    codes.Code
+	}{
+		{"下载成功", []byte("hello"), nil, codes.Unset},
+		{"下载失败", nil, errors.New("文件不存在"), codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exporter.Reset()
+			ossInfra := newTracedOssInfra(&fakeOssInfra{content: test.content, err: test.err}, "aliyun")
+
+			content, err := ossInfra.Get(context.Background(), "dir/file.txt")
+			if string(content) != string(test.content) || err != test.err {
+				t.Fatalf("应该返回厂商实现的结果(%s, %v)", content, err)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("链路数量错误(%d)", len(spans))
+			}
+			span := spans[0]
+			if span.Name != "oss.Get" || span.SpanKind != trace.SpanKindClient {
+				t.Fatalf("链路名称或类型错误(%s, %s)", span.Name, span.SpanKind)
+			}
+			if span.Status.Code != test.code {
+				t.Fatalf("链路状态错误(%v)，期望(%v)", span.Status.Code, test.code)
+			}
+
+			expected := map[attribute.Key]attribute.Value{
+				"oss.vendor": attribute.StringValue("aliyun"),
+				"oss.path":   attribute.StringValue("dir/file.txt"),
+				"oss.size":   attribute.IntValue(len(test.content)),
+			}
+			for _, kv := range span.Attributes {
+				if value, ok := expected[kv.Key]; ok && value == kv.Value {
+					delete(expected, kv.Key)
+				}
+			}
+			if len(expected) > 0 {
+				t.Fatalf("链路属性错误(%v)", span.Attributes)
+			}
+		})
+	}
+}
@@ -43,6 +43,17 @@ type RedisInfraConfig struct {
 	InternalHostPort string                `mapstructure:"internalHostPort" default:"127.0.0.1:6379" ` // Redis内网主机名称或访问地址和访问端口
 	Password         string                `mapstructure:"password" default:"" `                       // 登录密码，默认为空
 	DB               int                   `mapstructure:"db" default:"0" `                            // 数据库索引(从0开始),根据数据库个数逐个递增
+	Mode             string                `mapstructure:"mode" default:"standalone"`                  // 部署模式：standalone(单机)、sentinel(哨兵)、cluster(集群)
+	Addrs            string                `mapstructure:"addrs" default:""`                           // 哨兵或集群节点的外网地址，多个地址使用逗号分隔，为空时使用hostPort
+	InternalAddrs    string                `mapstructure:"internalAddrs" default:""`                   // 哨兵或集群节点的内网地址，多个地址使用逗号分隔，为空时使用internalHostPort
+	MasterName       string                `mapstructure:"masterName" default:""`                      // 哨兵模式的主节点名称
+	SentinelPassword string                `mapstructure:"sentinelPassword" default:""`                // 哨兵节点的登录密码，默认为空
+	Username         string                `mapstructure:"username" default:""`                        // ACL用户名(Redis 6.0以上)，为空时使用默认用户
+	TLS              bool                  `mapstructure:"tls" default:"false"`                        // 是否使用TLS连接
+	TLSSkipVerify    bool                  `mapstructure:"tlsSkipVerify" default:"false"`              // 是否跳过服务端证书校验(仅用于测试环境)
+	PoolSize         int                   `mapstructure:"poolSize" default:"0"`                       // 每个节点的最大连接数，0表示使用默认值(CPU核数的10倍)
+	MinIdleConns     int                   `mapstructure:"minIdleConns" default:"5"`                   // 每个节点的最小空闲连接数
+	OperationTimeout int                   `mapstructure:"operationTimeout" default:"3000"`            // 单次操作超时时间，单位(毫秒)，调用者的上下文对象截止时间更早时以调用者为准，0表示不限制
 	base.BaseConfig  `mapstructure:"omit"` // 基础配置信息
 }
 
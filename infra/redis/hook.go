@@ -0,0 +1,119 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 11:05:47
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 11:05:47
+ * @FilePath: \common\infra\redis\hook.go
+ * @Description: redis命令执行钩子
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package redis_infra
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hongliu9527/common/infra/metrics"
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"github.com/go-redis/redis/v8"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 编译期保证接口实现的一致性
+var _ redis.Hook = metricsHook{}
+
+// beginTimeKey 命令开始执行时间在上下文对象中的键
+type beginTimeKey struct{}
+
+// metricsHook redis命令监控指标采集钩子
+type metricsHook struct{}
+
+// BeforeProcess 命令执行前记录开始时间
+func (metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, beginTimeKey{}, time.Now()), nil
+}
+
+// AfterProcess 命令执行后记录耗时和错误
+func (metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if begin, ok := ctx.Value(beginTimeKey{}).(time.Time); ok {
+		metrics.ObserveRedisCommand(cmd.Name(), time.Since(begin), commandError(cmd))
+	}
+	return nil
+}
+
+// BeforeProcessPipeline 管道执行前记录开始时间
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, beginTimeKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline 管道执行后按命令记录耗时和错误
+func (metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if begin, ok := ctx.Value(beginTimeKey{}).(time.Time); ok {
+		duration := time.Since(begin)
+		for _, cmd := range cmds {
+			metrics.ObserveRedisCommand(cmd.Name(), duration, commandError(cmd))
+		}
+	}
+	return nil
+}
+
+// commandError 获取命令的执行错误，键不存在不视为错误
+func commandError(cmd redis.Cmder) error {
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		return err
+	}
+	return nil
+}
+
+// 编译期保证接口实现的一致性
+var _ redis.Hook = tracingHook{}
+
+// tracingHook redis命令链路追踪钩子
+type tracingHook struct{}
+
+// BeforeProcess 命令执行前创建链路
+func (tracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = tracing.Start(ctx, cmd.FullName(), trace.SpanKindClient,
+		semconv.DBSystemRedis,
+		semconv.DBOperationKey.String(cmd.Name()),
+	)
+	return ctx, nil
+}
+
+// AfterProcess 命令执行后结束链路
+func (tracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	tracing.End(trace.SpanFromContext(ctx), commandError(cmd))
+	return nil
+}
+
+// BeforeProcessPipeline 管道执行前创建链路
+func (tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+
+	ctx, _ = tracing.Start(ctx, "pipeline", trace.SpanKindClient,
+		semconv.DBSystemRedis,
+		semconv.DBOperationKey.String(strings.Join(names, " ")),
+	)
+	return ctx, nil
+}
+
+// AfterProcessPipeline 管道执行后结束链路
+func (tracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = commandError(cmd); err != nil {
+			break
+		}
+	}
+
+	tracing.End(trace.SpanFromContext(ctx), err)
+	return nil
+}
@@ -0,0 +1,104 @@
+package redis_infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSpanExporter 使用内存链路导出器替换基础设施的链路追踪提供者
+func newSpanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { tracing.SetTracerProvider(nil) })
+	return exporter
+}
+
+// spanAttribute 获取链路的指定属性
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingHook(t *testing.T) {
+	exporter := newSpanExporter(t)
+	hook := tracingHook{}
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"执行成功", nil, codes.Unset},
+		{"键不存在不视为错误", redis.Nil, codes.Unset},
+		{"执行失败", errors.New("连接断开"), codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exporter.Reset()
+			cmd := redis.NewStringCmd(context.Background(), "get", "key")
+			cmd.SetErr(test.err)
+
+			ctx, _ := hook.BeforeProcess(context.Background(), cmd)
+			hook.AfterProcess(ctx, cmd)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("链路数量错误(%d)", len(spans))
+			}
+			span := spans[0]
+			if span.Name != "get" || span.SpanKind != trace.SpanKindClient {
+				t.Fatalf("链路名称或类型错误(%s, %s)", span.Name, span.SpanKind)
+			}
+			if spanAttribute(span, semconv.DBSystemKey).AsString() != "redis" || spanAttribute(span, semconv.DBOperationKey).AsString() != "get" {
+				t.Fatalf("链路属性错误(%v)", span.Attributes)
+			}
+			if span.Status.Code != test.code {
+				t.Fatalf("链路状态错误(%v)，期望(%v)", span.Status.Code, test.code)
+			}
+		})
+	}
+}
+
+func TestTracingHookPipeline(t *testing.T) {
+	exporter := newSpanExporter(t)
+	hook := tracingHook{}
+
+	ctx := context.Background()
+	failed := redis.NewStatusCmd(ctx, "set", "b", "1")
+	failed.SetErr(errors.New("只读副本"))
+	cmds := []redis.Cmder{redis.NewStringCmd(ctx, "get", "a"), failed}
+
+	ctx, _ = hook.BeforeProcessPipeline(ctx, cmds)
+	hook.AfterProcessPipeline(ctx, cmds)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("链路数量错误(%d)", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "pipeline" || spanAttribute(span, semconv.DBOperationKey).AsString() != "get set" {
+		t.Fatalf("管道链路名称或属性错误(%s, %v)", span.Name, span.Attributes)
+	}
+	// 管道中任意命令失败时记录错误
+	if span.Status.Code != codes.Error || span.Status.Description != "只读副本" {
+		t.Fatalf("管道链路状态错误(%v)", span.Status)
+	}
+}
@@ -13,6 +13,11 @@ package redis_infra
 
 import (
 	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
@@ -23,6 +28,13 @@ const (
 	RedisInfraName = "Redis" // Redis基础设施名称
 )
 
+// "部署模式"相关定义
+const (
+	ModeStandalone = "standalone" // 单机模式
+	ModeSentinel   = "sentinel"   // 哨兵模式
+	ModeCluster    = "cluster"    // 集群模式
+)
+
 // Name 查询基础设施名称
 func (i *RedisInfra) Name() string {
 	return RedisInfraName
@@ -30,36 +42,128 @@ func (i *RedisInfra) Name() string {
 
 // start 启动基础设施
 func (i *RedisInfra) start(ctx context.Context) error {
+	client, err := i.newClient()
+	if err != nil {
+		return err
+	}
+	client.AddHook(metricsHook{})
+	client.AddHook(tracingHook{})
+	i.client = client
+
 	infraCtx, infraCancel := context.WithCancel(ctx)
 	i.ctx = infraCtx
 	i.cancel = infraCancel
 
+	// TODO: 增加限流设置
+	// 参考：https://redis.uptrace.dev/guide/rate-limiting.html
+
+	return nil
+}
+
+// newClient 根据部署模式创建Redis客户端
+func (i *RedisInfra) newClient() (redis.UniversalClient, error) {
+	options := &redis.UniversalOptions{
+		Addrs:            i.addrs(),
+		DB:               i.config.DB,
+		Username:         i.config.Username,
+		Password:         i.config.Password,
+		SentinelPassword: i.config.SentinelPassword,
+		MasterName:       i.config.MasterName,
+		PoolSize:         i.config.PoolSize,
+		MinIdleConns:     i.config.MinIdleConns,
+	}
+
+	if i.config.TLS {
+		options.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: i.config.TLSSkipVerify,
+		}
+	}
+
+	switch strings.ToLower(i.config.Mode) {
+	case "", ModeStandalone:
+		return redis.NewClient(options.Simple()), nil
+	case ModeSentinel:
+		if options.MasterName == "" {
+			return nil, errors.New("哨兵模式必须配置主节点名称(masterName)")
+		}
+		return redis.NewFailoverClient(options.Failover()), nil
+	case ModeCluster:
+		if options.DB != 0 {
+			return nil, errors.Errorf("集群模式只能使用0号数据库(db=%d)", options.DB)
+		}
+		return redis.NewClusterClient(options.Cluster()), nil
+	default:
+		return nil, errors.Errorf("Redis部署模式未知(%s)", i.config.Mode)
+	}
+}
+
+// addrs 获取Redis访问地址，哨兵和集群模式优先使用addrs/internalAddrs配置的地址列表
+func (i *RedisInfra) addrs() []string {
 	// 判断是否使用外网地址
-	hostPort := i.config.InternalHostPort
+	hostPort, addrs := i.config.InternalHostPort, i.config.InternalAddrs
 	if i.config.UseExternalHost {
-		hostPort = i.config.HostPort
+		hostPort, addrs = i.config.HostPort, i.config.Addrs
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:         hostPort,
-		Password:     i.config.Password,
-		DB:           i.config.DB,
-		MinIdleConns: 5, // 最小空闲连接数
-	})
-	i.client = client
-
-	// TODO: 增加限流设置
-	// 参考：https://redis.uptrace.dev/guide/rate-limiting.html
+	result := make([]string, 0)
+	if mode := strings.ToLower(i.config.Mode); mode != "" && mode != ModeStandalone {
+		for _, addr := range strings.Split(addrs, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				result = append(result, addr)
+			}
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, hostPort)
+	}
+	return result
+}
 
-	return nil
+// isCluster 判断是否为集群模式，集群模式下涉及多个键的命令需要按键拆分执行
+func (i *RedisInfra) isCluster() bool {
+	_, ok := i.client.(*redis.ClusterClient)
+	return ok
 }
 
 // stop 停止基础设施
 func (i *RedisInfra) stop() error {
+	if i.cancel != nil {
+		i.cancel()
+	}
+	if i.client == nil {
+		return nil
+	}
+
 	err := i.client.Close()
 	if err != nil {
-		return errors.Wrap(err, "断开Redis哨兵失败")
+		return errors.Wrap(err, "断开Redis连接失败")
 	}
 
 	return nil
 }
+
+// checkStarted 检查基础设施是否已经启动，未启动(或者已经停止)时返回common.ErrInfraNotStarted
+func (i *RedisInfra) checkStarted() error {
+	if i.client == nil || i.ctx == nil || i.ctx.Err() != nil {
+		return errors.WithMessage(common.ErrInfraNotStarted, RedisInfraName)
+	}
+	return nil
+}
+
+// operationContext 创建单次操作的上下文对象，配置了操作超时时间时附加超时控制
+// 基础设施未启动时返回错误，避免使用空的上下文对象或者已关闭的客户端
+func (i *RedisInfra) operationContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if err := i.checkStarted(); err != nil {
+		return nil, nil, err
+	}
+	if ctx == nil {
+		ctx = i.ctx
+	}
+
+	if i.config.OperationTimeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(i.config.OperationTimeout)*time.Millisecond)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
@@ -24,7 +24,7 @@ import (
 // RedisInfra Redis基础设施类型定义
 type RedisInfra struct {
 	base.BaseInfra                          // 基础设施基类
-	client         *redis.Client            // Redis客户端实例
+	client         redis.UniversalClient    // Redis客户端实例(启动后根据部署模式创建)
 	config         *config.RedisInfraConfig // 配置信息
 	ctx            context.Context          // 上下文对象
 	cancel         context.CancelFunc       // 退出回调函数
@@ -37,13 +37,6 @@ var singleton RedisInfra
 func New(config *config.RedisInfraConfig) common.RedisInfra {
 	singleton.config = config
 	singleton.BaseInfra = base.NewBaseInfra(singleton.Name(), nil, singleton.start, singleton.stop)
-	singleton.client = redis.NewClient(
-		&redis.Options{
-			Addr:     config.HostPort,
-			Password: config.Password,
-			DB:       config.DB,
-		},
-	)
 
 	return &singleton
 }
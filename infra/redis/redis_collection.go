@@ -0,0 +1,502 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-24 14:26:09
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-24 14:26:09
+ * @FilePath: \common\infra\redis\redis_collection.go
+ * @Description: redis 哈希表、列表、集合和有序集合接口实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package redis_infra
+
+import (
+	"context"
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// HSet 设置哈希表的多个字段
+func (i *RedisInfra) HSet(key string, values map[string]interface{}) error {
+	return i.HSetContext(i.ctx, key, values)
+}
+
+// HSetContext 设置哈希表的多个字段(带上下文)
+func (i *RedisInfra) HSetContext(ctx context.Context, key string, values map[string]interface{}) error {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if len(values) == 0 {
+		return nil
+	}
+	return writeError(i.client.HSet(ctx, key, values).Err(), key)
+}
+
+// HGet 读取哈希表的单个字段，键或者字段不存在时返回common.ErrKeyNotFound
+func (i *RedisInfra) HGet(key string, field string) (string, error) {
+	return i.HGetContext(i.ctx, key, field)
+}
+
+// HGetContext 读取哈希表的单个字段(带上下文)
+func (i *RedisInfra) HGetContext(ctx context.Context, key string, field string) (string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	value, err := i.client.HGet(ctx, key, field).Result()
+	return value, readError(err, key)
+}
+
+// HGetAll 读取哈希表的所有字段，键不存在时返回空哈希表
+func (i *RedisInfra) HGetAll(key string) (map[string]string, error) {
+	return i.HGetAllContext(i.ctx, key)
+}
+
+// HGetAllContext 读取哈希表的所有字段(带上下文)
+func (i *RedisInfra) HGetAllContext(ctx context.Context, key string) (map[string]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	values, err := i.client.HGetAll(ctx, key).Result()
+	return values, readError(err, key)
+}
+
+// HDel 删除哈希表的字段，返回删除的字段数量
+func (i *RedisInfra) HDel(key string, fields ...string) (int64, error) {
+	return i.HDelContext(i.ctx, key, fields...)
+}
+
+// HDelContext 删除哈希表的字段(带上下文)
+func (i *RedisInfra) HDelContext(ctx context.Context, key string, fields ...string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.HDel(ctx, key, fields...).Result()
+	return count, writeError(err, key)
+}
+
+// HIncrBy 哈希表字段增加指定值
+func (i *RedisInfra) HIncrBy(key string, field string, value int64) (int64, error) {
+	return i.HIncrByContext(i.ctx, key, field, value)
+}
+
+// HIncrByContext 哈希表字段增加指定值(带上下文)
+func (i *RedisInfra) HIncrByContext(ctx context.Context, key string, field string, value int64) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	result, err := i.client.HIncrBy(ctx, key, field, value).Result()
+	return result, writeError(err, key)
+}
+
+// HLen 查询哈希表的字段数量
+func (i *RedisInfra) HLen(key string) (int64, error) {
+	return i.HLenContext(i.ctx, key)
+}
+
+// HLenContext 查询哈希表的字段数量(带上下文)
+func (i *RedisInfra) HLenContext(ctx context.Context, key string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.HLen(ctx, key).Result()
+	return count, readError(err, key)
+}
+
+// LPush 在列表头部插入数据，返回列表长度
+func (i *RedisInfra) LPush(key string, values ...interface{}) (int64, error) {
+	return i.LPushContext(i.ctx, key, values...)
+}
+
+// LPushContext 在列表头部插入数据(带上下文)
+func (i *RedisInfra) LPushContext(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	length, err := i.client.LPush(ctx, key, values...).Result()
+	return length, writeError(err, key)
+}
+
+// RPush 在列表尾部插入数据，返回列表长度
+func (i *RedisInfra) RPush(key string, values ...interface{}) (int64, error) {
+	return i.RPushContext(i.ctx, key, values...)
+}
+
+// RPushContext 在列表尾部插入数据(带上下文)
+func (i *RedisInfra) RPushContext(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	length, err := i.client.RPush(ctx, key, values...).Result()
+	return length, writeError(err, key)
+}
+
+// LPop 弹出列表头部的数据，列表为空时返回common.ErrKeyNotFound
+func (i *RedisInfra) LPop(key string) (string, error) {
+	return i.LPopContext(i.ctx, key)
+}
+
+// LPopContext 弹出列表头部的数据(带上下文)
+func (i *RedisInfra) LPopContext(ctx context.Context, key string) (string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	value, err := i.client.LPop(ctx, key).Result()
+	return value, readError(err, key)
+}
+
+// RPop 弹出列表尾部的数据，列表为空时返回common.ErrKeyNotFound
+func (i *RedisInfra) RPop(key string) (string, error) {
+	return i.RPopContext(i.ctx, key)
+}
+
+// RPopContext 弹出列表尾部的数据(带上下文)
+func (i *RedisInfra) RPopContext(ctx context.Context, key string) (string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	value, err := i.client.RPop(ctx, key).Result()
+	return value, readError(err, key)
+}
+
+// LRange 读取列表指定范围的数据，stop为-1时读取到末尾
+func (i *RedisInfra) LRange(key string, start int64, stop int64) ([]string, error) {
+	return i.LRangeContext(i.ctx, key, start, stop)
+}
+
+// LRangeContext 读取列表指定范围的数据(带上下文)
+func (i *RedisInfra) LRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	values, err := i.client.LRange(ctx, key, start, stop).Result()
+	return values, readError(err, key)
+}
+
+// LTrim 只保留列表指定范围的数据
+func (i *RedisInfra) LTrim(key string, start int64, stop int64) error {
+	return i.LTrimContext(i.ctx, key, start, stop)
+}
+
+// LTrimContext 只保留列表指定范围的数据(带上下文)
+func (i *RedisInfra) LTrimContext(ctx context.Context, key string, start int64, stop int64) error {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	return writeError(i.client.LTrim(ctx, key, start, stop).Err(), key)
+}
+
+// LLen 查询列表长度
+func (i *RedisInfra) LLen(key string) (int64, error) {
+	return i.LLenContext(i.ctx, key)
+}
+
+// LLenContext 查询列表长度(带上下文)
+func (i *RedisInfra) LLenContext(ctx context.Context, key string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	length, err := i.client.LLen(ctx, key).Result()
+	return length, readError(err, key)
+}
+
+// SAdd 向集合添加成员，返回新添加的成员数量
+func (i *RedisInfra) SAdd(key string, members ...interface{}) (int64, error) {
+	return i.SAddContext(i.ctx, key, members...)
+}
+
+// SAddContext 向集合添加成员(带上下文)
+func (i *RedisInfra) SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.SAdd(ctx, key, members...).Result()
+	return count, writeError(err, key)
+}
+
+// SRem 从集合删除成员，返回删除的成员数量
+func (i *RedisInfra) SRem(key string, members ...interface{}) (int64, error) {
+	return i.SRemContext(i.ctx, key, members...)
+}
+
+// SRemContext 从集合删除成员(带上下文)
+func (i *RedisInfra) SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.SRem(ctx, key, members...).Result()
+	return count, writeError(err, key)
+}
+
+// SMembers 读取集合的所有成员
+func (i *RedisInfra) SMembers(key string) ([]string, error) {
+	return i.SMembersContext(i.ctx, key)
+}
+
+// SMembersContext 读取集合的所有成员(带上下文)
+func (i *RedisInfra) SMembersContext(ctx context.Context, key string) ([]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	members, err := i.client.SMembers(ctx, key).Result()
+	return members, readError(err, key)
+}
+
+// SIsMember 判断是否为集合的成员
+func (i *RedisInfra) SIsMember(key string, member interface{}) (bool, error) {
+	return i.SIsMemberContext(i.ctx, key, member)
+}
+
+// SIsMemberContext 判断是否为集合的成员(带上下文)
+func (i *RedisInfra) SIsMemberContext(ctx context.Context, key string, member interface{}) (bool, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	ok, err := i.client.SIsMember(ctx, key, member).Result()
+	return ok, readError(err, key)
+}
+
+// SCard 查询集合的成员数量
+func (i *RedisInfra) SCard(key string) (int64, error) {
+	return i.SCardContext(i.ctx, key)
+}
+
+// SCardContext 查询集合的成员数量(带上下文)
+func (i *RedisInfra) SCardContext(ctx context.Context, key string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.SCard(ctx, key).Result()
+	return count, readError(err, key)
+}
+
+// ZAdd 向有序集合添加成员(已存在时更新分数)，返回新添加的成员数量
+func (i *RedisInfra) ZAdd(key string, members ...common.ZMember) (int64, error) {
+	return i.ZAddContext(i.ctx, key, members...)
+}
+
+// ZAddContext 向有序集合添加成员(已存在时更新分数)(带上下文)
+func (i *RedisInfra) ZAddContext(ctx context.Context, key string, members ...common.ZMember) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	zMembers := make([]*redis.Z, 0, len(members))
+	for _, member := range members {
+		zMembers = append(zMembers, &redis.Z{Score: member.Score, Member: member.Member})
+	}
+
+	count, err := i.client.ZAdd(ctx, key, zMembers...).Result()
+	return count, writeError(err, key)
+}
+
+// ZRem 从有序集合删除成员，返回删除的成员数量
+func (i *RedisInfra) ZRem(key string, members ...interface{}) (int64, error) {
+	return i.ZRemContext(i.ctx, key, members...)
+}
+
+// ZRemContext 从有序集合删除成员(带上下文)
+func (i *RedisInfra) ZRemContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.ZRem(ctx, key, members...).Result()
+	return count, writeError(err, key)
+}
+
+// ZIncrBy 有序集合成员的分数增加指定值
+func (i *RedisInfra) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return i.ZIncrByContext(i.ctx, key, increment, member)
+}
+
+// ZIncrByContext 有序集合成员的分数增加指定值(带上下文)
+func (i *RedisInfra) ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	score, err := i.client.ZIncrBy(ctx, key, increment, member).Result()
+	return score, writeError(err, key)
+}
+
+// ZScore 查询有序集合成员的分数，成员不存在时返回common.ErrKeyNotFound
+func (i *RedisInfra) ZScore(key string, member string) (float64, error) {
+	return i.ZScoreContext(i.ctx, key, member)
+}
+
+// ZScoreContext 查询有序集合成员的分数(带上下文)
+func (i *RedisInfra) ZScoreContext(ctx context.Context, key string, member string) (float64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	score, err := i.client.ZScore(ctx, key, member).Result()
+	return score, readError(err, key)
+}
+
+// ZRange 按分数从小到大读取指定排名范围的成员
+func (i *RedisInfra) ZRange(key string, start int64, stop int64) ([]string, error) {
+	return i.ZRangeContext(i.ctx, key, start, stop)
+}
+
+// ZRangeContext 按分数从小到大读取指定排名范围的成员(带上下文)
+func (i *RedisInfra) ZRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	members, err := i.client.ZRange(ctx, key, start, stop).Result()
+	return members, readError(err, key)
+}
+
+// ZRevRange 按分数从大到小读取指定排名范围的成员
+func (i *RedisInfra) ZRevRange(key string, start int64, stop int64) ([]string, error) {
+	return i.ZRevRangeContext(i.ctx, key, start, stop)
+}
+
+// ZRevRangeContext 按分数从大到小读取指定排名范围的成员(带上下文)
+func (i *RedisInfra) ZRevRangeContext(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	members, err := i.client.ZRevRange(ctx, key, start, stop).Result()
+	return members, readError(err, key)
+}
+
+// ZRangeWithScores 按分数从小到大读取指定排名范围的成员和分数
+func (i *RedisInfra) ZRangeWithScores(key string, start int64, stop int64) ([]common.ZMember, error) {
+	return i.ZRangeWithScoresContext(i.ctx, key, start, stop)
+}
+
+// ZRangeWithScoresContext 按分数从小到大读取指定排名范围的成员和分数(带上下文)
+func (i *RedisInfra) ZRangeWithScoresContext(ctx context.Context, key string, start int64, stop int64) ([]common.ZMember, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	zMembers, err := i.client.ZRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, readError(err, key)
+	}
+
+	members := make([]common.ZMember, 0, len(zMembers))
+	for _, member := range zMembers {
+		members = append(members, common.ZMember{Score: member.Score, Member: member.Member})
+	}
+	return members, nil
+}
+
+// ZRangeByScore 读取分数在[min, max]范围内的成员，min和max可以使用"-inf"、"+inf"以及"("开区间前缀，count为0时不限制数量
+func (i *RedisInfra) ZRangeByScore(key string, min string, max string, offset, count int64) ([]string, error) {
+	return i.ZRangeByScoreContext(i.ctx, key, min, max, offset, count)
+}
+
+// ZRangeByScoreContext 读取分数在[min, max]范围内的成员(带上下文)
+func (i *RedisInfra) ZRangeByScoreContext(ctx context.Context, key string, min string, max string, offset, count int64) ([]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// LIMIT必须同时指定偏移量和数量，只指定偏移量时数量为-1(不限制)
+	opt := &redis.ZRangeBy{Min: min, Max: max}
+	if offset > 0 || count > 0 {
+		opt.Offset = offset
+		opt.Count = count
+		if count <= 0 {
+			opt.Count = -1
+		}
+	}
+
+	members, err := i.client.ZRangeByScore(ctx, key, opt).Result()
+	return members, readError(err, key)
+}
+
+// ZCard 查询有序集合的成员数量
+func (i *RedisInfra) ZCard(key string) (int64, error) {
+	return i.ZCardContext(i.ctx, key)
+}
+
+// ZCardContext 查询有序集合的成员数量(带上下文)
+func (i *RedisInfra) ZCardContext(ctx context.Context, key string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	count, err := i.client.ZCard(ctx, key).Result()
+	return count, readError(err, key)
+}
@@ -12,40 +12,312 @@
 package redis_infra
 
 import (
+	"context"
 	"time"
 
+	"github.com/hongliu9527/common/infra/common"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 )
 
+// incrScript 计数器增加指定值，键没有过期时间时设置过期时间(毫秒)，保证计数和设置过期时间的原子性
+var incrScript = redis.NewScript(`
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// readError 包装读操作错误，键不存在时返回可以通过errors.Is判断的common.ErrKeyNotFound
+func readError(err error, key string) error {
+	if err == redis.Nil {
+		return errors.WithMessagef(common.ErrKeyNotFound, "redis读失败(%s)", key)
+	}
+	return errors.Wrapf(err, "redis读失败(%s)", key)
+}
+
+// writeError 包装写操作错误
+func writeError(err error, key string) error {
+	return errors.Wrapf(err, "redis写失败(%s)", key)
+}
+
 // Set 设置单个字符串数据
 func (i *RedisInfra) Set(key string, value interface{}, expiration time.Duration) error {
-	return errors.Wrapf(i.client.Set(i.ctx, key, value, expiration).Err(), "redis写失败(%s)", key)
+	return i.SetContext(i.ctx, key, value, expiration)
 }
 
-// Get 查询单个字符串数据
-func (i *RedisInfra) Get(key string) (string, error) {
-	value, err := i.client.Get(i.ctx, key).Result()
-
-	if err == redis.Nil {
-		return "", errors.Errorf("(%s)键不存在", key)
+// SetContext 设置单个字符串数据(带上下文)
+func (i *RedisInfra) SetContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return err
 	}
+	defer cancel()
 
-	return value, errors.Wrapf(err, "redis读失败(%s)", key)
+	return writeError(i.client.Set(ctx, key, value, expiration).Err(), key)
 }
 
-// ReadAllKeys 读取所有的key
-func (i *RedisInfra) ReadAllKeys(match string) ([]string, error) {
-	keys, _, err := i.client.Scan(i.ctx, 0, match, 1000000).Result()
-	return keys, err
+// Get 查询单个字符串数据，键不存在时返回common.ErrKeyNotFound
+func (i *RedisInfra) Get(key string) (string, error) {
+	return i.GetContext(i.ctx, key)
+}
+
+// GetContext 查询单个字符串数据(带上下文)
+func (i *RedisInfra) GetContext(ctx context.Context, key string) (string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	value, err := i.client.Get(ctx, key).Result()
+	return value, readError(err, key)
 }
 
 // Delete 删除
 func (i *RedisInfra) Delete(key string) error {
-	return i.client.Del(i.ctx, key).Err()
+	return i.DeleteContext(i.ctx, key)
+}
+
+// DeleteContext 删除(带上下文)
+func (i *RedisInfra) DeleteContext(ctx context.Context, key string) error {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	return writeError(i.client.Del(ctx, key).Err(), key)
 }
 
 // SetNX 单个键值数据不存在时设置
 func (i *RedisInfra) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
-	return i.client.SetNX(i.ctx, key, value, expiration).Result()
+	return i.SetNXContext(i.ctx, key, value, expiration)
+}
+
+// SetNXContext 单个键值数据不存在时设置(带上下文)
+func (i *RedisInfra) SetNXContext(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	ok, err := i.client.SetNX(ctx, key, value, expiration).Result()
+	return ok, writeError(err, key)
+}
+
+// MGet 批量读取字符串数据，不存在的键不包含在结果中
+func (i *RedisInfra) MGet(keys ...string) (map[string]string, error) {
+	return i.MGetContext(i.ctx, keys...)
+}
+
+// MGetContext 批量读取字符串数据(带上下文)
+func (i *RedisInfra) MGetContext(ctx context.Context, keys ...string) (map[string]string, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	result := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	if i.isCluster() {
+		// 集群模式下多个键可能分布在不同的槽，使用管道逐个读取
+		// 管道只返回第一个失败命令的错误(键不存在时为redis.Nil，会掩盖后续命令的错误)，因此逐个检查命令的错误
+		cmds := make([]*redis.StringCmd, 0, len(keys))
+		i.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				cmds = append(cmds, pipe.Get(ctx, key))
+			}
+			return nil
+		})
+
+		for index, cmd := range cmds {
+			value, err := cmd.Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return nil, readError(err, keys[index])
+			}
+			result[keys[index]] = value
+		}
+		return result, nil
+	}
+
+	values, err := i.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, readError(err, keys[0])
+	}
+
+	for index, value := range values {
+		if text, ok := value.(string); ok {
+			result[keys[index]] = text
+		}
+	}
+	return result, nil
+}
+
+// MSet 批量设置字符串数据，expiration为0且不是集群模式时使用MSET命令，否则在事务管道中逐个设置
+func (i *RedisInfra) MSet(values map[string]interface{}, expiration time.Duration) error {
+	return i.MSetContext(i.ctx, values, expiration)
+}
+
+// MSetContext 批量设置字符串数据(带上下文)
+func (i *RedisInfra) MSetContext(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	var firstKey string
+	pairs := make([]interface{}, 0, len(values)*2)
+	for key, value := range values {
+		if firstKey == "" {
+			firstKey = key
+		}
+		pairs = append(pairs, key, value)
+	}
+
+	if expiration == 0 && !i.isCluster() {
+		return writeError(i.client.MSet(ctx, pairs...).Err(), firstKey)
+	}
+
+	_, err = i.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(ctx, key, value, expiration)
+		}
+		return nil
+	})
+	return writeError(err, firstKey)
+}
+
+// Exists 查询存在的键数量
+func (i *RedisInfra) Exists(keys ...string) (int64, error) {
+	return i.ExistsContext(i.ctx, keys...)
+}
+
+// ExistsContext 查询存在的键数量(带上下文)
+func (i *RedisInfra) ExistsContext(ctx context.Context, keys ...string) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if i.isCluster() {
+		// 集群模式下多个键可能分布在不同的槽，使用管道逐个查询
+		cmds := make([]*redis.IntCmd, 0, len(keys))
+		_, err = i.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				cmds = append(cmds, pipe.Exists(ctx, key))
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, readError(err, keys[0])
+		}
+
+		var count int64
+		for _, cmd := range cmds {
+			count += cmd.Val()
+		}
+		return count, nil
+	}
+
+	count, err := i.client.Exists(ctx, keys...).Result()
+	return count, readError(err, keys[0])
+}
+
+// Expire 设置过期时间，键不存在时返回false
+func (i *RedisInfra) Expire(key string, expiration time.Duration) (bool, error) {
+	return i.ExpireContext(i.ctx, key, expiration)
+}
+
+// ExpireContext 设置过期时间(带上下文)
+func (i *RedisInfra) ExpireContext(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	ok, err := i.client.Expire(ctx, key, expiration).Result()
+	return ok, writeError(err, key)
+}
+
+// TTL 查询剩余过期时间，键不存在时返回common.ErrKeyNotFound，不过期时返回-1
+func (i *RedisInfra) TTL(key string) (time.Duration, error) {
+	return i.TTLContext(i.ctx, key)
+}
+
+// TTLContext 查询剩余过期时间(带上下文)
+func (i *RedisInfra) TTLContext(ctx context.Context, key string) (time.Duration, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	ttl, err := i.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, readError(err, key)
+	}
+
+	// 键不存在时返回-2，不过期时返回-1(未换算为时间单位)
+	switch ttl {
+	case -2:
+		return 0, readError(redis.Nil, key)
+	case -1:
+		return -1, nil
+	default:
+		return ttl, nil
+	}
+}
+
+// Incr 计数器加1，expiration大于0且键没有过期时间时设置过期时间
+func (i *RedisInfra) Incr(key string, expiration time.Duration) (int64, error) {
+	return i.IncrContext(i.ctx, key, expiration)
+}
+
+// IncrContext 计数器加1(带上下文)
+func (i *RedisInfra) IncrContext(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return i.IncrByContext(ctx, key, 1, expiration)
+}
+
+// IncrBy 计数器增加指定值，expiration大于0且键没有过期时间时设置过期时间
+func (i *RedisInfra) IncrBy(key string, value int64, expiration time.Duration) (int64, error) {
+	return i.IncrByContext(i.ctx, key, value, expiration)
+}
+
+// IncrByContext 计数器增加指定值(带上下文)
+func (i *RedisInfra) IncrByContext(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	ctx, cancel, err := i.operationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	if expiration <= 0 {
+		result, err := i.client.IncrBy(ctx, key, value).Result()
+		return result, writeError(err, key)
+	}
+
+	result, err := incrScript.Run(ctx, i.client, []string{key}, value, expiration.Milliseconds()).Int64()
+	return result, writeError(err, key)
 }
@@ -0,0 +1,371 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-25 15:06:48
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-25 15:06:48
+ * @FilePath: \common\infra\redis\redis_lock.go
+ * @Description: 基于Redis的分布式锁实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package redis_infra
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hongliu9527/go-tools/logger"
+	"github.com/pkg/errors"
+)
+
+// 分布式锁相关定义
+const (
+	defaultLockTTL           = 30 * time.Second       // 锁的默认租期
+	defaultLockRetryInterval = 100 * time.Millisecond // 阻塞等待期间的默认重试间隔
+)
+
+// releaseScript 令牌一致时删除锁，保证只释放自己持有的锁
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// refreshScript 令牌一致时重新设置锁的过期时间(毫秒)
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// closedChan 已关闭的通道，未持有锁时由Lost返回
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// 编译期保证接口实现的一致性
+var _ common.Lock = (*Lock)(nil)
+
+// Lock 基于Redis的分布式锁，锁的值为随机令牌，只有持有令牌的锁对象才能续期和释放
+// 同一个锁对象可以在多个协程之间共享，加锁和解锁操作串行执行
+type Lock struct {
+	infra     *RedisInfra        // Redis基础设施
+	key       string             // 锁的键
+	token     string             // 持有者令牌
+	options   common.LockOptions // 锁选项
+	operation sync.Mutex         // 加锁、解锁和续期操作的互斥锁
+	count     int                // 持有次数(可重入锁重复加锁时增加)
+	mutex     sync.Mutex         // 租约的互斥锁
+	lease     *lease             // 当前租约，未持有锁时为空
+}
+
+// lease 一次持有锁期间的租约，开启看门狗时由后台协程续期
+type lease struct {
+	stop     chan struct{} // 停止续期信号
+	done     chan struct{} // 续期协程退出信号
+	lost     chan struct{} // 锁丢失信号
+	lostOnce sync.Once     // 保证锁丢失信号只关闭一次
+}
+
+// markLost 标记锁已丢失
+func (l *lease) markLost() {
+	l.lostOnce.Do(func() {
+		close(l.lost)
+	})
+}
+
+// NewLock 创建分布式锁(不会立即加锁)
+func (i *RedisInfra) NewLock(key string, options common.LockOptions) common.Lock {
+	return NewLock(i, key, options)
+}
+
+// NewLock 创建分布式锁(不会立即加锁)，未设置的选项使用默认值
+func NewLock(infra *RedisInfra, key string, options common.LockOptions) *Lock {
+	if options.TTL <= 0 {
+		options.TTL = defaultLockTTL
+	}
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultLockRetryInterval
+	}
+
+	return &Lock{
+		infra:   infra,
+		key:     key,
+		token:   newLockToken(),
+		options: options,
+	}
+}
+
+// newLockToken 生成随机的持有者令牌
+func newLockToken() string {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		// 随机数生成失败时退化为使用当前时间，仍然可以区分不同的锁对象
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buffer)
+}
+
+// Key 锁的键
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 锁对象的持有者令牌
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Lock 加锁，WaitTimeout为0时只尝试一次，大于0时最多等待WaitTimeout，小于0时一直等待到上下文对象取消
+func (l *Lock) Lock(ctx context.Context) error {
+	l.operation.Lock()
+	defer l.operation.Unlock()
+
+	if ctx == nil {
+		ctx = l.infra.ctx
+	}
+
+	var deadline <-chan time.Time
+	if l.options.WaitTimeout > 0 {
+		timer := time.NewTimer(l.options.WaitTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		ok, err := l.tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if l.options.WaitTimeout == 0 {
+			return errors.WithMessagef(common.ErrLockNotAcquired, "锁(%s)已被其他实例持有", l.key)
+		}
+
+		retry := time.NewTimer(l.options.RetryInterval)
+		select {
+		case <-ctx.Done():
+			retry.Stop()
+			return errors.WithMessagef(ctx.Err(), "等待锁(%s)被取消", l.key)
+		case <-deadline:
+			retry.Stop()
+			return errors.WithMessagef(common.ErrLockNotAcquired, "等待锁(%s)超时(%s)", l.key, l.options.WaitTimeout)
+		case <-retry.C:
+		}
+	}
+}
+
+// TryLock 尝试加锁一次，锁被其他实例持有时返回false
+func (l *Lock) TryLock(ctx context.Context) (bool, error) {
+	l.operation.Lock()
+	defer l.operation.Unlock()
+
+	return l.tryLock(ctx)
+}
+
+// tryLock 尝试加锁一次，调用者需要持有操作互斥锁
+func (l *Lock) tryLock(ctx context.Context) (bool, error) {
+	if l.count > 0 {
+		if !l.options.Reentrant {
+			return false, errors.WithMessagef(common.ErrLockNotAcquired, "锁(%s)已被当前对象持有且不可重入", l.key)
+		}
+
+		// 重入时续期，同时确认锁没有过期
+		if err := l.refresh(ctx, l.options.TTL); err != nil {
+			if errors.Is(err, common.ErrLockNotHeld) {
+				// 锁已过期或者被其他实例持有，之前的持有次数全部失效，通知等待Lost的协程并停止续期
+				l.count = 0
+				l.loseLease()
+			}
+			return false, err
+		}
+		l.count++
+		return true, nil
+	}
+
+	ok, err := l.infra.SetNXContext(ctx, l.key, l.token, l.options.TTL)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	l.count = 1
+	l.startLease()
+	return true, nil
+}
+
+// Unlock 解锁，可重入锁在解锁次数与加锁次数相同时才释放
+func (l *Lock) Unlock(ctx context.Context) error {
+	return l.UnlockAfter(ctx, 0)
+}
+
+// UnlockAfter 停止续期并在delay后自动过期，期间其他实例无法获取锁，delay小于等于0时立即释放
+// 适用于需要保证最小互斥时间的场景(例如多个实例之间存在时差的定时任务)
+func (l *Lock) UnlockAfter(ctx context.Context, delay time.Duration) error {
+	l.operation.Lock()
+	defer l.operation.Unlock()
+
+	if l.count == 0 {
+		return errors.WithMessagef(common.ErrLockNotHeld, "锁(%s)未加锁", l.key)
+	}
+	l.count--
+	if l.count > 0 {
+		return nil
+	}
+
+	l.stopLease()
+	if delay > 0 {
+		return l.refresh(ctx, delay)
+	}
+
+	ctx, cancel, err := l.infra.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	released, err := releaseScript.Run(ctx, l.infra.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return writeError(err, l.key)
+	}
+	if released == 0 {
+		return errors.WithMessagef(common.ErrLockNotHeld, "锁(%s)已过期或者被其他实例持有", l.key)
+	}
+	return nil
+}
+
+// Refresh 续期，ttl小于等于0时使用选项中的租期
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	l.operation.Lock()
+	defer l.operation.Unlock()
+
+	if l.count == 0 {
+		return errors.WithMessagef(common.ErrLockNotHeld, "锁(%s)未加锁", l.key)
+	}
+	if ttl <= 0 {
+		ttl = l.options.TTL
+	}
+
+	err := l.refresh(ctx, ttl)
+	if errors.Is(err, common.ErrLockNotHeld) {
+		l.count = 0
+		l.loseLease()
+	}
+	return err
+}
+
+// refresh 令牌一致时重新设置锁的过期时间
+func (l *Lock) refresh(ctx context.Context, ttl time.Duration) error {
+	ctx, cancel, err := l.infra.operationContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	refreshed, err := refreshScript.Run(ctx, l.infra.client, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return writeError(err, l.key)
+	}
+	if refreshed == 0 {
+		return errors.WithMessagef(common.ErrLockNotHeld, "锁(%s)已过期或者被其他实例持有", l.key)
+	}
+	return nil
+}
+
+// Lost 看门狗续期失败(锁已丢失)时关闭，未持有锁时返回已关闭的通道
+func (l *Lock) Lost() <-chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.lease == nil {
+		return closedChan
+	}
+	return l.lease.lost
+}
+
+// startLease 加锁成功后创建租约，开启看门狗时启动续期协程
+func (l *Lock) startLease() {
+	current := &lease{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		lost: make(chan struct{}),
+	}
+
+	l.mutex.Lock()
+	l.lease = current
+	l.mutex.Unlock()
+
+	if !l.options.Watchdog {
+		close(current.done)
+		return
+	}
+	go l.watchdog(current)
+}
+
+// stopLease 停止续期并清除租约
+func (l *Lock) stopLease() {
+	l.mutex.Lock()
+	current := l.lease
+	l.lease = nil
+	l.mutex.Unlock()
+
+	if current == nil {
+		return
+	}
+	close(current.stop)
+	<-current.done
+}
+
+// loseLease 标记当前租约的锁已丢失，并停止续期和清除租约
+func (l *Lock) loseLease() {
+	l.mutex.Lock()
+	current := l.lease
+	l.mutex.Unlock()
+
+	if current != nil {
+		current.markLost()
+	}
+	l.stopLease()
+}
+
+// watchdog 每隔TTL/3续期一次，锁被其他实例持有、超过租期未能续期成功或者基础设施停止时标记锁已丢失
+func (l *Lock) watchdog(current *lease) {
+	defer close(current.done)
+
+	ticker := time.NewTicker(l.options.TTL / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-current.stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := l.refresh(l.infra.ctx, l.options.TTL)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+
+		if errors.Is(err, common.ErrLockNotHeld) || errors.Is(err, common.ErrInfraNotStarted) || time.Since(renewed) >= l.options.TTL {
+			logger.Warning("分布式锁(%s)续期失败，锁已丢失(%s)", l.key, err.Error())
+			current.markLost()
+			return
+		}
+		logger.Warning("分布式锁(%s)续期失败，稍后重试(%s)", l.key, err.Error())
+	}
+}
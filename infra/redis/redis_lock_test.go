@@ -0,0 +1,224 @@
+package redis_infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+)
+
+// isClosed 判断通道是否已经关闭
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestLockToken(t *testing.T) {
+	infra, server := newTestRedis(t)
+	ctx := context.Background()
+
+	first := NewLock(infra, "lock", common.LockOptions{})
+	second := NewLock(infra, "lock", common.LockOptions{})
+	if first.Token() == second.Token() {
+		t.Fatal("不同锁对象的令牌不应该相同")
+	}
+
+	if err := first.Lock(ctx); err != nil {
+		t.Fatalf("加锁失败(%s)", err)
+	}
+	if value, _ := server.Get("lock"); value != first.Token() {
+		t.Fatalf("锁的值应该为持有者令牌(%s)", value)
+	}
+	if ttl := server.TTL("lock"); ttl != defaultLockTTL {
+		t.Fatalf("锁的租期错误(%s)", ttl)
+	}
+	if isClosed(first.Lost()) {
+		t.Fatal("持有锁期间不应该标记锁已丢失")
+	}
+
+	// 其他锁对象无法获取和释放已被持有的锁
+	if err := second.Lock(ctx); !errors.Is(err, common.ErrLockNotAcquired) {
+		t.Fatalf("锁已被持有时应该返回ErrLockNotAcquired(%v)", err)
+	}
+	if err := second.Unlock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("未持有锁时解锁应该返回ErrLockNotHeld(%v)", err)
+	}
+	if !server.Exists("lock") {
+		t.Fatal("其他锁对象不应该释放锁")
+	}
+
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("解锁失败(%s)", err)
+	}
+	if server.Exists("lock") || !isClosed(first.Lost()) {
+		t.Fatal("解锁后应该删除锁，且Lost返回已关闭的通道")
+	}
+	if ok, err := second.TryLock(ctx); err != nil || !ok {
+		t.Fatalf("锁释放后应该可以加锁(%v, %v)", ok, err)
+	}
+
+	// 锁过期后被其他实例持有时，解锁不能删除其他实例的锁
+	server.Set("lock", "other")
+	if err := second.Unlock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("锁被其他实例持有时解锁应该返回ErrLockNotHeld(%v)", err)
+	}
+	if value, _ := server.Get("lock"); value != "other" {
+		t.Fatalf("不应该删除其他实例的锁(%s)", value)
+	}
+}
+
+func TestLockWait(t *testing.T) {
+	infra, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	holder := NewLock(infra, "lock", common.LockOptions{})
+	waiter := NewLock(infra, "lock", common.LockOptions{WaitTimeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	if err := holder.Lock(ctx); err != nil {
+		t.Fatalf("加锁失败(%s)", err)
+	}
+
+	if err := waiter.Lock(ctx); !errors.Is(err, common.ErrLockNotAcquired) {
+		t.Fatalf("等待超时时应该返回ErrLockNotAcquired(%v)", err)
+	}
+
+	// 等待期间锁被释放时加锁成功
+	time.AfterFunc(30*time.Millisecond, func() { holder.Unlock(ctx) })
+	if err := waiter.Lock(ctx); err != nil {
+		t.Fatalf("等待期间锁被释放时应该加锁成功(%s)", err)
+	}
+	waiter.Unlock(ctx)
+
+	// 上下文对象取消时停止等待
+	holder.Lock(ctx)
+	forever := NewLock(infra, "lock", common.LockOptions{WaitTimeout: -1, RetryInterval: 10 * time.Millisecond})
+	cancelCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if err := forever.Lock(cancelCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("上下文对象取消时应该停止等待(%v)", err)
+	}
+}
+
+func TestLockReentrant(t *testing.T) {
+	infra, server := newTestRedis(t)
+	ctx := context.Background()
+
+	// 不可重入锁重复加锁时返回错误
+	lock := NewLock(infra, "plain", common.LockOptions{})
+	lock.Lock(ctx)
+	if err := lock.Lock(ctx); !errors.Is(err, common.ErrLockNotAcquired) {
+		t.Fatalf("不可重入锁重复加锁应该返回ErrLockNotAcquired(%v)", err)
+	}
+	if err := lock.Unlock(ctx); err != nil || server.Exists("plain") {
+		t.Fatalf("不可重入锁解锁一次后应该释放(%v)", err)
+	}
+
+	// 可重入锁在解锁次数与加锁次数相同时才释放
+	reentrant := NewLock(infra, "reentrant", common.LockOptions{Reentrant: true, TTL: time.Minute})
+	for i := 0; i < 3; i++ {
+		if err := reentrant.Lock(ctx); err != nil {
+			t.Fatalf("第%d次加锁失败(%s)", i+1, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := reentrant.Unlock(ctx); err != nil || !server.Exists("reentrant") {
+			t.Fatalf("第%d次解锁后不应该释放锁(%v)", i+1, err)
+		}
+	}
+	if err := reentrant.Unlock(ctx); err != nil || server.Exists("reentrant") {
+		t.Fatalf("解锁次数与加锁次数相同时应该释放锁(%v)", err)
+	}
+	if err := reentrant.Unlock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("多余的解锁应该返回ErrLockNotHeld(%v)", err)
+	}
+
+	// 重入时发现锁已经丢失，清除持有次数并标记锁已丢失
+	reentrant.Lock(ctx)
+	reentrant.Lock(ctx)
+	lost := reentrant.Lost()
+	server.Set("reentrant", "other")
+	if err := reentrant.Lock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("锁已丢失时重入应该返回ErrLockNotHeld(%v)", err)
+	}
+	if !isClosed(lost) {
+		t.Fatal("锁已丢失时应该关闭Lost通道")
+	}
+	if err := reentrant.Unlock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("锁丢失后持有次数应该清零(%v)", err)
+	}
+
+	// 锁重新可用后可以再次加锁
+	server.Del("reentrant")
+	if err := reentrant.Lock(ctx); err != nil {
+		t.Fatalf("锁丢失后重新加锁失败(%s)", err)
+	}
+	if err := reentrant.Unlock(ctx); err != nil {
+		t.Fatalf("解锁失败(%s)", err)
+	}
+}
+
+func TestLockUnlockAfter(t *testing.T) {
+	infra, server := newTestRedis(t)
+	ctx := context.Background()
+
+	lock := NewLock(infra, "job", common.LockOptions{TTL: time.Minute})
+	other := NewLock(infra, "job", common.LockOptions{})
+	lock.Lock(ctx)
+
+	// 延迟释放期间其他实例无法获取锁，过期后可以获取
+	if err := lock.UnlockAfter(ctx, 5*time.Second); err != nil {
+		t.Fatalf("延迟解锁失败(%s)", err)
+	}
+	if ttl := server.TTL("job"); ttl != 5*time.Second {
+		t.Fatalf("延迟解锁后锁的剩余时间错误(%s)", ttl)
+	}
+	if ok, _ := other.TryLock(ctx); ok {
+		t.Fatal("延迟释放期间其他实例不应该获取锁")
+	}
+	server.FastForward(5 * time.Second)
+	if ok, err := other.TryLock(ctx); err != nil || !ok {
+		t.Fatalf("锁过期后其他实例应该可以获取锁(%v, %v)", ok, err)
+	}
+
+	if err := lock.UnlockAfter(ctx, time.Second); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("未加锁时延迟解锁应该返回ErrLockNotHeld(%v)", err)
+	}
+}
+
+func TestLockWatchdog(t *testing.T) {
+	infra, server := newTestRedis(t)
+	ctx := context.Background()
+
+	lock := NewLock(infra, "watchdog", common.LockOptions{TTL: 90 * time.Millisecond, Watchdog: true})
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatalf("加锁失败(%s)", err)
+	}
+
+	// 看门狗每隔TTL/3续期一次
+	server.SetTTL("watchdog", time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if ttl := server.TTL("watchdog"); ttl != 90*time.Millisecond {
+		t.Fatalf("看门狗应该续期(%s)", ttl)
+	}
+
+	// 锁被其他实例持有时续期失败，标记锁已丢失
+	lost := lock.Lost()
+	server.Set("watchdog", "other")
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("锁丢失后应该关闭Lost通道")
+	}
+
+	if err := lock.Unlock(ctx); !errors.Is(err, common.ErrLockNotHeld) {
+		t.Fatalf("锁丢失后解锁应该返回ErrLockNotHeld(%v)", err)
+	}
+	if value, _ := server.Get("watchdog"); value != "other" {
+		t.Fatalf("不应该删除其他实例的锁(%s)", value)
+	}
+}
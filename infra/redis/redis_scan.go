@@ -0,0 +1,181 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-25 10:14:32
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-25 10:14:32
+ * @FilePath: \common\infra\redis\redis_scan.go
+ * @Description: redis 键遍历接口实现
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package redis_infra
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// 键遍历相关定义
+const (
+	defaultScanCount   = 1000   // 每次SCAN命令的数量提示默认值
+	defaultMaxReadKeys = 100000 // ReadAllKeys最多读取的键数量
+)
+
+// scanState 一次键遍历的共享状态，集群模式下多个主节点并发遍历时保证回调串行执行
+type scanState struct {
+	mutex   sync.Mutex                // 回调函数和计数的互斥锁
+	match   string                    // 匹配模式
+	maxKeys int                       // 最多遍历的键数量，0表示不限制
+	total   int                       // 已经遍历的键数量
+	fn      func(keys []string) error // 回调函数
+	err     error                     // 第一个出现的错误，出现错误后停止遍历
+}
+
+// deliver 将一批键交给回调函数，超过最大键数量时只交付未超过的部分并返回common.ErrTooManyKeys
+func (s *scanState) deliver(keys []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	exceeded := false
+	if s.maxKeys > 0 && s.total+len(keys) > s.maxKeys {
+		keys = keys[:s.maxKeys-s.total]
+		exceeded = true
+	}
+
+	s.total += len(keys)
+	if len(keys) > 0 {
+		if err := s.fn(keys); err != nil {
+			s.err = err
+			return err
+		}
+	}
+
+	if exceeded {
+		s.err = errors.WithMessagef(common.ErrTooManyKeys, "匹配(%s)的键超过%d个", s.match, s.maxKeys)
+	}
+	return s.err
+}
+
+// ReadAllKeys 读取所有匹配的key，超过100000个时返回common.ErrTooManyKeys，键较多时请使用ScanKeys分批处理
+func (i *RedisInfra) ReadAllKeys(match string) ([]string, error) {
+	return i.ReadAllKeysContext(i.ctx, match)
+}
+
+// ReadAllKeysContext 读取所有匹配的key(带上下文)
+func (i *RedisInfra) ReadAllKeysContext(ctx context.Context, match string) ([]string, error) {
+	return i.readAllKeys(ctx, match, defaultMaxReadKeys)
+}
+
+// readAllKeys 读取所有匹配的key，去重后的键数量超过maxKeys时返回common.ErrTooManyKeys
+func (i *RedisInfra) readAllKeys(ctx context.Context, match string, maxKeys int) ([]string, error) {
+	// SCAN命令可能返回重复的键(遍历期间发生rehash)，需要去重，并按去重后的数量判断是否超过限制
+	// (ScanOptions.MaxKeys按SCAN返回的数量计数，重复的键会提前触发限制，因此这里不使用)
+	keys := make([]string, 0)
+	exists := make(map[string]struct{})
+	err := i.ScanKeys(ctx, match, common.ScanOptions{}, func(batch []string) error {
+		for _, key := range batch {
+			if _, ok := exists[key]; ok {
+				continue
+			}
+			if len(keys) >= maxKeys {
+				return errors.WithMessagef(common.ErrTooManyKeys, "匹配(%s)的键超过%d个", match, maxKeys)
+			}
+			exists[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// ScanKeys 使用SCAN命令按游标分批遍历匹配的键，每批键交给fn处理，fn返回错误或者上下文对象取消时停止遍历
+// 集群模式下并发遍历所有主节点，fn串行执行；SCAN命令可能返回重复的键，需要精确结果时由调用者去重
+// options.MaxKeys按SCAN返回的键数量计数(包含重复的键)，需要按去重后的数量限制时由调用者在fn中计数
+// 每次SCAN命令单独应用操作超时时间，整个遍历过程的超时由调用者的上下文对象控制
+func (i *RedisInfra) ScanKeys(ctx context.Context, match string, options common.ScanOptions, fn func(keys []string) error) error {
+	if err := i.checkStarted(); err != nil {
+		return err
+	}
+	if ctx == nil {
+		ctx = i.ctx
+	}
+
+	if options.Count <= 0 {
+		options.Count = defaultScanCount
+	}
+
+	scanCtx, scanCancel := context.WithCancel(ctx)
+	defer scanCancel()
+
+	state := &scanState{match: match, maxKeys: options.MaxKeys, fn: fn}
+	scanNode := func(ctx context.Context, client redis.Cmdable) error {
+		err := i.scanNode(ctx, client, match, options.Count, state)
+		if err != nil {
+			// 停止其余主节点的遍历
+			scanCancel()
+		}
+		return err
+	}
+
+	var err error
+	if cluster, ok := i.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(scanCtx, func(ctx context.Context, client *redis.Client) error {
+			return scanNode(ctx, client)
+		})
+	} else {
+		err = scanNode(scanCtx, i.client)
+	}
+
+	// 优先返回回调函数的错误或者超过最大键数量的错误，而不是因此取消遍历导致的错误
+	state.mutex.Lock()
+	if state.err != nil {
+		err = state.err
+	}
+	state.mutex.Unlock()
+	return err
+}
+
+// scanNode 遍历单个节点中匹配的键
+func (i *RedisInfra) scanNode(ctx context.Context, client redis.Cmdable, match string, count int64, state *scanState) error {
+	var cursor uint64
+	for {
+		callCtx, cancel, err := i.operationContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		keys, cursor, err = client.Scan(callCtx, cursor, match, count).Result()
+		cancel()
+		if err != nil {
+			return readError(err, match)
+		}
+
+		if err := state.deliver(keys); err != nil {
+			return err
+		}
+		if cursor == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
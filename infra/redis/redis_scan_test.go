@@ -0,0 +1,101 @@
+package redis_infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/hongliu9527/common/infra/common"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// duplicateScanHook 让每次SCAN命令重复返回同一批键，模拟遍历期间发生rehash
+type duplicateScanHook struct {
+	metricsHook
+}
+
+// AfterProcess 命令执行后的处理
+func (duplicateScanHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if scan, ok := cmd.(*redis.ScanCmd); ok {
+		keys, cursor := scan.Val()
+		scan.SetVal(append(keys, keys...), cursor)
+	}
+	return nil
+}
+
+// newScanTestRedis 创建包含count个匹配键和一个不匹配键的测试实例
+func newScanTestRedis(t *testing.T, count int) *RedisInfra {
+	infra, server := newTestRedis(t)
+	for i := 0; i < count; i++ {
+		server.Set(fmt.Sprintf("user:%02d", i), "1")
+	}
+	server.Set("other", "1")
+	return infra
+}
+
+func TestScanKeys(t *testing.T) {
+	infra := newScanTestRedis(t, 25)
+	ctx := context.Background()
+
+	keys := make([]string, 0)
+	err := infra.ScanKeys(ctx, "user:*", common.ScanOptions{Count: 10}, func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	})
+	if err != nil || len(keys) != 25 {
+		t.Fatalf("遍历匹配的键错误(%d, %v)", len(keys), err)
+	}
+
+	// 超过最大键数量时只交付未超过的部分
+	total := 0
+	err = infra.ScanKeys(ctx, "user:*", common.ScanOptions{Count: 10, MaxKeys: 7}, func(batch []string) error {
+		total += len(batch)
+		return nil
+	})
+	if !errors.Is(err, common.ErrTooManyKeys) || total != 7 {
+		t.Fatalf("超过最大键数量时结果错误(%d, %v)", total, err)
+	}
+
+	// 回调函数返回错误时停止遍历并返回该错误
+	stop := errors.New("stop")
+	calls := 0
+	err = infra.ScanKeys(ctx, "user:*", common.ScanOptions{Count: 1}, func(batch []string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("回调函数返回错误时应该停止遍历(%d, %v)", calls, err)
+	}
+}
+
+func TestReadAllKeys(t *testing.T) {
+	infra := newScanTestRedis(t, 25)
+	infra.client.AddHook(duplicateScanHook{})
+	ctx := context.Background()
+
+	// ScanKeys的最大键数量包含重复的键
+	err := infra.ScanKeys(ctx, "user:*", common.ScanOptions{MaxKeys: 25}, func(batch []string) error { return nil })
+	if !errors.Is(err, common.ErrTooManyKeys) {
+		t.Fatalf("重复的键应该计入最大键数量(%v)", err)
+	}
+
+	// ReadAllKeys按去重后的数量判断是否超过限制
+	keys, err := infra.readAllKeys(ctx, "user:*", 25)
+	if err != nil || len(keys) != 25 {
+		t.Fatalf("读取所有匹配的键错误(%d, %v)", len(keys), err)
+	}
+	sort.Strings(keys)
+	if keys[0] != "user:00" || keys[24] != "user:24" {
+		t.Fatalf("读取的键错误(%v)", keys)
+	}
+
+	if _, err := infra.readAllKeys(ctx, "user:*", 24); !errors.Is(err, common.ErrTooManyKeys) {
+		t.Fatalf("去重后超过最大键数量时应该返回ErrTooManyKeys(%v)", err)
+	}
+	if keys, err := infra.ReadAllKeys("missing:*"); err != nil || len(keys) != 0 {
+		t.Fatalf("没有匹配的键时应该返回空列表(%v, %v)", keys, err)
+	}
+}
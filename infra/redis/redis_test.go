@@ -0,0 +1,273 @@
+package redis_infra
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hongliu9527/common/infra/common"
+	"github.com/hongliu9527/common/infra/redis/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestRedis 创建连接到内存Redis服务的基础设施实例
+func newTestRedis(t *testing.T) (*RedisInfra, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return newTestInfra(t, client), server
+}
+
+// newTestInfra 使用指定的客户端创建已启动的基础设施实例
+func newTestInfra(t *testing.T, client redis.UniversalClient) *RedisInfra {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &RedisInfra{client: client, config: &config.RedisInfraConfig{}, ctx: ctx, cancel: cancel}
+}
+
+func TestRedisNotStarted(t *testing.T) {
+	infra := &RedisInfra{config: &config.RedisInfraConfig{}}
+	if _, err := infra.Get("key"); !errors.Is(err, common.ErrInfraNotStarted) {
+		t.Fatalf("基础设施未启动时应该返回ErrInfraNotStarted(%v)", err)
+	}
+}
+
+func TestRedisString(t *testing.T) {
+	infra, server := newTestRedis(t)
+
+	if _, err := infra.Get("missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("键不存在时应该返回ErrKeyNotFound(%v)", err)
+	}
+
+	if err := infra.Set("name", "alice", time.Minute); err != nil {
+		t.Fatalf("设置数据失败(%s)", err)
+	}
+	if value, err := infra.Get("name"); err != nil || value != "alice" {
+		t.Fatalf("读取数据错误(%s, %v)", value, err)
+	}
+
+	if ok, err := infra.SetNX("name", "bob", 0); err != nil || ok {
+		t.Fatalf("键已存在时不应该设置成功(%v, %v)", ok, err)
+	}
+
+	if err := infra.MSet(map[string]interface{}{"a": 1, "b": "2"}, 0); err != nil {
+		t.Fatalf("批量设置数据失败(%s)", err)
+	}
+	values, err := infra.MGet("a", "missing", "b")
+	if err != nil || !reflect.DeepEqual(values, map[string]string{"a": "1", "b": "2"}) {
+		t.Fatalf("批量读取数据错误(%v, %v)", values, err)
+	}
+	if count, err := infra.Exists("a", "b", "missing"); err != nil || count != 2 {
+		t.Fatalf("存在的键数量错误(%d, %v)", count, err)
+	}
+
+	if err := infra.Delete("name"); err != nil {
+		t.Fatalf("删除数据失败(%s)", err)
+	}
+	if server.Exists("name") {
+		t.Fatal("删除后键不应该存在")
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	infra, server := newTestRedis(t)
+
+	// 键不存在(PTTL返回-2)
+	if _, err := infra.TTL("missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("键不存在时应该返回ErrKeyNotFound(%v)", err)
+	}
+
+	// 键不过期(PTTL返回-1)
+	server.Set("forever", "1")
+	if ttl, err := infra.TTL("forever"); err != nil || ttl != -1 {
+		t.Fatalf("键不过期时应该返回-1(%s, %v)", ttl, err)
+	}
+
+	if ok, err := infra.Expire("forever", time.Minute); err != nil || !ok {
+		t.Fatalf("设置过期时间失败(%v, %v)", ok, err)
+	}
+	if ttl, err := infra.TTL("forever"); err != nil || ttl != time.Minute {
+		t.Fatalf("剩余过期时间错误(%s, %v)", ttl, err)
+	}
+	if ok, err := infra.Expire("missing", time.Minute); err != nil || ok {
+		t.Fatalf("键不存在时设置过期时间应该返回false(%v, %v)", ok, err)
+	}
+}
+
+func TestRedisCounter(t *testing.T) {
+	infra, server := newTestRedis(t)
+
+	// 设置过期时间时只在第一次创建计数器时生效，后续加1不会延长过期时间
+	if value, err := infra.Incr("counter", time.Minute); err != nil || value != 1 {
+		t.Fatalf("计数器加1错误(%d, %v)", value, err)
+	}
+	server.FastForward(30 * time.Second)
+	if value, err := infra.IncrBy("counter", 10, time.Minute); err != nil || value != 11 {
+		t.Fatalf("计数器增加指定值错误(%d, %v)", value, err)
+	}
+	if ttl := server.TTL("counter"); ttl != 30*time.Second {
+		t.Fatalf("已有过期时间时不应该重新设置过期时间(%s)", ttl)
+	}
+
+	// 不设置过期时间时计数器不过期
+	if value, err := infra.IncrBy("forever", -2, 0); err != nil || value != -2 {
+		t.Fatalf("计数器减少错误(%d, %v)", value, err)
+	}
+	if ttl := server.TTL("forever"); ttl != 0 {
+		t.Fatalf("计数器不应该有过期时间(%s)", ttl)
+	}
+}
+
+func TestRedisHash(t *testing.T) {
+	infra, _ := newTestRedis(t)
+
+	if err := infra.HSet("user", map[string]interface{}{"name": "alice", "age": 18}); err != nil {
+		t.Fatalf("设置哈希表失败(%s)", err)
+	}
+	if value, err := infra.HGet("user", "name"); err != nil || value != "alice" {
+		t.Fatalf("读取哈希表字段错误(%s, %v)", value, err)
+	}
+	if _, err := infra.HGet("user", "missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("字段不存在时应该返回ErrKeyNotFound(%v)", err)
+	}
+	if value, err := infra.HIncrBy("user", "age", 2); err != nil || value != 20 {
+		t.Fatalf("哈希表字段增加指定值错误(%d, %v)", value, err)
+	}
+	if values, err := infra.HGetAll("user"); err != nil || !reflect.DeepEqual(values, map[string]string{"name": "alice", "age": "20"}) {
+		t.Fatalf("读取哈希表所有字段错误(%v, %v)", values, err)
+	}
+	if count, err := infra.HDel("user", "age", "missing"); err != nil || count != 1 {
+		t.Fatalf("删除哈希表字段数量错误(%d, %v)", count, err)
+	}
+	if count, err := infra.HLen("user"); err != nil || count != 1 {
+		t.Fatalf("哈希表字段数量错误(%d, %v)", count, err)
+	}
+	if values, err := infra.HGetAll("missing"); err != nil || len(values) != 0 {
+		t.Fatalf("键不存在时应该返回空哈希表(%v, %v)", values, err)
+	}
+}
+
+func TestRedisList(t *testing.T) {
+	infra, _ := newTestRedis(t)
+
+	if length, err := infra.RPush("queue", "b", "c"); err != nil || length != 2 {
+		t.Fatalf("在列表尾部插入数据错误(%d, %v)", length, err)
+	}
+	if length, err := infra.LPush("queue", "a"); err != nil || length != 3 {
+		t.Fatalf("在列表头部插入数据错误(%d, %v)", length, err)
+	}
+	if values, err := infra.LRange("queue", 0, -1); err != nil || !reflect.DeepEqual(values, []string{"a", "b", "c"}) {
+		t.Fatalf("读取列表错误(%v, %v)", values, err)
+	}
+	if value, err := infra.LPop("queue"); err != nil || value != "a" {
+		t.Fatalf("弹出列表头部数据错误(%s, %v)", value, err)
+	}
+	if value, err := infra.RPop("queue"); err != nil || value != "c" {
+		t.Fatalf("弹出列表尾部数据错误(%s, %v)", value, err)
+	}
+
+	infra.RPush("queue", "d", "e")
+	if err := infra.LTrim("queue", 1, -1); err != nil {
+		t.Fatalf("裁剪列表失败(%s)", err)
+	}
+	if length, err := infra.LLen("queue"); err != nil || length != 2 {
+		t.Fatalf("列表长度错误(%d, %v)", length, err)
+	}
+
+	if _, err := infra.LPop("missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("列表为空时应该返回ErrKeyNotFound(%v)", err)
+	}
+	if _, err := infra.RPop("missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("列表为空时应该返回ErrKeyNotFound(%v)", err)
+	}
+}
+
+func TestRedisSet(t *testing.T) {
+	infra, _ := newTestRedis(t)
+
+	if count, err := infra.SAdd("tags", "a", "b", "a"); err != nil || count != 2 {
+		t.Fatalf("向集合添加成员数量错误(%d, %v)", count, err)
+	}
+	if ok, err := infra.SIsMember("tags", "a"); err != nil || !ok {
+		t.Fatalf("应该是集合的成员(%v, %v)", ok, err)
+	}
+	if count, err := infra.SRem("tags", "a", "missing"); err != nil || count != 1 {
+		t.Fatalf("从集合删除成员数量错误(%d, %v)", count, err)
+	}
+	infra.SAdd("tags", "c")
+	members, err := infra.SMembers("tags")
+	sort.Strings(members)
+	if err != nil || !reflect.DeepEqual(members, []string{"b", "c"}) {
+		t.Fatalf("读取集合成员错误(%v, %v)", members, err)
+	}
+	if count, err := infra.SCard("tags"); err != nil || count != 2 {
+		t.Fatalf("集合成员数量错误(%d, %v)", count, err)
+	}
+}
+
+func TestRedisSortedSet(t *testing.T) {
+	infra, _ := newTestRedis(t)
+
+	count, err := infra.ZAdd("rank", common.ZMember{Score: 3, Member: "c"}, common.ZMember{Score: 1, Member: "a"}, common.ZMember{Score: 2, Member: "b"})
+	if err != nil || count != 3 {
+		t.Fatalf("向有序集合添加成员数量错误(%d, %v)", count, err)
+	}
+	if score, err := infra.ZIncrBy("rank", 10, "a"); err != nil || score != 11 {
+		t.Fatalf("有序集合成员分数增加错误(%v, %v)", score, err)
+	}
+	if score, err := infra.ZScore("rank", "b"); err != nil || score != 2 {
+		t.Fatalf("有序集合成员分数错误(%v, %v)", score, err)
+	}
+	if _, err := infra.ZScore("rank", "missing"); !errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("成员不存在时应该返回ErrKeyNotFound(%v)", err)
+	}
+
+	if members, err := infra.ZRange("rank", 0, -1); err != nil || !reflect.DeepEqual(members, []string{"b", "c", "a"}) {
+		t.Fatalf("按分数从小到大读取成员错误(%v, %v)", members, err)
+	}
+	if members, err := infra.ZRevRange("rank", 0, 0); err != nil || !reflect.DeepEqual(members, []string{"a"}) {
+		t.Fatalf("按分数从大到小读取成员错误(%v, %v)", members, err)
+	}
+	expect := []common.ZMember{{Score: 2, Member: "b"}, {Score: 3, Member: "c"}}
+	if members, err := infra.ZRangeWithScores("rank", 0, 1); err != nil || !reflect.DeepEqual(members, expect) {
+		t.Fatalf("读取成员和分数错误(%v, %v)", members, err)
+	}
+	if members, err := infra.ZRangeByScore("rank", "2", "+inf", 1, 0); err != nil || !reflect.DeepEqual(members, []string{"c", "a"}) {
+		t.Fatalf("按分数范围读取成员错误(%v, %v)", members, err)
+	}
+
+	if count, err := infra.ZRem("rank", "a", "missing"); err != nil || count != 1 {
+		t.Fatalf("从有序集合删除成员数量错误(%d, %v)", count, err)
+	}
+	if count, err := infra.ZCard("rank"); err != nil || count != 2 {
+		t.Fatalf("有序集合成员数量错误(%d, %v)", count, err)
+	}
+}
+
+func TestRedisClusterMGet(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}})
+	t.Cleanup(func() { client.Close() })
+	infra := newTestInfra(t, client)
+
+	server.Set("a", "1")
+	server.Set("b", "2")
+	values, err := infra.MGet("a", "missing", "b")
+	if err != nil || !reflect.DeepEqual(values, map[string]string{"a": "1", "b": "2"}) {
+		t.Fatalf("集群模式批量读取数据错误(%v, %v)", values, err)
+	}
+
+	// 键不存在的命令在前时，后续命令的错误不能被忽略
+	server.HSet("hash", "field", "value")
+	if _, err := infra.MGet("missing", "hash"); err == nil || errors.Is(err, common.ErrKeyNotFound) {
+		t.Fatalf("读取类型错误的键时应该返回错误(%v)", err)
+	}
+}
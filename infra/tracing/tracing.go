@@ -0,0 +1,65 @@
+/*
+ * @Author: hongliu
+ * @Date: 2026-10-19 14:02:18
+ * @LastEditors: hongliu
+ * @LastEditTime: 2026-10-19 14:02:18
+ * @FilePath: \common\infra\tracing\tracing.go
+ * @Description: 基础设施链路追踪
+ *
+ * Copyright (c) 2022 by 洪流, All Rights Reserved.
+ */
+
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 常量相关定义
+const (
+	instrumentationName = "github.com/hongliu9527/common" // 链路追踪埋点库名称
+)
+
+// providerHolder 链路追踪提供者容器(atomic.Value要求存储的类型保持一致)
+type providerHolder struct {
+	provider trace.TracerProvider
+}
+
+// provider 基础设施使用的链路追踪提供者，未设置时使用otel全局提供者
+var provider atomic.Value
+
+// SetTracerProvider 设置基础设施使用的链路追踪提供者，传入nil时恢复使用otel全局提供者
+func SetTracerProvider(tracerProvider trace.TracerProvider) {
+	provider.Store(providerHolder{provider: tracerProvider})
+}
+
+// Tracer 获取基础设施使用的链路追踪器
+func Tracer() trace.Tracer {
+	if holder, ok := provider.Load().(providerHolder); ok && holder.provider != nil {
+		return holder.provider.Tracer(instrumentationName)
+	}
+	return otel.GetTracerProvider().Tracer(instrumentationName)
+}
+
+// Start 创建一个子链路，上下文对象为空时创建根链路
+func Start(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// End 结束链路，并记录链路执行过程中出现的错误
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}